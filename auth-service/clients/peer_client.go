@@ -0,0 +1,150 @@
+package clients
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"auth-service/config"
+)
+
+// PeerClient вызывает внутренние эндпоинты других сервисов с X-Internal-Token
+type PeerClient struct {
+	services map[string]string
+	token    string
+	http     *http.Client
+}
+
+// PeerResult - результат вызова одного сервиса
+type PeerResult struct {
+	Service string `json:"service"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PeerExport - данные пользователя, которые вернул один сервис
+type PeerExport struct {
+	PeerResult
+	Data json.RawMessage `json:"-"`
+}
+
+func NewPeerClient() *PeerClient {
+	return &PeerClient{
+		services: config.PeerServices,
+		token:    config.InternalToken,
+		http:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AnonymizeUser просит каждый сервис обезличить данные пользователя.
+// email нужен сервисам, где пользователь встречается только по адресу (заявки contact-service).
+// Результаты возвращаются по всем сервисам, даже если часть вызовов завершилась ошибкой.
+func (c *PeerClient) AnonymizeUser(userID uint, email string) []PeerResult {
+	names := c.names()
+	results := make([]PeerResult, 0, len(names))
+	for _, name := range names {
+		res := PeerResult{Service: name, OK: true}
+		if _, err := c.do(http.MethodPost, c.userURL(name, userID, "anonymize", email)); err != nil {
+			res.OK = false
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// ExportUser собирает данные пользователя из каждого сервиса.
+// Ответ сервиса должен быть JSON; он попадает в архив без изменений.
+func (c *PeerClient) ExportUser(userID uint, email string) []PeerExport {
+	names := c.names()
+	results := make([]PeerExport, 0, len(names))
+	for _, name := range names {
+		res := PeerExport{PeerResult: PeerResult{Service: name, OK: true}}
+		body, err := c.do(http.MethodGet, c.userURL(name, userID, "export", email))
+		if err == nil && !json.Valid(body) {
+			err = fmt.Errorf("invalid JSON in response")
+		}
+		if err != nil {
+			res.OK = false
+			res.Error = err.Error()
+		} else {
+			res.Data = body
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// UserDeleted сообщает сервисам о мягком удалении пользователя, чтобы они могли
+// передать или заархивировать его данные (проекты в project-service).
+// Сервисы без такого эндпоинта отвечают 404 и считаются успешно обработавшими вызов.
+func (c *PeerClient) UserDeleted(userID uint) []PeerResult {
+	names := c.names()
+	results := make([]PeerResult, 0, len(names))
+	for _, name := range names {
+		res := PeerResult{Service: name, OK: true}
+		_, err := c.do(http.MethodPost, c.userURL(name, userID, "deleted", ""))
+		var se *statusError
+		if err != nil && !(errors.As(err, &se) && se.code == http.StatusNotFound) {
+			res.OK = false
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// statusError - ответ сервиса с кодом вне 2xx
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.code, e.body)
+}
+
+func (c *PeerClient) names() []string {
+	names := make([]string, 0, len(c.services))
+	for name := range c.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *PeerClient) userURL(service string, userID uint, action, email string) string {
+	u := fmt.Sprintf("%s/api/internal/users/%d/%s", c.services[service], userID, action)
+	if email != "" {
+		u += "?email=" + url.QueryEscape(email)
+	}
+	return u
+}
+
+func (c *PeerClient) do(method, target string) ([]byte, error) {
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &statusError{code: resp.StatusCode, body: string(body)}
+	}
+	return body, nil
+}
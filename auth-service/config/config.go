@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -14,8 +15,84 @@ var (
 	JWTSecret  []byte
 	JWTTTLMin  int
 	SQLitePath string
+	// InternalToken - общий секрет для межсервисных вызовов (заголовок X-Internal-Token)
+	InternalToken string
+	// PeerServices - базовые URL сервисов, которые хранят данные пользователей
+	PeerServices map[string]string
+
+	// Почта и одноразовые ссылки
+	Mailer                   string // smtp, file или log
+	MailFrom                 string
+	SMTPHost                 string
+	SMTPPort                 string
+	SMTPUsername             string
+	SMTPPassword             string
+	MailFilePath             string
+	AppBaseURL               string // адрес фронтенда для ссылок в письмах
+	EmailVerifyTTLMin        int
+	PasswordResetTTLMin      int
+	RequireEmailVerification bool
+
+	// Двухфакторная аутентификация
+	TOTPIssuer         string
+	MFARequiredRoles   []string // роли, которым 2FA обязательна
+	MFAChallengeTTLMin int
+
+	// Защита от перебора паролей: пороги для учётной записи и для IP
+	LoginBackoffAfter     int
+	LoginLockoutAfter     int
+	LoginIPBackoffAfter   int
+	LoginIPLockoutAfter   int
+	LoginLockoutMin       int
+	LoginFailureWindowMin int
+
+	// OpenID Connect провайдер
+	OIDCIssuer     string // внешний адрес auth-service, попадает в iss и discovery
+	OIDCKeyPath    string // PEM-ключ RS256 для ID-токенов, создаётся при первом запуске
+	OIDCCodeTTLSec int
+
+	// Вход через внешние OpenID Connect провайдеры
+	SSOProviders   []SSOProvider
+	SSOStateTTLMin int
+
+	// Персональные API-ключи
+	APIKeyDefaultTTLDays int
+	APIKeyMaxTTLDays     int
+	APIKeyMaxPerUser     int
+
+	// Вход администратора от имени пользователя
+	ImpersonationTTLMin int
+
+	// Приглашения пользователей
+	InvitationTTLHours int
+
+	// Выгрузка персональных данных
+	DataExportDir      string
+	DataExportTTLHours int
+
+	// Пароли: алгоритм хеширования и политика
+	PasswordHash             string // argon2id или bcrypt
+	BcryptCost               int
+	Argon2MemoryKB           int
+	Argon2Iterations         int
+	Argon2Parallelism        int
+	PasswordMinLength        int
+	PasswordMaxLength        int
+	PasswordRequiredClasses  []string // lower, upper, letter, digit, symbol
+	PasswordHistory          int      // новый пароль не должен совпадать с N последними, включая текущий
+	PasswordBreachedListPath string   // SHA-1 утёкших паролей, по строке (формат HIBP)
 )
 
+// SSOProvider - внешний провайдер из SSO_PROVIDERS, параметры в SSO_<NAME>_*
+type SSOProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+}
+
 func init() {
 	execDir, err := os.Executable()
 	if err != nil {
@@ -54,5 +131,123 @@ func init() {
 		SQLitePath = "auth.db"
 	}
 
+	InternalToken = os.Getenv("INTERNAL_API_TOKEN")
+	if InternalToken == "" {
+		log.Println("⚠️ INTERNAL_API_TOKEN is not set, protected internal endpoints will reject all calls")
+	}
+
+	PeerServices = map[string]string{
+		"user-service":      envOrDefault("USER_SERVICE_URL", "http://localhost:8085"),
+		"project-service":   envOrDefault("PROJECT_SERVICE_URL", "http://localhost:8082"),
+		"portfolio-service": envOrDefault("PORTFOLIO_SERVICE_URL", "http://localhost:8083"),
+		"contact-service":   envOrDefault("CONTACT_SERVICE_URL", "http://localhost:8084"),
+	}
+
+	Mailer = envOrDefault("MAILER", "log")
+	MailFrom = envOrDefault("MAIL_FROM", "no-reply@localhost")
+	SMTPHost = envOrDefault("SMTP_HOST", "localhost")
+	SMTPPort = envOrDefault("SMTP_PORT", "25")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	MailFilePath = envOrDefault("MAIL_FILE_PATH", "mail.log")
+	AppBaseURL = envOrDefault("APP_BASE_URL", "http://localhost:3000")
+	EmailVerifyTTLMin = intEnv("EMAIL_VERIFY_TTL_MINUTES", 24*60)
+	PasswordResetTTLMin = intEnv("PASSWORD_RESET_TTL_MINUTES", 30)
+	RequireEmailVerification = os.Getenv("REQUIRE_EMAIL_VERIFICATION") != "false"
+
+	TOTPIssuer = envOrDefault("TOTP_ISSUER", "ooolalex")
+	MFARequiredRoles = listEnv("MFA_REQUIRED_ROLES", "admin")
+	MFAChallengeTTLMin = intEnv("MFA_CHALLENGE_TTL_MINUTES", 5)
+
+	LoginBackoffAfter = intEnv("LOGIN_BACKOFF_AFTER", 3)
+	LoginLockoutAfter = intEnv("LOGIN_LOCKOUT_AFTER", 10)
+	LoginIPBackoffAfter = intEnv("LOGIN_IP_BACKOFF_AFTER", 10)
+	LoginIPLockoutAfter = intEnv("LOGIN_IP_LOCKOUT_AFTER", 50)
+	LoginLockoutMin = intEnv("LOGIN_LOCKOUT_MINUTES", 15)
+	LoginFailureWindowMin = intEnv("LOGIN_FAILURE_WINDOW_MINUTES", 15)
+
+	OIDCIssuer = strings.TrimRight(envOrDefault("OIDC_ISSUER", "http://localhost:"+Port), "/")
+	OIDCKeyPath = envOrDefault("OIDC_KEY_PATH", "oidc_key.pem")
+	OIDCCodeTTLSec = intEnv("OIDC_CODE_TTL_SECONDS", 60)
+
+	for _, name := range listEnv("SSO_PROVIDERS", "") {
+		SSOProviders = append(SSOProviders, ssoProviderEnv(strings.ToLower(name)))
+	}
+	SSOStateTTLMin = intEnv("SSO_STATE_TTL_MINUTES", 10)
+
+	APIKeyDefaultTTLDays = intEnv("API_KEY_DEFAULT_TTL_DAYS", 90)
+	APIKeyMaxTTLDays = intEnv("API_KEY_MAX_TTL_DAYS", 365)
+	APIKeyMaxPerUser = intEnv("API_KEY_MAX_PER_USER", 20)
+
+	ImpersonationTTLMin = intEnv("IMPERSONATION_TTL_MINUTES", 15)
+
+	InvitationTTLHours = intEnv("INVITATION_TTL_HOURS", 72)
+
+	DataExportDir = envOrDefault("DATA_EXPORT_DIR", "exports")
+	DataExportTTLHours = intEnv("DATA_EXPORT_TTL_HOURS", 24)
+
+	PasswordHash = envOrDefault("PASSWORD_HASH", "argon2id")
+	BcryptCost = intEnv("BCRYPT_COST", 12)
+	Argon2MemoryKB = intEnv("ARGON2_MEMORY_KB", 64*1024)
+	Argon2Iterations = intEnv("ARGON2_ITERATIONS", 3)
+	Argon2Parallelism = intEnv("ARGON2_PARALLELISM", 2)
+	PasswordMinLength = intEnv("PASSWORD_MIN_LENGTH", 8)
+	PasswordMaxLength = intEnv("PASSWORD_MAX_LENGTH", 128)
+	PasswordRequiredClasses = listEnv("PASSWORD_REQUIRED_CLASSES", "letter,digit")
+	PasswordHistory = intEnv("PASSWORD_HISTORY", 5)
+	PasswordBreachedListPath = os.Getenv("PASSWORD_BREACHED_LIST")
+
 	log.Printf("✅ Config loaded: PORT=%s | TTL=%d min | DB=%s", Port, JWTTTLMin, SQLitePath)
 }
+
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func intEnv(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("❌ Invalid %s value: %v", key, err)
+	}
+	return n
+}
+
+// ssoProviderEnv читает SSO_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _SCOPES и _REDIRECT_URL.
+// По умолчанию провайдер возвращает пользователя на callback самого auth-service.
+func ssoProviderEnv(name string) SSOProvider {
+	prefix := "SSO_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	p := SSOProvider{
+		Name:         name,
+		Issuer:       strings.TrimRight(os.Getenv(prefix+"ISSUER"), "/"),
+		ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		Scopes:       listEnv(prefix+"SCOPES", "openid,email,profile"),
+		RedirectURL:  envOrDefault(prefix+"REDIRECT_URL", OIDCIssuer+"/api/v1/sso/"+name+"/callback"),
+	}
+	if p.Issuer == "" || p.ClientID == "" {
+		log.Fatalf("❌ SSO provider %q requires %sISSUER and %sCLIENT_ID", name, prefix, prefix)
+	}
+	return p
+}
+
+// listEnv читает список через запятую; переменная, заданная пустой строкой, даёт пустой список
+func listEnv(key, def string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		v = def
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
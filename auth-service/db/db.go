@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"log"
 
 	"auth-service/config"
@@ -19,23 +20,38 @@ func Init() {
 	}
 	DB = d
 
-	// Automigrate models
-	if err := DB.AutoMigrate(&models.User{}); err != nil {
+	// Учётные записи, созданные до появления подтверждения email, считаем подтверждёнными
+	backfillVerified := DB.Migrator().HasTable(&models.User{}) &&
+		!DB.Migrator().HasColumn(&models.User{}, "EmailVerified")
+
+	// Automigrate models (список в models.All)
+	if err := DB.AutoMigrate(models.All()...); err != nil {
 		log.Fatalf("auto migrate failed: %v", err)
 	}
 
+	if err := SeedRBAC(DB); err != nil {
+		log.Fatalf("rbac seed failed: %v", err)
+	}
+
+	if backfillVerified {
+		if err := DB.Model(&models.User{}).Where("1 = 1").Update("email_verified", true).Error; err != nil {
+			log.Fatalf("email_verified backfill failed: %v", err)
+		}
+	}
+
 	// Seed admin if not exists
 	seedAdmin()
 }
 
 func seedAdmin() {
 	var count int64
-	DB.Model(&models.User{}).Where("role = ?", "admin").Count(&count)
+	DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&count)
 	if count == 0 {
 		admin := models.User{
-			Email:    "admin@example.com",
-			FullName: "Admin",
-			Role:     "admin",
+			Email:         "admin@example.com",
+			FullName:      "Admin",
+			Role:          models.RoleAdmin,
+			EmailVerified: true,
 		}
 		// password: admin123 (hashed)
 		if err := admin.SetPassword("admin123"); err != nil {
@@ -46,6 +62,58 @@ func seedAdmin() {
 			log.Printf("failed to insert admin seed: %v", err)
 			return
 		}
-		log.Println("✅ Admin seeded: admin@example.com / admin123")
+		log.Println("✅ Admin seeded: admin@example.com / admin123 - change the password, 2FA setup is required on first login")
 	}
 }
+
+// SeedRBAC создаёт встроенные права и роли. Роль admin при каждом запуске
+// получает полный набор прав, чтобы новые права не требовали ручной миграции.
+func SeedRBAC(d *gorm.DB) error {
+	return d.Transaction(func(tx *gorm.DB) error {
+		for _, p := range models.BuiltinPermissions {
+			perm := p
+			if err := tx.Where(models.Permission{Name: perm.Name}).Attrs(perm).FirstOrCreate(&perm).Error; err != nil {
+				return err
+			}
+		}
+		var all []models.Permission
+		if err := tx.Find(&all).Error; err != nil {
+			return err
+		}
+		byName := make(map[string]models.Permission, len(all))
+		for _, p := range all {
+			byName[p.Name] = p
+		}
+
+		for _, br := range models.BuiltinRoles {
+			var role models.Role
+			err := tx.Where("name = ?", br.Name).First(&role).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			created := errors.Is(err, gorm.ErrRecordNotFound)
+			if created {
+				role = models.Role{Name: br.Name, Description: br.Description, System: br.System}
+				if err := tx.Create(&role).Error; err != nil {
+					return err
+				}
+			}
+			var perms []models.Permission
+			switch {
+			case br.Name == models.RoleAdmin:
+				perms = all
+			case created:
+				for _, name := range br.Permissions {
+					perms = append(perms, byName[name])
+				}
+			}
+			if len(perms) == 0 {
+				continue
+			}
+			if err := tx.Model(&role).Association("Permissions").Replace(perms); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
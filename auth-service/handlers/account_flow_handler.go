@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"auth-service/repositories"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type emailReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type verifyEmailReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type resetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// VerifyEmail accepts the token either as JSON body (POST) or as ?token= (GET link)
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if c.Request.Method == http.MethodPost {
+		var body verifyEmailReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		token = body.Token
+	}
+	if token == "" {
+		utils.JSONError(c, http.StatusBadRequest, "token is required")
+		return
+	}
+	u, err := h.accounts.VerifyEmail(token)
+	if errors.Is(err, repositories.ErrTokenInvalid) {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to verify email")
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"email": u.Email, "email_verified": true})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var body emailReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.accounts.ResendVerification(body.Email); err != nil {
+		log.Printf("failed to resend verification email: %v", err)
+	}
+	// одинаковый ответ для любого адреса
+	utils.JSONSuccess(c, http.StatusAccepted, gin.H{"sent": true})
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var body emailReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.accounts.ForgotPassword(body.Email); err != nil {
+		log.Printf("failed to send password reset email: %v", err)
+	}
+	// одинаковый ответ для любого адреса
+	utils.JSONSuccess(c, http.StatusAccepted, gin.H{"sent": true})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var body resetPasswordReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	err := h.accounts.ResetPassword(body.Token, body.Password)
+	if respondPasswordError(c, err) {
+		return
+	}
+	if errors.Is(err, repositories.ErrTokenInvalid) {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to reset password")
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"reset": true})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type suspendUserReq struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until"`
+}
+
+// SuspendUser - admin: temporary or indefinite suspension with a reason
+func (h *UserHandler) SuspendUser(c *gin.Context) {
+	requester, id, ok := adminTarget(c, models.PermUsersManage)
+	if !ok {
+		return
+	}
+	if requester.ID == id {
+		utils.JSONError(c, http.StatusBadRequest, "cannot suspend yourself")
+		return
+	}
+	var body suspendUserReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Until != nil && !body.Until.After(time.Now()) {
+		utils.JSONError(c, http.StatusBadRequest, "until must be in the future")
+		return
+	}
+	u, err := h.repo.FindByID(id)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+	u.Suspend(body.Reason, body.Until)
+	if err := h.repo.Update(u); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if _, err := h.sessions.RevokeAll(u.ID); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, u)
+}
+
+// UnsuspendUser - admin: lift suspension
+func (h *UserHandler) UnsuspendUser(c *gin.Context) {
+	_, id, ok := adminTarget(c, models.PermUsersManage)
+	if !ok {
+		return
+	}
+	u, err := h.repo.FindByID(id)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+	u.Unsuspend()
+	if err := h.repo.Update(u); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, u)
+}
+
+// ListDeletedUsers - admin: soft-deleted accounts, same filters as ListUsers
+func (h *UserHandler) ListDeletedUsers(c *gin.Context) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	if !requester.Can(models.PermUsersManage) {
+		utils.JSONError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	q := c.Request.URL.Query()
+	q.Set("status", repositories.StateDeleted)
+	c.Request.URL.RawQuery = q.Encode()
+	h.listUsers(c)
+}
+
+// RestoreUser - admin: undo soft delete
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	_, id, ok := adminTarget(c, models.PermUsersManage)
+	if !ok {
+		return
+	}
+	u, err := h.repo.FindByIDUnscoped(id)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+	if !u.DeletedAt.Valid {
+		utils.JSONError(c, http.StatusConflict, "user is not deleted")
+		return
+	}
+	if err := h.repo.Restore(u); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, u)
+}
+
+// PurgeUser - admin: anonymize the user's data in every service, then delete the account for good.
+// If any service fails, the account is kept so the purge can be retried.
+func (h *UserHandler) PurgeUser(c *gin.Context) {
+	requester, id, ok := adminTarget(c, models.PermUsersManage)
+	if !ok {
+		return
+	}
+	if requester.ID == id {
+		utils.JSONError(c, http.StatusBadRequest, "cannot purge yourself")
+		return
+	}
+	u, err := h.repo.FindByIDUnscoped(id)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	req, err := h.privacy.Erase(u, requester.ID)
+	if errors.Is(err, services.ErrErasureIncomplete) {
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+			"error":    err.Error(),
+			"services": req.Services,
+		})
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"purged":   true,
+		"id":       u.ID,
+		"services": req.Services,
+	})
+}
+
+// adminTarget проверяет, что запрос от администратора, и разбирает :id
+func adminTarget(c *gin.Context, perm string) (*middleware.ContextUser, uint, bool) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	if !requester.Can(perm) {
+		utils.JSONError(c, http.StatusForbidden, "forbidden")
+		return nil, 0, false
+	}
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid id")
+		return nil, 0, false
+	}
+	return requester, uint(id64), true
+}
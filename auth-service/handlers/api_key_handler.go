@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	users *repositories.UserRepo
+	keys  *services.APIKeyService
+}
+
+func NewAPIKeyHandler() *APIKeyHandler {
+	return &APIKeyHandler{
+		users: repositories.NewUserRepo(),
+		keys:  services.NewAPIKeyService(),
+	}
+}
+
+type createAPIKeyReq struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type apiKeyView struct {
+	models.APIKey
+	Scopes []string `json:"scopes"`
+	Key    string   `json:"key,omitempty"`
+}
+
+func newAPIKeyView(k *models.APIKey) apiKeyView {
+	return apiKeyView{APIKey: *k, Scopes: k.ScopeList()}
+}
+
+func (h *APIKeyHandler) List(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	items, err := h.keys.List(uCtx.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := make([]apiKeyView, len(items))
+	for i := range items {
+		out[i] = newAPIKeyView(&items[i])
+	}
+	utils.JSONSuccess(c, http.StatusOK, out)
+}
+
+// Create - ключ показывается только в этом ответе
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	var body createAPIKeyReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, err := h.users.FindByID(uCtx.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+	key, plain, err := h.keys.Create(u, body.Name, body.Scopes, body.ExpiresInDays)
+	switch {
+	case errors.Is(err, services.ErrScopeNotAllowed):
+		utils.JSONError(c, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, services.ErrAPIKeyTTL):
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrAPIKeyLimit):
+		utils.JSONError(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	view := newAPIKeyView(key)
+	view.Key = plain
+	utils.JSONSuccess(c, http.StatusCreated, view)
+}
+
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.keys.Revoke(uCtx.ID, uint(id64)); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			utils.JSONError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"revoked": true})
+}
+
+type verifyAPIKeyReq struct {
+	Key string `json:"key" binding:"required"`
+}
+
+// VerifyInternal - проверка ключа для других сервисов (X-Internal-Token).
+// Ответ совпадает с /internal/users/:id/role, permissions уже ограничены scopes ключа.
+func (h *APIKeyHandler) VerifyInternal(c *gin.Context) {
+	var body verifyAPIKeyReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	access, err := h.keys.Authenticate(body.Key)
+	var suspended *services.SuspendedError
+	switch {
+	case errors.As(err, &suspended):
+		utils.JSONError(c, http.StatusForbidden, "account suspended")
+		return
+	case errors.Is(err, services.ErrAPIKeyInvalid):
+		utils.JSONError(c, http.StatusUnauthorized, err.Error())
+		return
+	case err != nil:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"id":          access.User.ID,
+		"role":        access.User.Role,
+		"permissions": access.Permissions,
+		"api_key_id":  access.Key.ID,
+	})
+}
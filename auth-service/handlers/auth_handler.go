@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"auth-service/models"
+	"auth-service/password"
 	"auth-service/services"
 	"auth-service/utils"
 
@@ -10,16 +16,28 @@ import (
 )
 
 type AuthHandler struct {
-	svc *services.AuthService
+	svc      *services.AuthService
+	accounts *services.AccountService
+	mfa      *services.MFAService
+	guard    *services.LoginGuard
+	sessions *services.SessionService
+	sso      *services.SSOService
 }
 
 func NewAuthHandler() *AuthHandler {
-	return &AuthHandler{svc: services.NewAuthService()}
+	return &AuthHandler{
+		svc:      services.NewAuthService(),
+		accounts: services.NewAccountService(),
+		mfa:      services.NewMFAService(),
+		guard:    services.NewLoginGuard(),
+		sessions: services.NewSessionService(),
+		sso:      services.NewSSOService(),
+	}
 }
 
 type registerReq struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // длина и состав - по политике паролей
 	FullName string `json:"full_name"`
 }
 
@@ -35,10 +53,17 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 	u, err := h.svc.Register(body.Email, body.Password, body.FullName)
+	if respondPasswordError(c, err) {
+		return
+	}
 	if err != nil {
 		utils.JSONError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	// письмо не критично для регистрации: его можно запросить повторно
+	if err := h.accounts.SendVerification(u); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", u.ID, err)
+	}
 	// do not return password
 	u.Password = ""
 	utils.JSONSuccess(c, http.StatusCreated, u)
@@ -50,15 +75,99 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		utils.JSONError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if !h.allowAttempt(c, nil, body.Email) {
+		return
+	}
 	u, err := h.svc.Authenticate(body.Email, body.Password)
+	var suspended *services.SuspendedError
+	if errors.As(err, &suspended) {
+		h.audit(c, nil, body.Email, models.LoginSuspended)
+		respondSuspended(c, suspended)
+		return
+	}
+	if errors.Is(err, services.ErrEmailNotVerified) {
+		h.audit(c, nil, body.Email, models.LoginEmailNotVerified)
+		utils.JSONError(c, http.StatusForbidden, err.Error())
+		return
+	}
 	if err != nil {
+		h.recordFailure(c, nil, body.Email, models.LoginBadCredentials)
 		utils.JSONError(c, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
-	token, err := h.svc.GenerateJWT(u)
-	if err != nil {
-		utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+	if h.requireSecondFactor(c, u) {
+		h.audit(c, &u.ID, u.Email, models.LoginMFARequired)
 		return
 	}
-	utils.JSONSuccess(c, http.StatusOK, gin.H{"token": token})
+	h.completeLogin(c, u)
+}
+
+// respondPasswordError отвечает 400 на пароль, не прошедший политику, со списком нарушений.
+// false - err другого рода и ответ не отправлен.
+func respondPasswordError(c *gin.Context, err error) bool {
+	var policy *password.PolicyError
+	switch {
+	case errors.As(err, &policy):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": policy.Error(), "violations": policy.Violations})
+	case errors.Is(err, services.ErrPasswordReused):
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+func respondSuspended(c *gin.Context, suspended *services.SuspendedError) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":           suspended.Error(),
+		"reason":          suspended.Reason,
+		"suspended_until": suspended.Until,
+	})
+}
+
+// allowAttempt отклоняет попытку с 429, если учётная запись или IP под ограничением
+func (h *AuthHandler) allowAttempt(c *gin.Context, userID *uint, email string) bool {
+	err := h.guard.Check(email, c.ClientIP())
+	if err == nil {
+		return true
+	}
+	te, ok := services.IsThrottled(err)
+	if !ok {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to check login attempts")
+		return false
+	}
+	h.audit(c, userID, email, models.LoginThrottled)
+	retry := int(te.RetryAfter.Round(time.Second) / time.Second)
+	if retry < 1 {
+		retry = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retry))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       te.Error(),
+		"locked":      te.Locked,
+		"retry_after": retry,
+	})
+	return false
+}
+
+func (h *AuthHandler) recordFailure(c *gin.Context, userID *uint, email, reason string) {
+	if err := h.guard.RecordFailure(email, c.ClientIP()); err != nil {
+		log.Printf("failed to record login failure: %v", err)
+	}
+	h.audit(c, userID, email, reason)
+}
+
+// completeLogin сбрасывает счётчик ошибок и выдаёт JWT
+func (h *AuthHandler) completeLogin(c *gin.Context, u *models.User) {
+	if err := h.guard.RecordSuccess(u.Email); err != nil {
+		log.Printf("failed to reset login failures: %v", err)
+	}
+	h.audit(c, &u.ID, u.Email, models.LoginOK)
+	h.issueToken(c, u)
+}
+
+func (h *AuthHandler) audit(c *gin.Context, userID *uint, email, reason string) {
+	if err := h.guard.Audit(userID, email, c.ClientIP(), c.Request.UserAgent(), reason); err != nil {
+		log.Printf("failed to write login audit: %v", err)
+	}
 }
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonationHandler - вход администратора от имени пользователя и журнал таких входов
+type ImpersonationHandler struct {
+	svc *services.ImpersonationService
+}
+
+func NewImpersonationHandler() *ImpersonationHandler {
+	return &ImpersonationHandler{svc: services.NewImpersonationService()}
+}
+
+type impersonateReq struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// Start выдаёт токен пользователя :id с claim "act" (users.impersonate)
+func (h *ImpersonationHandler) Start(c *gin.Context) {
+	requester, id, ok := adminTarget(c, models.PermUsersImpersonate)
+	if !ok {
+		return
+	}
+	var body impersonateReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	res, err := h.svc.Start(requester.ID, id, body.Reason, c.ClientIP(), c.Request.UserAgent())
+	var suspended *services.SuspendedError
+	switch {
+	case errors.As(err, &suspended):
+		respondSuspended(c, suspended)
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	case errors.Is(err, services.ErrImpersonationReason),
+		errors.Is(err, services.ErrImpersonateSelf):
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrImpersonatePrivileged):
+		utils.JSONError(c, http.StatusForbidden, err.Error())
+		return
+	case err != nil:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusCreated, gin.H{
+		"token":         res.Token,
+		"expires_at":    res.Impersonation.ExpiresAt,
+		"impersonation": res.Impersonation,
+	})
+}
+
+// End вызывается с токеном имперсонации и отзывает его
+func (h *ImpersonationHandler) End(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	if !uCtx.Impersonated() {
+		utils.JSONError(c, http.StatusBadRequest, "not impersonating")
+		return
+	}
+	imp, err := h.svc.End(uCtx.SessionID, uCtx.ActorID)
+	switch {
+	case errors.Is(err, services.ErrImpersonationNotFound):
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrImpersonationForbidden):
+		utils.JSONError(c, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, services.ErrImpersonationFinished):
+		utils.JSONError(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, imp)
+}
+
+// List - журнал имперсонаций (audit.read), фильтры actor_id и user_id
+func (h *ImpersonationHandler) List(c *gin.Context) {
+	page := 1
+	size := 50
+	if p := c.Query("page"); p != "" {
+		if pv, err := strconv.Atoi(p); err == nil && pv > 0 {
+			page = pv
+		}
+	}
+	if s := c.Query("size"); s != "" {
+		if sv, err := strconv.Atoi(s); err == nil && sv > 0 && sv <= 200 {
+			size = sv
+		}
+	}
+	var filter repositories.ImpersonationFilter
+	for param, dst := range map[string]**uint{"actor_id": &filter.ActorID, "user_id": &filter.UserID} {
+		if v := c.Query(param); v != "" {
+			id64, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				utils.JSONError(c, http.StatusBadRequest, "invalid "+param)
+				return
+			}
+			id := uint(id64)
+			*dst = &id
+		}
+	}
+	items, total, err := h.svc.List(filter, (page-1)*size, size)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+		"size":  size,
+	})
+}
+
+// Get - имперсонация со всеми действиями во всех сервисах (audit.read)
+func (h *ImpersonationHandler) Get(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	imp, actions, err := h.svc.Get(uint(id64))
+	if errors.Is(err, services.ErrImpersonationNotFound) {
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"impersonation": imp, "actions": actions})
+}
+
+type recordActionReq struct {
+	SID     string `json:"sid" binding:"required"`
+	ActorID uint   `json:"actor_id" binding:"required"`
+	UserID  uint   `json:"user_id" binding:"required"`
+	Service string `json:"service" binding:"required"`
+	Method  string `json:"method" binding:"required"`
+	Path    string `json:"path" binding:"required"`
+	Status  int    `json:"status"`
+	IP      string `json:"ip"`
+}
+
+// RecordInternal принимает действия, выполненные во время имперсонации в других сервисах
+func (h *ImpersonationHandler) RecordInternal(c *gin.Context) {
+	var body recordActionReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	err := h.svc.RecordAction(body.SID, &models.ImpersonationAction{
+		ActorID: body.ActorID,
+		UserID:  body.UserID,
+		Service: body.Service,
+		Method:  body.Method,
+		Path:    body.Path,
+		Status:  body.Status,
+		IP:      body.IP,
+	})
+	if errors.Is(err, services.ErrImpersonationNotFound) {
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusCreated, gin.H{"recorded": true})
+}
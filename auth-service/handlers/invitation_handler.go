@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationHandler - приглашения пользователей (users.manage, roles.manage для ролей кроме user)
+type InvitationHandler struct {
+	svc  *services.InvitationService
+	rbac *services.RBACService
+}
+
+func NewInvitationHandler() *InvitationHandler {
+	return &InvitationHandler{
+		svc:  services.NewInvitationService(),
+		rbac: services.NewRBACService(),
+	}
+}
+
+type inviteReq struct {
+	Email    string `json:"email" binding:"required,email"`
+	FullName string `json:"full_name"`
+	Role     string `json:"role"`
+}
+
+type invitationView struct {
+	models.Invitation
+	Status string `json:"status"`
+}
+
+func newInvitationView(i *models.Invitation) invitationView {
+	return invitationView{Invitation: *i, Status: i.Status(time.Now())}
+}
+
+// Create приглашает пользователя. Пароль администратор не задаёт: его выбирает приглашённый.
+func (h *InvitationHandler) Create(c *gin.Context) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	if !requester.Can(models.PermUsersManage) {
+		utils.JSONError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	var body inviteReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Role == "" {
+		body.Role = models.RoleUser
+	}
+	if body.Role != models.RoleUser && !requester.Can(models.PermRolesManage) {
+		utils.JSONError(c, http.StatusForbidden, "permission required: "+models.PermRolesManage)
+		return
+	}
+	if err := h.rbac.ValidateRole(body.Role); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid role")
+		return
+	}
+	inv, err := h.svc.Invite(requester.ID, body.Email, body.FullName, body.Role)
+	if errors.Is(err, services.ErrInvitationNotSent) {
+		log.Printf("invitation %d: %v", inv.ID, err)
+		utils.JSONSuccess(c, http.StatusCreated, gin.H{"invitation": newInvitationView(inv), "email_sent": false})
+		return
+	}
+	if err != nil {
+		invitationError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusCreated, gin.H{"invitation": newInvitationView(inv), "email_sent": true})
+}
+
+// List - приглашения, по умолчанию действующие; ?status=pending|accepted|revoked|expired|all
+func (h *InvitationHandler) List(c *gin.Context) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	if !requester.Can(models.PermUsersManage) {
+		utils.JSONError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	status := c.DefaultQuery("status", models.InvitationPending)
+	switch status {
+	case "all":
+		status = ""
+	case models.InvitationPending, models.InvitationAccepted, models.InvitationRevoked, models.InvitationExpired:
+	default:
+		utils.JSONError(c, http.StatusBadRequest, "invalid status, expected pending, accepted, revoked, expired or all")
+		return
+	}
+	page := 1
+	size := 50
+	if p := c.Query("page"); p != "" {
+		if pv, err := strconv.Atoi(p); err == nil && pv > 0 {
+			page = pv
+		}
+	}
+	if s := c.Query("size"); s != "" {
+		if sv, err := strconv.Atoi(s); err == nil && sv > 0 && sv <= 200 {
+			size = sv
+		}
+	}
+	items, total, err := h.svc.List(status, (page-1)*size, size)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := make([]invitationView, len(items))
+	for i := range items {
+		out[i] = newInvitationView(&items[i])
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"items": out,
+		"total": total,
+		"page":  page,
+		"size":  size,
+	})
+}
+
+// Resend отправляет новую ссылку, прежняя перестаёт работать
+func (h *InvitationHandler) Resend(c *gin.Context) {
+	_, id, ok := adminTarget(c, models.PermUsersManage)
+	if !ok {
+		return
+	}
+	inv, err := h.svc.Resend(id)
+	if err != nil {
+		invitationError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, newInvitationView(inv))
+}
+
+func (h *InvitationHandler) Revoke(c *gin.Context) {
+	_, id, ok := adminTarget(c, models.PermUsersManage)
+	if !ok {
+		return
+	}
+	inv, err := h.svc.Revoke(id)
+	if err != nil {
+		invitationError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, newInvitationView(inv))
+}
+
+// Lookup - данные приглашения для страницы принятия (?token=)
+func (h *InvitationHandler) Lookup(c *gin.Context) {
+	inv, err := h.svc.Lookup(c.Query("token"))
+	if err != nil {
+		invitationError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"email":      inv.Email,
+		"full_name":  inv.FullName,
+		"role":       inv.Role,
+		"expires_at": inv.ExpiresAt,
+	})
+}
+
+type acceptInvitationReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	FullName string `json:"full_name"`
+}
+
+// Accept создаёт учётную запись с паролем, выбранным приглашённым; дальше обычный /login
+func (h *InvitationHandler) Accept(c *gin.Context) {
+	var body acceptInvitationReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, err := h.svc.Accept(body.Token, body.Password, body.FullName)
+	if respondPasswordError(c, err) {
+		return
+	}
+	if err != nil {
+		invitationError(c, err)
+		return
+	}
+	u.Password = ""
+	utils.JSONSuccess(c, http.StatusCreated, u)
+}
+
+func invitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvitationNotFound):
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvitationInvalid):
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrEmailTaken),
+		errors.Is(err, services.ErrInvitationPending),
+		errors.Is(err, services.ErrInvitationClosed),
+		errors.Is(err, services.ErrInvitationRole):
+		utils.JSONError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvitationNotSent):
+		utils.JSONError(c, http.StatusBadGateway, err.Error())
+	default:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnlockUser - admin: reset failed login counter and lift lockout
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	_, id, ok := adminTarget(c, models.PermUsersManage)
+	if !ok {
+		return
+	}
+	u, err := h.repo.FindByID(id)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+	if err := h.guard.Unlock(u.Email); err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"user_id": u.ID, "unlocked": true})
+}
+
+// LoginStatus - admin: current failed login counter of the account
+func (h *UserHandler) LoginStatus(c *gin.Context) {
+	_, id, ok := adminTarget(c, models.PermAuditRead)
+	if !ok {
+		return
+	}
+	u, err := h.repo.FindByID(id)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+	t, err := h.guard.Status(u.Email)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"user_id":         u.ID,
+		"failures":        t.Failures,
+		"last_failure_at": t.LastFailureAt,
+		"locked_until":    t.LockedUntil,
+	})
+}
+
+// ListLoginAttempts - admin: login audit log.
+// Фильтры: user_id, email, ip, success (true|false), from, to
+func (h *UserHandler) ListLoginAttempts(c *gin.Context) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	if !requester.Can(models.PermAuditRead) {
+		utils.JSONError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	page := 1
+	size := 50
+	if p := c.Query("page"); p != "" {
+		if pv, err := strconv.Atoi(p); err == nil && pv > 0 {
+			page = pv
+		}
+	}
+	if s := c.Query("size"); s != "" {
+		if sv, err := strconv.Atoi(s); err == nil && sv > 0 && sv <= 200 {
+			size = sv
+		}
+	}
+	filter, err := parseAttemptFilter(c)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	items, total, err := h.guard.Attempts(filter, (page-1)*size, size)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+		"size":  size,
+	})
+}
+
+func parseAttemptFilter(c *gin.Context) (repositories.AttemptFilter, error) {
+	f := repositories.AttemptFilter{
+		Email: strings.ToLower(strings.TrimSpace(c.Query("email"))),
+		IP:    c.Query("ip"),
+	}
+	if v := c.Query("user_id"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return f, errors.New("invalid user_id")
+		}
+		id := uint(id64)
+		f.UserID = &id
+	}
+	if v := c.Query("success"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, errors.New("invalid success, expected true or false")
+		}
+		f.Success = &b
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := parseFilterTime(v, false)
+		if err != nil {
+			return f, errors.New("invalid from, expected RFC3339 or YYYY-MM-DD")
+		}
+		f.From = &t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseFilterTime(v, true)
+		if err != nil {
+			return f, errors.New("invalid to, expected RFC3339 or YYYY-MM-DD")
+		}
+		f.To = &t
+	}
+	return f, nil
+}
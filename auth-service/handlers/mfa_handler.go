@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type challengeReq struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+type challengeCodeReq struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type mfaCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// LoginMFA - second login step: exchange challenge token and TOTP/recovery code for a JWT
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var body challengeCodeReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, err := h.mfa.ParseChallenge(body.ChallengeToken, services.ChallengeVerify)
+	if err != nil {
+		utils.JSONError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if !h.allowAttempt(c, &u.ID, u.Email) {
+		return
+	}
+	if err := h.mfa.Verify(u, body.Code); err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			h.recordFailure(c, &u.ID, u.Email, models.LoginBadMFACode)
+		}
+		mfaError(c, err)
+		return
+	}
+	h.completeLogin(c, u)
+}
+
+// LoginEnroll - first login of a user whose role requires 2FA: start TOTP setup
+func (h *AuthHandler) LoginEnroll(c *gin.Context) {
+	var body challengeReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, err := h.mfa.ParseChallenge(body.ChallengeToken, services.ChallengeEnroll)
+	if err != nil {
+		utils.JSONError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	secret, uri, err := h.mfa.BeginSetup(u)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"secret": secret, "otpauth_uri": uri})
+}
+
+// LoginEnrollConfirm - confirm the first code, enable 2FA and finish login
+func (h *AuthHandler) LoginEnrollConfirm(c *gin.Context) {
+	var body challengeCodeReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, err := h.mfa.ParseChallenge(body.ChallengeToken, services.ChallengeEnroll)
+	if err != nil {
+		utils.JSONError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if !h.allowAttempt(c, &u.ID, u.Email) {
+		return
+	}
+	codes, err := h.mfa.Confirm(u, body.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			h.recordFailure(c, &u.ID, u.Email, models.LoginBadMFACode)
+		}
+		mfaError(c, err)
+		return
+	}
+	token, err := h.startSession(c, u)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	if err := h.guard.RecordSuccess(u.Email); err != nil {
+		log.Printf("failed to reset login failures: %v", err)
+	}
+	h.audit(c, &u.ID, u.Email, models.LoginOK)
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"token": token, "recovery_codes": codes})
+}
+
+// requireSecondFactor отвечает challenge-токеном, если для входа нужен второй фактор
+func (h *AuthHandler) requireSecondFactor(c *gin.Context, u *models.User) bool {
+	if !u.TOTPEnabled && !h.mfa.Required(u) {
+		return false
+	}
+	purpose := services.ChallengeVerify
+	if !u.TOTPEnabled {
+		purpose = services.ChallengeEnroll
+	}
+	challenge, err := h.mfa.IssueChallenge(u, purpose)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate challenge")
+		return true
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"mfa_required":        true,
+		"enrollment_required": !u.TOTPEnabled,
+		"challenge_token":     challenge,
+	})
+	return true
+}
+
+func (h *AuthHandler) issueToken(c *gin.Context, u *models.User) {
+	token, err := h.startSession(c, u)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"token": token})
+}
+
+// startSession записывает сессию устройства и выдаёт привязанный к ней JWT
+func (h *AuthHandler) startSession(c *gin.Context, u *models.User) (string, error) {
+	sess, err := h.sessions.Start(u.ID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		return "", err
+	}
+	return h.svc.GenerateJWT(u, sess.SID)
+}
+
+// MFAHandler - управление 2FA текущим пользователем
+type MFAHandler struct {
+	repo *repositories.UserRepo
+	mfa  *services.MFAService
+}
+
+func NewMFAHandler() *MFAHandler {
+	return &MFAHandler{
+		repo: repositories.NewUserRepo(),
+		mfa:  services.NewMFAService(),
+	}
+}
+
+func (h *MFAHandler) Status(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	left, err := h.mfa.RecoveryCodesLeft(u)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"enabled":             u.TOTPEnabled,
+		"required":            h.mfa.Required(u),
+		"recovery_codes_left": left,
+	})
+}
+
+func (h *MFAHandler) Setup(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	secret, uri, err := h.mfa.BeginSetup(u)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"secret": secret, "otpauth_uri": uri})
+}
+
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var body mfaCodeReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	codes, err := h.mfa.Confirm(u, body.Code)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"enabled": true, "recovery_codes": codes})
+}
+
+func (h *MFAHandler) Disable(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var body mfaCodeReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.mfa.Disable(u, body.Code); err != nil {
+		mfaError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"enabled": false})
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var body mfaCodeReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	codes, err := h.mfa.RegenerateRecoveryCodes(u, body.Code)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (h *MFAHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	u, err := h.repo.FindByID(uCtx.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return nil, false
+	}
+	return u, true
+}
+
+func mfaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		utils.JSONError(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrMFARequired):
+		utils.JSONError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrMFAAlreadyEnabled),
+		errors.Is(err, services.ErrMFANotEnabled),
+		errors.Is(err, services.ErrMFASetupNotStarted):
+		utils.JSONError(c, http.StatusConflict, err.Error())
+	default:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
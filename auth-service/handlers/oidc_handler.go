@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"auth-service/middleware"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCHandler - OpenID Connect провайдер для собственных приложений (SPA, мобильное).
+// Ответы протокольных эндпоинтов (token, userinfo, discovery) не оборачиваются в {"data": ...}:
+// их формат задан спецификацией и его ждут стандартные клиентские библиотеки.
+type OIDCHandler struct {
+	svc *services.OIDCService
+}
+
+func NewOIDCHandler() *OIDCHandler {
+	return &OIDCHandler{svc: services.NewOIDCService()}
+}
+
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.Discovery())
+}
+
+func (h *OIDCHandler) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.JWKS())
+}
+
+// Authorize выдаёт код пользователю, уже вошедшему через /login (Bearer-токен).
+// GET отвечает 302 на redirect_uri, POST возвращает адрес в JSON - так удобнее SPA,
+// которое держит токен в памяти и не может добавить заголовок к навигации браузера.
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	req := services.AuthorizeRequest{
+		ResponseType:        c.Request.FormValue("response_type"),
+		ClientID:            c.Request.FormValue("client_id"),
+		RedirectURI:         c.Request.FormValue("redirect_uri"),
+		Scope:               c.Request.FormValue("scope"),
+		State:               c.Request.FormValue("state"),
+		Nonce:               c.Request.FormValue("nonce"),
+		CodeChallenge:       c.Request.FormValue("code_challenge"),
+		CodeChallengeMethod: c.Request.FormValue("code_challenge_method"),
+	}
+
+	location, err := h.svc.Authorize(req, uCtx.ID)
+	var redirectErr *services.AuthorizeRedirectError
+	if errors.As(err, &redirectErr) {
+		location, err = redirectErr.RedirectURI, nil
+	}
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+	if c.Request.Method == http.MethodGet {
+		c.Redirect(http.StatusFound, location)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"redirect_uri": location})
+}
+
+func (h *OIDCHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	req := services.TokenRequest{
+		GrantType:    c.PostForm("grant_type"),
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		ClientID:     c.PostForm("client_id"),
+		ClientSecret: c.PostForm("client_secret"),
+		CodeVerifier: c.PostForm("code_verifier"),
+		IP:           c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+	}
+	// client_secret_basic
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+
+	resp, err := h.svc.Exchange(req)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	info, err := h.svc.UserInfo(uCtx.ID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func oauthError(c *gin.Context, err error) {
+	var oe *services.OAuthError
+	if errors.As(err, &oe) {
+		c.AbortWithStatusJSON(oe.Status, gin.H{"error": oe.Code, "error_description": oe.Description})
+		return
+	}
+	log.Printf("oidc: %v", err)
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+}
+
+// Client registration (oauth_clients.manage)
+
+type createOAuthClientReq struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required"`
+	// по умолчанию публичный клиент (SPA, мобильное приложение)
+	Public *bool `json:"public"`
+}
+
+type updateOAuthClientReq struct {
+	RedirectURIs []string `json:"redirect_uris" binding:"required"`
+}
+
+type oauthClientView struct {
+	ClientID     string   `json:"client_id"`
+	Name         string   `json:"name"`
+	Public       bool     `json:"public"`
+	RedirectURIs []string `json:"redirect_uris"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+}
+
+func (h *OIDCHandler) ListClients(c *gin.Context) {
+	clients, err := h.svc.ListClients()
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := make([]oauthClientView, len(clients))
+	for i, cl := range clients {
+		out[i] = oauthClientView{ClientID: cl.ClientID, Name: cl.Name, Public: cl.Public, RedirectURIs: cl.RedirectURIList()}
+	}
+	utils.JSONSuccess(c, http.StatusOK, out)
+}
+
+// CreateClient - секрет конфиденциального клиента возвращается только в этом ответе
+func (h *OIDCHandler) CreateClient(c *gin.Context) {
+	var body createOAuthClientReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	public := body.Public == nil || *body.Public
+	cl, secret, err := h.svc.RegisterClient(body.Name, body.RedirectURIs, public)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusCreated, oauthClientView{
+		ClientID:     cl.ClientID,
+		Name:         cl.Name,
+		Public:       cl.Public,
+		RedirectURIs: cl.RedirectURIList(),
+		ClientSecret: secret,
+	})
+}
+
+func (h *OIDCHandler) UpdateClient(c *gin.Context) {
+	var body updateOAuthClientReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	cl, err := h.svc.UpdateRedirectURIs(c.Param("client_id"), body.RedirectURIs)
+	if errors.Is(err, services.ErrOAuthClientNotFound) {
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, oauthClientView{ClientID: cl.ClientID, Name: cl.Name, Public: cl.Public, RedirectURIs: cl.RedirectURIList()})
+}
+
+func (h *OIDCHandler) DeleteClient(c *gin.Context) {
+	err := h.svc.DeleteClient(c.Param("client_id"))
+	if errors.Is(err, services.ErrOAuthClientNotFound) {
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"deleted": true})
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrivacyHandler - выгрузка и удаление персональных данных самим пользователем
+type PrivacyHandler struct {
+	svc   *services.PrivacyService
+	users *repositories.UserRepo
+}
+
+func NewPrivacyHandler() *PrivacyHandler {
+	return &PrivacyHandler{
+		svc:   services.NewPrivacyService(),
+		users: repositories.NewUserRepo(),
+	}
+}
+
+type dataRequestView struct {
+	models.DataRequest
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func newDataRequestView(r *models.DataRequest) dataRequestView {
+	v := dataRequestView{DataRequest: *r}
+	if r.Downloadable(time.Now()) {
+		v.DownloadURL = fmt.Sprintf("/api/v1/me/data-export/%d", r.ID)
+	}
+	return v
+}
+
+// Export собирает данные пользователя из всех сервисов в архив для скачивания
+func (h *PrivacyHandler) Export(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	req, err := h.svc.Export(u, u.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusCreated, newDataRequestView(req))
+}
+
+// List - выгрузки и запросы на удаление пользователя
+func (h *PrivacyHandler) List(c *gin.Context) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	items, err := h.svc.List(requester.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := make([]dataRequestView, len(items))
+	for i := range items {
+		out[i] = newDataRequestView(&items[i])
+	}
+	utils.JSONSuccess(c, http.StatusOK, out)
+}
+
+// Download отдаёт zip-архив выгрузки
+func (h *PrivacyHandler) Download(c *gin.Context) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	req, err := h.svc.Archive(requester.ID, uint(id))
+	switch {
+	case errors.Is(err, services.ErrDataRequestNotFound):
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrDataExportExpired):
+		utils.JSONError(c, http.StatusGone, err.Error())
+		return
+	case err != nil:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.FileAttachment(req.File, fmt.Sprintf("data-export-%d.zip", req.ID))
+}
+
+type eraseReq struct {
+	Password string `json:"password"` // если у учётной записи есть пароль
+	Confirm  string `json:"confirm"`  // email, если вход только через внешних провайдеров
+}
+
+// Erase удаляет учётную запись и обезличивает данные пользователя во всех сервисах.
+// Требует пароль, а при входе только через внешних провайдеров - email в confirm.
+func (h *PrivacyHandler) Erase(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var body eraseReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if u.Password != "" {
+		if ok, _ := u.CheckPassword(body.Password); !ok {
+			utils.JSONError(c, http.StatusUnauthorized, "invalid password")
+			return
+		}
+	} else if body.Confirm != u.Email {
+		utils.JSONError(c, http.StatusBadRequest, "confirm must match your email")
+		return
+	}
+	req, err := h.svc.Erase(u, u.ID)
+	if errors.Is(err, services.ErrErasureIncomplete) {
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+			"error":    err.Error(),
+			"services": req.Services,
+		})
+		return
+	}
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{
+		"erased":   true,
+		"id":       u.ID,
+		"services": req.Services,
+	})
+}
+
+func (h *PrivacyHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	u, err := h.users.FindByID(requester.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return nil, false
+	}
+	return u, true
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandler struct {
+	rbac *services.RBACService
+}
+
+func NewRoleHandler() *RoleHandler {
+	return &RoleHandler{rbac: services.NewRBACService()}
+}
+
+type createRoleReq struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type updateRoleReq struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	perms, err := h.rbac.ListPermissions()
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, perms)
+}
+
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbac.ListRoles()
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, roles)
+}
+
+func (h *RoleHandler) GetRole(c *gin.Context) {
+	role, err := h.rbac.GetRole(c.Param("name"))
+	if err != nil {
+		roleError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, role)
+}
+
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var body createRoleReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	role, err := h.rbac.CreateRole(body.Name, body.Description, body.Permissions)
+	if err != nil {
+		roleError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusCreated, role)
+}
+
+// UpdateRole - permissions, если переданы, полностью заменяют набор прав роли
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	var body updateRoleReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Permissions == nil && body.Description == nil {
+		utils.JSONError(c, http.StatusBadRequest, "nothing to update")
+		return
+	}
+	role, err := h.rbac.UpdateRole(c.Param("name"), body.Description, body.Permissions)
+	if err != nil {
+		roleError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, role)
+}
+
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	if err := h.rbac.DeleteRole(c.Param("name")); err != nil {
+		roleError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"deleted": true})
+}
+
+func roleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrRoleExists), errors.Is(err, services.ErrRoleInUse):
+		utils.JSONError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrSystemRole):
+		utils.JSONError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrInvalidRoleName), errors.Is(err, services.ErrUnknownPermission):
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
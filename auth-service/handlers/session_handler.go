@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	users    *repositories.UserRepo
+	sessions *services.SessionService
+}
+
+func NewSessionHandler() *SessionHandler {
+	return &SessionHandler{
+		users:    repositories.NewUserRepo(),
+		sessions: services.NewSessionService(),
+	}
+}
+
+type sessionView struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// MySessions - устройства, на которых выполнен вход; текущая сессия помечена current
+func (h *SessionHandler) MySessions(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	h.list(c, uCtx.ID, uCtx.SessionID)
+}
+
+func (h *SessionHandler) RevokeMySession(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.sessions.Revoke(uCtx.ID, uint(id64)); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			utils.JSONError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"revoked": true})
+}
+
+// UserSessions - admin: active sessions of a user
+func (h *SessionHandler) UserSessions(c *gin.Context) {
+	_, id, ok := adminTarget(c, models.PermUsersRead)
+	if !ok {
+		return
+	}
+	if _, err := h.users.FindByID(id); err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+	h.list(c, id, "")
+}
+
+// RevokeUserSessions - admin: sign the user out everywhere
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	_, id, ok := adminTarget(c, models.PermUsersManage)
+	if !ok {
+		return
+	}
+	if _, err := h.users.FindByID(id); err != nil {
+		utils.JSONError(c, http.StatusNotFound, "user not found")
+		return
+	}
+	n, err := h.sessions.RevokeAll(id)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"revoked": n})
+}
+
+func (h *SessionHandler) list(c *gin.Context, userID uint, currentSID string) {
+	items, err := h.sessions.List(userID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := make([]sessionView, len(items))
+	for i, s := range items {
+		out[i] = sessionView{Session: s, Current: currentSID != "" && s.SID == currentSID}
+	}
+	utils.JSONSuccess(c, http.StatusOK, out)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/services"
+	"auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SSOProviders - провайдеры для кнопок "Войти через ..."
+func (h *AuthHandler) SSOProviders(c *gin.Context) {
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"providers": h.sso.Providers()})
+}
+
+// SSOLogin начинает вход через внешний провайдер.
+// GET отвечает 302 на провайдера, POST возвращает адрес в JSON.
+func (h *AuthHandler) SSOLogin(c *gin.Context) {
+	authURL, err := h.sso.Begin(c.Param("provider"), nil)
+	if err != nil {
+		ssoError(c, err)
+		return
+	}
+	if c.Request.Method == http.MethodGet {
+		c.Redirect(http.StatusFound, authURL)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// SSOCallback принимает code и state: GET - прямой возврат от провайдера,
+// POST - фронтенд, указанный в SSO_<NAME>_REDIRECT_URL, пересылает их сам.
+// Ответ такой же, как у /login, включая запрос второго фактора.
+func (h *AuthHandler) SSOCallback(c *gin.Context) {
+	if e := c.Request.FormValue("error"); e != "" {
+		h.audit(c, nil, "", models.LoginSSOFailed)
+		utils.JSONError(c, http.StatusUnauthorized, "identity provider returned "+e)
+		return
+	}
+	res, err := h.sso.Complete(c.Param("provider"), c.Request.FormValue("state"), c.Request.FormValue("code"), nil)
+	var suspended *services.SuspendedError
+	if errors.As(err, &suspended) {
+		h.audit(c, nil, "", models.LoginSuspended)
+		respondSuspended(c, suspended)
+		return
+	}
+	if err != nil {
+		h.audit(c, nil, "", models.LoginSSOFailed)
+		ssoError(c, err)
+		return
+	}
+	if h.requireSecondFactor(c, res.User) {
+		h.audit(c, &res.User.ID, res.User.Email, models.LoginMFARequired)
+		return
+	}
+	h.completeLogin(c, res.User)
+}
+
+// IdentityHandler - внешние учётные записи текущего пользователя
+type IdentityHandler struct {
+	sso *services.SSOService
+}
+
+func NewIdentityHandler() *IdentityHandler {
+	return &IdentityHandler{sso: services.NewSSOService()}
+}
+
+func (h *IdentityHandler) List(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	items, err := h.sso.Identities(uCtx.ID)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, items)
+}
+
+// Link возвращает адрес провайдера; после возврата фронтенд отправляет code и state
+// в LinkCallback с токеном того же пользователя
+func (h *IdentityHandler) Link(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	authURL, err := h.sso.Begin(c.Param("provider"), &uCtx.ID)
+	if err != nil {
+		ssoError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+type ssoCallbackReq struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+func (h *IdentityHandler) LinkCallback(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	var body ssoCallbackReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	res, err := h.sso.Complete(c.Param("provider"), body.State, body.Code, &uCtx.ID)
+	if err != nil {
+		ssoError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, res.Identity)
+}
+
+func (h *IdentityHandler) Unlink(c *gin.Context) {
+	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.sso.Unlink(uCtx.ID, uint(id64)); err != nil {
+		ssoError(c, err)
+		return
+	}
+	utils.JSONSuccess(c, http.StatusOK, gin.H{"unlinked": true})
+}
+
+func ssoError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownProvider),
+		errors.Is(err, services.ErrIdentityNotFound):
+		utils.JSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrSSOStateInvalid):
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrSSOFailed):
+		// подробности ответа провайдера - только в лог
+		log.Printf("sso: %v", err)
+		utils.JSONError(c, http.StatusUnauthorized, services.ErrSSOFailed.Error())
+	case errors.Is(err, services.ErrProviderEmailNotVerified),
+		errors.Is(err, services.ErrLocalEmailNotVerified):
+		utils.JSONError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrIdentityLinked),
+		errors.Is(err, services.ErrLastLoginMethod):
+		utils.JSONError(c, http.StatusConflict, err.Error())
+	default:
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -1,65 +1,45 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"auth-service/clients"
 	"auth-service/middleware"
 	"auth-service/models"
 	"auth-service/repositories"
+	"auth-service/services"
 	"auth-service/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	repo *repositories.UserRepo
+	repo      *repositories.UserRepo
+	peers     *clients.PeerClient
+	privacy   *services.PrivacyService
+	guard     *services.LoginGuard
+	rbac      *services.RBACService
+	sessions  *services.SessionService
+	passwords *services.PasswordService
 }
 
 func NewUserHandler() *UserHandler {
 	return &UserHandler{
-		repo: repositories.NewUserRepo(),
+		repo:      repositories.NewUserRepo(),
+		peers:     clients.NewPeerClient(),
+		privacy:   services.NewPrivacyService(),
+		guard:     services.NewLoginGuard(),
+		rbac:      services.NewRBACService(),
+		sessions:  services.NewSessionService(),
+		passwords: services.NewPasswordService(),
 	}
 }
 
-type createUserReq struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-	FullName string `json:"full_name"`
-	Role     string `json:"role"`
-}
-
-func (h *UserHandler) CreateUser(c *gin.Context) {
-	var body createUserReq
-	if err := c.ShouldBindJSON(&body); err != nil {
-		utils.JSONError(c, http.StatusBadRequest, err.Error())
-		return
-	}
-	if body.Role != "admin" {
-		body.Role = "user"
-	}
-	// check exists
-	if _, err := h.repo.FindByEmail(body.Email); err == nil {
-		utils.JSONError(c, http.StatusBadRequest, "email already used")
-		return
-	}
-	u := &models.User{
-		Email:    body.Email,
-		FullName: body.FullName,
-		Role:     body.Role,
-	}
-	if err := u.SetPassword(body.Password); err != nil {
-		utils.JSONError(c, http.StatusInternalServerError, "failed to hash password")
-		return
-	}
-	if err := h.repo.Create(u); err != nil {
-		utils.JSONError(c, http.StatusInternalServerError, err.Error())
-		return
-	}
-	u.Password = ""
-	utils.JSONSuccess(c, http.StatusCreated, u)
-}
-
 func (h *UserHandler) GetMe(c *gin.Context) {
 	uCtx := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
 	user, err := h.repo.FindByID(uCtx.ID)
@@ -79,7 +59,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
-	if requester.Role != "admin" && requester.ID != uint(id64) {
+	if !requester.Can(models.PermUsersRead) && requester.ID != uint(id64) {
 		utils.JSONError(c, http.StatusForbidden, "forbidden")
 		return
 	}
@@ -93,6 +73,20 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 }
 
 func (h *UserHandler) ListUsers(c *gin.Context) {
+	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
+	if !requester.Can(models.PermUsersRead) {
+		utils.JSONError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	h.listUsers(c)
+}
+
+// ListUsersInternal - internal endpoint for other services, protected by X-Internal-Token
+func (h *UserHandler) ListUsersInternal(c *gin.Context) {
+	h.listUsers(c)
+}
+
+func (h *UserHandler) listUsers(c *gin.Context) {
 	page := 1
 	size := 20
 	if p := c.Query("page"); p != "" {
@@ -105,8 +99,13 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 			size = sv
 		}
 	}
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	offset := (page - 1) * size
-	users, total, err := h.repo.List(offset, size)
+	users, total, err := h.repo.List(filter, offset, size)
 	if err != nil {
 		utils.JSONError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -122,6 +121,82 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	})
 }
 
+// parseUserFilter читает фильтры и сортировку из query:
+// ids (через запятую, до 100), role, email, q, created_from, created_to,
+// status (active|deleted|all), sort, order (asc|desc)
+func parseUserFilter(c *gin.Context) (repositories.UserFilter, error) {
+	f := repositories.UserFilter{
+		Role:   c.Query("role"),
+		Email:  c.Query("email"),
+		Search: c.Query("q"),
+		SortBy: c.DefaultQuery("sort", "id"),
+	}
+
+	if v := c.Query("ids"); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) > 100 {
+			return f, errors.New("too many ids, at most 100")
+		}
+		for _, p := range parts {
+			id, err := strconv.ParseUint(strings.TrimSpace(p), 10, 64)
+			if err != nil || id == 0 {
+				return f, errors.New("invalid ids")
+			}
+			f.IDs = append(f.IDs, uint(id))
+		}
+	}
+
+	switch status := c.DefaultQuery("status", repositories.StateActive); status {
+	case repositories.StateActive, repositories.StateDeleted, repositories.StateAll:
+		f.State = status
+	default:
+		return f, errors.New("invalid status, expected active, deleted or all")
+	}
+
+	if !repositories.IsSortable(f.SortBy) {
+		return f, errors.New("invalid sort field")
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		f.SortDesc = true
+	default:
+		return f, errors.New("invalid order, expected asc or desc")
+	}
+
+	if v := c.Query("created_from"); v != "" {
+		t, err := parseFilterTime(v, false)
+		if err != nil {
+			return f, errors.New("invalid created_from")
+		}
+		f.CreatedFrom = &t
+	}
+	if v := c.Query("created_to"); v != "" {
+		t, err := parseFilterTime(v, true)
+		if err != nil {
+			return f, errors.New("invalid created_to")
+		}
+		f.CreatedTo = &t
+	}
+	return f, nil
+}
+
+// parseFilterTime принимает RFC3339 или дату YYYY-MM-DD.
+// Для верхней границы дата без времени означает конец дня.
+func parseFilterTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
 type updateUserReq struct {
 	FullName *string `json:"full_name"`
 	Password *string `json:"password"`
@@ -136,7 +211,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
-	if requester.Role != "admin" && requester.ID != uint(id64) {
+	if !requester.Can(models.PermUsersManage) && !requester.OwnsAccount(uint(id64)) {
 		utils.JSONError(c, http.StatusForbidden, "forbidden")
 		return
 	}
@@ -154,18 +229,19 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		u.FullName = *body.FullName
 	}
 	if body.Password != nil {
-		if err := u.SetPassword(*body.Password); err != nil {
-			utils.JSONError(c, http.StatusInternalServerError, "failed to hash password")
+		if err := h.passwords.Set(u, *body.Password); err != nil {
+			if !respondPasswordError(c, err) {
+				utils.JSONError(c, http.StatusInternalServerError, "failed to hash password")
+			}
 			return
 		}
 	}
 	if body.Role != nil {
-		if requester.Role != "admin" {
-			utils.JSONError(c, http.StatusForbidden, "only admin can change role")
+		if !requester.Can(models.PermRolesManage) {
+			utils.JSONError(c, http.StatusForbidden, "permission required: "+models.PermRolesManage)
 			return
 		}
-		if *body.Role != "admin" && *body.Role != "user" {
-			utils.JSONError(c, http.StatusBadRequest, "invalid role")
+		if !h.assignableRole(c, requester, *body.Role) {
 			return
 		}
 		u.Role = *body.Role
@@ -186,7 +262,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 	requester := c.MustGet(middleware.CtxUserKey).(*middleware.ContextUser)
-	if requester.Role != "admin" && requester.ID != uint(id64) {
+	if !requester.Can(models.PermUsersManage) && !requester.OwnsAccount(uint(id64)) {
 		utils.JSONError(c, http.StatusForbidden, "forbidden")
 		return
 	}
@@ -199,6 +275,12 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		utils.JSONError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	// сервисы передают или архивируют данные пользователя; сбой не отменяет удаление
+	for _, r := range h.peers.UserDeleted(u.ID) {
+		if !r.OK {
+			log.Printf("user %d deleted: %s did not handle it: %s", u.ID, r.Service, r.Error)
+		}
+	}
 	utils.JSONSuccess(c, http.StatusOK, gin.H{"deleted": true})
 }
 
@@ -218,7 +300,8 @@ func (h *UserHandler) GetUserInternal(c *gin.Context) {
 	utils.JSONSuccess(c, http.StatusOK, u)
 }
 
-// GetUserRole - internal endpoint to check user role
+// GetUserRole - internal endpoint to check user role.
+// Optional ?sid= is the session from the caller's token: a revoked session gives 401.
 func (h *UserHandler) GetUserRole(c *gin.Context) {
 	idStr := c.Param("id")
 	id64, err := strconv.ParseUint(idStr, 10, 64)
@@ -231,9 +314,30 @@ func (h *UserHandler) GetUserRole(c *gin.Context) {
 		utils.JSONError(c, http.StatusNotFound, "user not found")
 		return
 	}
+	if u.IsSuspended(time.Now()) {
+		utils.JSONError(c, http.StatusForbidden, "account suspended")
+		return
+	}
+	if sid := c.Query("sid"); sid != "" {
+		_, err := h.sessions.Validate(sid, u.ID)
+		if errors.Is(err, services.ErrSessionRevoked) {
+			utils.JSONError(c, http.StatusUnauthorized, "session revoked")
+			return
+		}
+		if err != nil {
+			utils.JSONError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	perms, err := h.rbac.Permissions(u.Role)
+	if err != nil {
+		utils.JSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
 	utils.JSONSuccess(c, http.StatusOK, gin.H{
-		"id":   u.ID,
-		"role": u.Role,
+		"id":          u.ID,
+		"role":        u.Role,
+		"permissions": perms,
 	})
 }
 
@@ -254,7 +358,7 @@ func (h *UserHandler) UpdateUserRoleInternal(c *gin.Context) {
 		return
 	}
 
-	if body.Role != "admin" && body.Role != "user" {
+	if err := h.rbac.ValidateRole(body.Role); err != nil {
 		utils.JSONError(c, http.StatusBadRequest, "invalid role")
 		return
 	}
@@ -274,3 +378,17 @@ func (h *UserHandler) UpdateUserRoleInternal(c *gin.Context) {
 	u.Password = ""
 	utils.JSONSuccess(c, http.StatusOK, u)
 }
+
+// assignableRole: роль должна существовать, а назначать что-либо кроме user
+// может только обладатель roles.manage
+func (h *UserHandler) assignableRole(c *gin.Context, requester *middleware.ContextUser, role string) bool {
+	if role != models.RoleUser && !requester.Can(models.PermRolesManage) {
+		utils.JSONError(c, http.StatusForbidden, "permission required: "+models.PermRolesManage)
+		return false
+	}
+	if err := h.rbac.ValidateRole(role); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "invalid role")
+		return false
+	}
+	return true
+}
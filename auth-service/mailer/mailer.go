@@ -0,0 +1,138 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message - письмо в виде обычного текста
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма. Реализации: SMTPMailer, FileMailer, LogMailer.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Config - настройки для New
+type Config struct {
+	Backend  string // smtp, file или log
+	From     string
+	SMTPHost string
+	SMTPPort string
+	Username string
+	Password string
+	FilePath string
+}
+
+// New создаёт Mailer по имени бэкенда. Неизвестный бэкенд - ошибка.
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Backend {
+	case "smtp":
+		return &SMTPMailer{
+			Addr:     cfg.SMTPHost + ":" + cfg.SMTPPort,
+			Host:     cfg.SMTPHost,
+			From:     cfg.From,
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}, nil
+	case "file":
+		return &FileMailer{Path: cfg.FilePath, From: cfg.From}, nil
+	case "log", "":
+		return &LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer backend %q", cfg.Backend)
+	}
+}
+
+// SMTPMailer отправляет письма через SMTP-сервер.
+// Если Username пуст, аутентификация не выполняется (например, для локального релея).
+type SMTPMailer struct {
+	Addr     string
+	Host     string
+	From     string
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// FileMailer дописывает письма в файл - удобно для локальной разработки
+type FileMailer struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(format(m.From, msg)); err != nil {
+		return err
+	}
+	_, err = f.WriteString("\r\n")
+	return err
+}
+
+// LogMailer пишет письма в лог сервиса
+type LogMailer struct{}
+
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("📧 mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// format собирает RFC 5322 сообщение
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + headerValue(from) + "\r\n")
+	b.WriteString("To: " + headerValue(msg.To) + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", headerValue(msg.Subject)) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// headerValue убирает переводы строк, чтобы нельзя было внедрить заголовки
+func headerValue(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
+
+// Default - почтовый бэкенд сервиса, настраивается в Init
+var Default Mailer = &LogMailer{}
+
+// Init создаёт Default по настройкам из config-значений
+func Init(cfg Config) {
+	m, err := New(cfg)
+	if err != nil {
+		log.Fatalf("mailer init failed: %v", err)
+	}
+	Default = m
+	log.Printf("✅ Mailer: %s", cfg.Backend)
+}
@@ -0,0 +1,133 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP - минимальный SMTP-сервер для проверки SMTPMailer без сети
+type fakeSMTP struct {
+	ln   net.Listener
+	data chan string
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln, data: make(chan string, 1)}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP fake")
+
+	var body strings.Builder
+	inData := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				s.data <- body.String()
+				reply("250 OK")
+				continue
+			}
+			body.WriteString(line)
+			continue
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM"), strings.HasPrefix(cmd, "RCPT TO"):
+			reply("250 OK")
+		case cmd == "DATA":
+			inData = true
+			reply("354 Go ahead")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	srv := startFakeSMTP(t)
+	host, port, _ := net.SplitHostPort(srv.ln.Addr().String())
+
+	m, err := New(Config{Backend: "smtp", From: "no-reply@shop.test", SMTPHost: host, SMTPPort: port})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	err = m.Send(Message{To: "user@example.com", Subject: "Сброс пароля", Body: "Ссылка:\nhttp://app.test/reset?token=abc"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	data := <-srv.data
+	for _, want := range []string{
+		"From: no-reply@shop.test\r\n",
+		"To: user@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"http://app.test/reset?token=abc",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Письмо не содержит %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	m, err := New(Config{Backend: "file", From: "no-reply@shop.test", FilePath: path})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	// перевод строки в теме не должен превращаться в новый заголовок
+	if err := m.Send(Message{To: "a@example.com", Subject: "Hi\r\nBcc: evil@example.com", Body: "one"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := m.Send(Message{To: "b@example.com", Subject: "Hi", Body: "two"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	got := string(b)
+	if !strings.Contains(got, "To: a@example.com") || !strings.Contains(got, "To: b@example.com") {
+		t.Errorf("В файле должны быть оба письма:\n%s", got)
+	}
+	if strings.Contains(got, "\r\nBcc:") {
+		t.Errorf("Заголовок внедрён через тему:\n%s", got)
+	}
+}
+
+func TestNew_UnknownBackend(t *testing.T) {
+	if _, err := New(Config{Backend: "pigeon"}); err == nil {
+		t.Errorf("Ожидалась ошибка для неизвестного бэкенда")
+	}
+}
@@ -5,6 +5,9 @@ import (
 
 	"auth-service/config"
 	"auth-service/db"
+	"auth-service/mailer"
+	"auth-service/oidc"
+	"auth-service/password"
 	"auth-service/routes"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +15,41 @@ import (
 
 func main() {
 	db.Init()
+	mailer.Init(mailer.Config{
+		Backend:  config.Mailer,
+		From:     config.MailFrom,
+		SMTPHost: config.SMTPHost,
+		SMTPPort: config.SMTPPort,
+		Username: config.SMTPUsername,
+		Password: config.SMTPPassword,
+		FilePath: config.MailFilePath,
+	})
+
+	err := password.Init(password.Config{
+		Algorithm:     config.PasswordHash,
+		BcryptCost:    config.BcryptCost,
+		Argon2Memory:  uint32(config.Argon2MemoryKB),
+		Argon2Time:    uint32(config.Argon2Iterations),
+		Argon2Threads: uint8(config.Argon2Parallelism),
+		Policy: password.Policy{
+			MinLength:       config.PasswordMinLength,
+			MaxLength:       config.PasswordMaxLength,
+			RequiredClasses: config.PasswordRequiredClasses,
+		},
+		BreachedList: config.PasswordBreachedListPath,
+	})
+	if err != nil {
+		log.Fatalf("password config failed: %v", err)
+	}
+
+	if err := oidc.Init(config.OIDCKeyPath); err != nil {
+		log.Fatalf("oidc key init failed: %v", err)
+	}
+	providers := make([]oidc.ProviderConfig, len(config.SSOProviders))
+	for i, p := range config.SSOProviders {
+		providers[i] = oidc.ProviderConfig(p)
+	}
+	oidc.InitProviders(providers)
 
 	r := gin.Default()
 
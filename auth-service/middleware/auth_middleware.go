@@ -1,26 +1,68 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"auth-service/config"
+	"auth-service/models"
+	"auth-service/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type ContextUser struct {
-	ID    uint
-	Role  string
-	Email string
+	ID          uint
+	Role        string
+	Email       string
+	Permissions []string
+	SessionID   string
+	APIKeyID    uint // запрос с API-ключом, Permissions ограничены его scopes
+	ActorID     uint // администратор, действующий от имени пользователя (claim "act")
+}
+
+// Can проверяет право из токена
+func (u *ContextUser) Can(perm string) bool {
+	for _, p := range u.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnsAccount - владелец учётной записи, вошедший по сессии.
+// API-ключ и имперсонация не дают права менять пароль или удалять учётную запись.
+func (u *ContextUser) OwnsAccount(id uint) bool {
+	return u.ID == id && u.APIKeyID == 0 && u.ActorID == 0
+}
+
+// Impersonated - запрос выполняет администратор от имени пользователя
+func (u *ContextUser) Impersonated() bool {
+	return u.ActorID != 0
 }
 
 const CtxUserKey = "currentUser"
 
+// APIKeyHeader - альтернатива "Authorization: Bearer ak_..."
+const APIKeyHeader = "X-API-Key"
+
+// JWTAuthMiddleware принимает JWT сессии или персональный API-ключ
 func JWTAuthMiddleware() gin.HandlerFunc {
+	sessions := services.NewSessionService()
+	apiKeys := services.NewAPIKeyService()
+	impersonations := services.NewImpersonationService()
 	return func(c *gin.Context) {
+		if key := APIKeyFromRequest(c); key != "" {
+			authenticateAPIKey(c, apiKeys, key)
+			return
+		}
+
 		auth := c.GetHeader("Authorization")
 		if auth == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
@@ -70,12 +112,139 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			email = e
 		}
 
+		var perms []string
+		if list, ok := claims["permissions"].([]interface{}); ok {
+			for _, p := range list {
+				if s, ok := p.(string); ok {
+					perms = append(perms, s)
+				}
+			}
+		}
+
 		if uid == 0 {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid sub in token"})
 			return
 		}
 
-		c.Set(CtxUserKey, &ContextUser{ID: uint(uid), Role: role, Email: email})
+		// токены без sid выпущены до появления сессий и живут до своего exp
+		sid, _ := claims["sid"].(string)
+		if sid != "" {
+			_, err := sessions.Validate(sid, uint(uid))
+			if errors.Is(err, services.ErrSessionRevoked) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check session"})
+				return
+			}
+		}
+
+		u := &ContextUser{ID: uint(uid), Role: role, Email: email, Permissions: perms, SessionID: sid, ActorID: actorFromClaims(claims)}
+		c.Set(CtxUserKey, u)
+		if u.Impersonated() {
+			impersonationGuard(c, impersonations, u)
+			return
+		}
+		c.Next()
+	}
+}
+
+// actorFromClaims - id администратора из claim "act", 0 для обычного токена
+func actorFromClaims(claims jwt.MapClaims) uint {
+	act, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	sub, _ := act["sub"].(float64)
+	return uint(sub)
+}
+
+// impersonationGuard запрещает удаление во время имперсонации и записывает
+// каждый запрос, в том числе отклонённый, в журнал с обеими личностями
+func impersonationGuard(c *gin.Context, impersonations *services.ImpersonationService, u *ContextUser) {
+	if c.Request.Method == http.MethodDelete {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+	} else {
+		c.Next()
+	}
+	err := impersonations.RecordAction(u.SessionID, &models.ImpersonationAction{
+		ActorID: u.ActorID,
+		UserID:  u.ID,
+		Service: "auth-service",
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+		Status:  c.Writer.Status(),
+		IP:      c.ClientIP(),
+	})
+	if err != nil {
+		log.Printf("impersonation: actor %d as user %d %s %s: failed to record action: %v",
+			u.ActorID, u.ID, c.Request.Method, c.Request.URL.Path, err)
+	}
+}
+
+// APIKeyFromRequest возвращает API-ключ из X-API-Key или из Bearer-токена с префиксом ak_
+func APIKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(token, models.APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys *services.APIKeyService, key string) {
+	access, err := apiKeys.Authenticate(key)
+	var suspended *services.SuspendedError
+	if errors.As(err, &suspended) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": suspended.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrAPIKeyInvalid) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check api key"})
+		return
+	}
+	c.Set(CtxUserKey, &ContextUser{
+		ID:          access.User.ID,
+		Role:        access.User.Role,
+		Email:       access.User.Email,
+		Permissions: access.Permissions,
+		APIKeyID:    access.Key.ID,
+	})
+	c.Next()
+}
+
+// RequireSession отклоняет запросы с API-ключом и во время имперсонации: так нельзя
+// выпускать новые ключи и управлять входом. Ставится после JWTAuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, ok := c.MustGet(CtxUserKey).(*ContextUser)
+		if !ok || u.APIKeyID != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint is not available with an api key"})
+			return
+		}
+		if u.Impersonated() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission пропускает запрос, только если в токене есть право perm.
+// Ставится после JWTAuthMiddleware.
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, ok := c.MustGet(CtxUserKey).(*ContextUser)
+		if !ok || !u.Can(perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission required: " + perm})
+			return
+		}
 		c.Next()
 	}
 }
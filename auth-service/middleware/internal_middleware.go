@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const InternalTokenHeader = "X-Internal-Token"
+
+// InternalAuthMiddleware пропускает только межсервисные запросы с корректным X-Internal-Token.
+// Если токен не задан, все запросы отклоняются.
+func InternalAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader(InternalTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid internal token"})
+			return
+		}
+		c.Next()
+	}
+}
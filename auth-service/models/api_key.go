@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix - начало любого API-ключа; по нему сервисы отличают ключ от JWT
+const APIKeyPrefix = "ak_"
+
+// APIKey - персональный ключ для скриптов и интеграций. Хранится только SHA-256 ключа,
+// Prefix - его первые символы, чтобы пользователь мог узнать ключ в списке.
+// Scopes - права, которыми ограничен ключ; действуют только те, что есть и у роли владельца.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	KeyHash    string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes     string     `json:"-"` // через пробел
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Виды и состояния запросов по персональным данным
+const (
+	DataRequestExport  = "export"
+	DataRequestErasure = "erasure"
+
+	DataRequestCompleted = "completed"
+	DataRequestPartial   = "partial" // часть сервисов не ответила
+)
+
+// DataRequestService - результат по одному сервису
+type DataRequestService struct {
+	Service string `json:"service"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DataRequest - выгрузка или удаление персональных данных пользователя.
+// Запись об удалении остаётся после удаления учётной записи и хранит только ID.
+type DataRequest struct {
+	ID          uint                 `gorm:"primaryKey" json:"id"`
+	UserID      uint                 `gorm:"index;not null" json:"user_id"`
+	RequestedBy uint                 `gorm:"not null" json:"requested_by"` // сам пользователь или администратор
+	Kind        string               `gorm:"not null" json:"kind"`
+	Status      string               `gorm:"not null" json:"status"`
+	Services    []DataRequestService `gorm:"serializer:json" json:"services"`
+	File        string               `json:"-"`                    // архив выгрузки
+	ExpiresAt   *time.Time           `json:"expires_at,omitempty"` // после этого архив удаляется
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+}
+
+// Downloadable сообщает, можно ли скачать архив выгрузки на момент now
+func (r *DataRequest) Downloadable(now time.Time) bool {
+	return r.Kind == DataRequestExport && r.File != "" && r.ExpiresAt != nil && now.Before(*r.ExpiresAt)
+}
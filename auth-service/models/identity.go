@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ExternalIdentity - учётная запись внешнего OpenID Connect провайдера, привязанная к пользователю.
+// Пара (Provider, Subject) уникальна: sub стабилен у провайдера, email - нет.
+type ExternalIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index;not null" json:"user_id"`
+	Provider    string     `gorm:"uniqueIndex:idx_identity_subject;not null" json:"provider"`
+	Subject     string     `gorm:"uniqueIndex:idx_identity_subject;not null" json:"-"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// SSOState - незавершённый вход или привязка через внешний провайдер.
+// state передаётся провайдеру и хранится как SHA-256, nonce и code_verifier остаются на сервере.
+// LinkUserID заполнен, если пользователь привязывает провайдер к своей учётной записи.
+type SSOState struct {
+	ID           uint   `gorm:"primaryKey"`
+	StateHash    string `gorm:"uniqueIndex;not null"`
+	Provider     string `gorm:"not null"`
+	Nonce        string `gorm:"not null"`
+	CodeVerifier string `gorm:"not null"`
+	LinkUserID   *uint
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Impersonation - вход администратора от имени пользователя. Токен выдаётся на пользователя
+// с claim "act" (RFC 8693) и сессией SID, отзыв сессии завершает имперсонацию во всех сервисах.
+type Impersonation struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	ActorID   uint       `gorm:"index;not null" json:"actor_id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	Reason    string     `gorm:"not null" json:"reason"`
+	SID       string     `gorm:"column:sid;uniqueIndex;not null" json:"-"`
+	IP        string     `json:"ip"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ImpersonationAction - запрос, выполненный во время имперсонации, в любом из сервисов.
+// Хранит обе личности: кто действовал и от чьего имени.
+type ImpersonationAction struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ImpersonationID uint      `gorm:"index;not null" json:"impersonation_id"`
+	ActorID         uint      `gorm:"index;not null" json:"actor_id"`
+	UserID          uint      `gorm:"not null" json:"user_id"`
+	Service         string    `gorm:"not null" json:"service"`
+	Method          string    `json:"method"`
+	Path            string    `json:"path"`
+	Status          int       `json:"status"`
+	IP              string    `json:"ip"`
+	CreatedAt       time.Time `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Состояния приглашения
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation - приглашение, созданное администратором. Пользователь появляется только
+// после принятия: приглашённый сам задаёт пароль по одноразовой ссылке из письма.
+type Invitation struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Email      string     `gorm:"index;not null" json:"email"`
+	FullName   string     `json:"full_name"`
+	Role       string     `gorm:"not null" json:"role"`
+	TokenHash  string     `gorm:"uniqueIndex;not null" json:"-"`
+	InvitedBy  uint       `gorm:"not null" json:"invited_by"`
+	UserID     *uint      `json:"user_id,omitempty"` // созданный пользователь
+	ExpiresAt  time.Time  `json:"expires_at"`
+	SentAt     time.Time  `json:"sent_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Status вычисляет состояние на момент now
+func (i *Invitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	}
+	return InvitationPending
+}
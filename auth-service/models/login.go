@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Причины в журнале входов
+const (
+	LoginOK               = "ok"
+	LoginBadCredentials   = "invalid_credentials"
+	LoginThrottled        = "throttled"
+	LoginSuspended        = "suspended"
+	LoginEmailNotVerified = "email_not_verified"
+	LoginMFARequired      = "mfa_required"
+	LoginBadMFACode       = "invalid_mfa_code"
+	LoginSSOFailed        = "sso_failed"
+)
+
+// LoginAttempt - запись аудита попытки входа
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    *uint     `gorm:"index" json:"user_id"`
+	Email     string    `gorm:"index" json:"email"`
+	IP        string    `gorm:"index" json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `gorm:"index" json:"success"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// LoginThrottle - счётчик неудачных попыток по ключу "account:<email>" или "ip:<addr>"
+type LoginThrottle struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Key           string     `gorm:"uniqueIndex;not null" json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
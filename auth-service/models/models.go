@@ -0,0 +1,12 @@
+package models
+
+// All - все модели auth-service для AutoMigrate; новую модель достаточно добавить сюда,
+// чтобы её получили и сервис, и тестовые базы
+func All() []interface{} {
+	return []interface{}{
+		&User{}, &ActionToken{}, &RecoveryCode{}, &LoginAttempt{}, &LoginThrottle{},
+		&Permission{}, &Role{}, &Session{}, &OAuthClient{}, &AuthorizationCode{},
+		&ExternalIdentity{}, &SSOState{}, &APIKey{}, &Impersonation{}, &ImpersonationAction{},
+		&PasswordHistory{}, &Invitation{}, &DataRequest{},
+	}
+}
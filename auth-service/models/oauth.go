@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuthClient - приложение, которое входит через auth-service по OpenID Connect.
+// Поддерживаются только собственные (first-party) клиенты, поэтому экран согласия не показывается.
+// У публичных клиентов (SPA, мобильное приложение) секрета нет, защита кода - только PKCE.
+type OAuthClient struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ClientID     string    `gorm:"uniqueIndex;not null" json:"client_id"`
+	Name         string    `gorm:"not null" json:"name"`
+	SecretHash   string    `json:"-"`
+	Public       bool      `json:"public"`
+	RedirectURIs string    `gorm:"type:text;not null" json:"-"` // по одному на строку
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (c *OAuthClient) RedirectURIList() []string {
+	return strings.Split(c.RedirectURIs, "\n")
+}
+
+// AllowsRedirect - redirect_uri сравнивается посимвольно, без нормализации
+func (c *OAuthClient) AllowsRedirect(uri string) bool {
+	for _, u := range c.RedirectURIList() {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationCode - одноразовый код authorization code flow, хранится как SHA-256
+type AuthorizationCode struct {
+	ID                  uint   `gorm:"primaryKey"`
+	CodeHash            string `gorm:"uniqueIndex;not null"`
+	ClientID            string `gorm:"index;not null"`
+	UserID              uint   `gorm:"not null"`
+	RedirectURI         string `gorm:"not null"`
+	Scope               string
+	Nonce               string
+	CodeChallenge       string `gorm:"not null"`
+	CodeChallengeMethod string `gorm:"not null"`
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+	CreatedAt           time.Time
+}
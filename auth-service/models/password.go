@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordHistory - прежние хеши пароля пользователя, чтобы не допускать их повторного использования
+type PasswordHistory struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	Hash      string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// AddPasswordHistory сохраняет прежний хеш и оставляет в истории только keep последних
+func AddPasswordHistory(tx *gorm.DB, userID uint, hash string, keep int) error {
+	if err := tx.Create(&PasswordHistory{UserID: userID, Hash: hash}).Error; err != nil {
+		return err
+	}
+	var kept []uint
+	if err := tx.Model(&PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id desc").Limit(keep).Pluck("id", &kept).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? AND id NOT IN ?", userID, kept).Delete(&PasswordHistory{}).Error
+}
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Встроенные роли. Остальные роли создаются администратором через API.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Права доступа. Сервисы проверяют право, а не название роли.
+const (
+	PermUsersRead        = "users.read"
+	PermUsersManage      = "users.manage"
+	PermRolesManage      = "roles.manage"
+	PermAuditRead        = "audit.read"
+	PermProductsManage   = "products.manage"
+	PermOrdersManage     = "orders.manage"
+	PermProjectsManage   = "projects.manage"
+	PermPortfolioManage  = "portfolio.manage"
+	PermContactsManage   = "contacts.manage"
+	PermOAuthManage      = "oauth_clients.manage"
+	PermUsersImpersonate = "users.impersonate"
+)
+
+type Permission struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"uniqueIndex;not null" json:"name"`
+	Description string `json:"description"`
+}
+
+// Role - именованный набор прав. User.Role хранит Role.Name.
+// System-роли нельзя удалить, права роли admin не редактируются.
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"uniqueIndex;not null" json:"name"`
+	Description string       `json:"description"`
+	System      bool         `gorm:"default:false" json:"system"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+func (r *Role) PermissionNames() []string {
+	names := make([]string, len(r.Permissions))
+	for i, p := range r.Permissions {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// BuiltinPermissions - права, о которых знают сервисы
+var BuiltinPermissions = []Permission{
+	{Name: PermUsersRead, Description: "view users, profiles and activity"},
+	{Name: PermUsersManage, Description: "create, update, suspend and delete users"},
+	{Name: PermRolesManage, Description: "manage roles and assign them to users"},
+	{Name: PermAuditRead, Description: "view login audit log"},
+	{Name: PermProductsManage, Description: "manage products"},
+	{Name: PermOrdersManage, Description: "manage orders"},
+	{Name: PermProjectsManage, Description: "manage projects"},
+	{Name: PermPortfolioManage, Description: "manage portfolio items"},
+	{Name: PermContactsManage, Description: "process contact requests"},
+	{Name: PermOAuthManage, Description: "register OpenID Connect clients"},
+	{Name: PermUsersImpersonate, Description: "sign in as a customer for support, every action is audited"},
+}
+
+// BuiltinRole - роль, создаваемая при первом запуске
+type BuiltinRole struct {
+	Name        string
+	Description string
+	System      bool
+	Permissions []string
+}
+
+// BuiltinRoles - роль admin получает все права при каждом запуске,
+// остальные создаются один раз и дальше редактируются через API
+var BuiltinRoles = []BuiltinRole{
+	{Name: RoleAdmin, Description: "full access", System: true},
+	{Name: RoleUser, Description: "regular customer", System: true},
+	{Name: "content-editor", Description: "portfolio content", Permissions: []string{PermPortfolioManage}},
+	{Name: "support", Description: "contact requests", Permissions: []string{PermContactsManage, PermUsersRead}},
+	{Name: "order-manager", Description: "orders and customers", Permissions: []string{PermOrdersManage, PermUsersRead}},
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RecoveryCode - резервный код двухфакторной аутентификации, хранится как bcrypt-хеш
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
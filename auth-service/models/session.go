@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Session - вход пользователя с конкретного устройства. SID попадает в JWT (claim "sid"),
+// отзыв сессии делает токен недействительным до истечения его срока.
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	ActorID    *uint      `json:"actor_id,omitempty"` // администратор, вошедший от имени пользователя
+	SID        string     `gorm:"column:sid;uniqueIndex;not null" json:"-"`
+	Device     string     `json:"device"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -3,31 +3,109 @@ package models
 import (
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"auth-service/password"
+
 	"gorm.io/gorm"
 )
 
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+)
+
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string         `gorm:"not null" json:"-"`
-	FullName  string         `json:"full_name"`
-	Role      string         `gorm:"default:user" json:"role"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	Email           string         `gorm:"uniqueIndex;not null" json:"email"`
+	Password        string         `gorm:"not null" json:"-"`
+	FullName        string         `json:"full_name"`
+	Role            string         `gorm:"default:user" json:"role"`
+	Status          string         `gorm:"default:active;not null" json:"status"`
+	EmailVerified   bool           `gorm:"default:false" json:"email_verified"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	TOTPSecret      string         `json:"-"`
+	TOTPEnabled     bool           `gorm:"default:false" json:"totp_enabled"`
+	TOTPLastStep    int64          `json:"-"` // последний принятый интервал, защита от повтора кода
+	SuspendedReason string         `json:"suspended_reason,omitempty"`
+	SuspendedUntil  *time.Time     `json:"suspended_until,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// прежний хеш после смены пароля и размер истории; AfterSave переносит хеш в историю
+	retiredPassword string
+	historyKeep     int
+}
+
+// IsSuspended сообщает, действует ли блокировка на момент now.
+// Блокировка без SuspendedUntil действует бессрочно.
+func (u *User) IsSuspended(now time.Time) bool {
+	if u.Status != StatusSuspended {
+		return false
+	}
+	return u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil)
+}
+
+// Suspend блокирует пользователя; until == nil означает бессрочную блокировку
+func (u *User) Suspend(reason string, until *time.Time) {
+	u.Status = StatusSuspended
+	u.SuspendedReason = reason
+	u.SuspendedUntil = until
+}
+
+// MarkEmailVerified отмечает email как подтверждённый
+func (u *User) MarkEmailVerified(at time.Time) {
+	u.EmailVerified = true
+	u.EmailVerifiedAt = &at
+}
+
+// Unsuspend снимает блокировку
+func (u *User) Unsuspend() {
+	u.Status = StatusActive
+	u.SuspendedReason = ""
+	u.SuspendedUntil = nil
 }
 
 func (u *User) SetPassword(plain string) error {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+	hashed, err := password.Hash(plain)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hashed)
+	u.Password = hashed
+	return nil
+}
+
+// RetirePassword запоминает прежний хеш: он попадёт в историю паролей вместе с сохранением
+// пользователя, в истории останутся keep последних
+func (u *User) RetirePassword(hash string, keep int) {
+	u.retiredPassword = hash
+	u.historyKeep = keep
+}
+
+// AfterSave пишет прежний хеш в историю в той же транзакции, что и сохранение пользователя:
+// если пользователь не сохранён, история тоже не меняется
+func (u *User) AfterSave(tx *gorm.DB) error {
+	if u.retiredPassword == "" || u.ID == 0 {
+		return nil
+	}
+	if err := AddPasswordHistory(tx, u.ID, u.retiredPassword, u.historyKeep); err != nil {
+		return err
+	}
+	u.retiredPassword = ""
 	return nil
 }
 
-func (u *User) CheckPassword(plain string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(plain))
-	return err == nil
+// CheckPassword сверяет пароль. Хеш, созданный старым алгоритмом или с меньшей стоимостью,
+// пересчитывается по текущим настройкам; rehashed означает, что пользователя нужно сохранить.
+func (u *User) CheckPassword(plain string) (ok, rehashed bool) {
+	if u.Password == "" {
+		return false, false
+	}
+	ok, rehash := password.Verify(plain, u.Password)
+	if !ok || !rehash {
+		return ok, false
+	}
+	if err := u.SetPassword(plain); err != nil {
+		return true, false
+	}
+	return true, true
 }
@@ -0,0 +1,322 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ProviderConfig - внешний провайдер, через который пользователи входят в auth-service
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+}
+
+// Metadata - используемые поля discovery-документа провайдера
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Identity - проверенные данные пользователя от внешнего провайдера
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// JWKS перечитывается при незнакомом kid, но не чаще этого интервала
+const jwksRefreshInterval = time.Minute
+
+const maxResponseBytes = 1 << 20
+
+// Provider - relying party для одного внешнего провайдера.
+// Discovery и ключи загружаются при первом обращении: недоступный провайдер
+// не должен мешать запуску сервиса и остальным способам входа.
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	meta   *Metadata
+	keys   map[string]*rsa.PublicKey
+	keysAt time.Time
+}
+
+func NewProvider(cfg ProviderConfig, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+	return &Provider{cfg: cfg, client: client}
+}
+
+func (p *Provider) Name() string { return p.cfg.Name }
+
+// Providers - настроенные внешние провайдеры по имени, заполняются в InitProviders
+var Providers = map[string]*Provider{}
+
+func InitProviders(cfgs []ProviderConfig) {
+	Providers = make(map[string]*Provider, len(cfgs))
+	for _, cfg := range cfgs {
+		Providers[cfg.Name] = NewProvider(cfg, nil)
+	}
+}
+
+// AuthCodeURL - адрес, на который отправляется браузер пользователя.
+// verifier остаётся на сервере, провайдеру уходит только его S256-хеш.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) (string, error) {
+	meta, err := p.metadata()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: invalid authorization_endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", ChallengeS256(verifier))
+	q.Set("code_challenge_method", MethodS256)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange обменивает код на токены и возвращает данные из проверенного ID-токена.
+// Если провайдер не кладёт email в ID-токен, он берётся из userinfo.
+func (p *Provider) Exchange(code, verifier, nonce string) (*Identity, error) {
+	meta, err := p.metadata()
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	resp, err := p.client.PostForm(meta.TokenEndpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("oidc: token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return nil, fmt.Errorf("oidc: token endpoint returned %d: %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+	if tr.IDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+
+	id, err := p.verifyIDToken(meta, tr.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if id.Email == "" && meta.UserinfoEndpoint != "" && tr.AccessToken != "" {
+		if err := p.fillFromUserInfo(meta, tr.AccessToken, id); err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}
+
+func (p *Provider) verifyIDToken(meta *Metadata, raw, nonce string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, p.keyFunc,
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid id_token: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, errors.New("oidc: id_token nonce mismatch")
+	}
+	// при нескольких получателях токен должен быть выдан именно нам
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.cfg.ClientID {
+			return nil, errors.New("oidc: id_token azp mismatch")
+		}
+	}
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return nil, errors.New("oidc: id_token has no sub")
+	}
+	id := &Identity{Subject: sub}
+	id.Email, _ = claims["email"].(string)
+	id.EmailVerified = boolClaim(claims["email_verified"])
+	id.Name, _ = claims["name"].(string)
+	return id, nil
+}
+
+func (p *Provider) fillFromUserInfo(meta *Metadata, accessToken string, id *Identity) error {
+	req, err := http.NewRequest(http.MethodGet, meta.UserinfoEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	var info map[string]interface{}
+	if err := p.doJSON(req, &info); err != nil {
+		return fmt.Errorf("oidc: userinfo: %w", err)
+	}
+	// ответ userinfo относится к пользователю ID-токена только при совпадении sub
+	if sub, _ := info["sub"].(string); sub != id.Subject {
+		return errors.New("oidc: userinfo sub mismatch")
+	}
+	id.Email, _ = info["email"].(string)
+	id.EmailVerified = boolClaim(info["email_verified"])
+	if name, _ := info["name"].(string); name != "" {
+		id.Name = name
+	}
+	return nil
+}
+
+// boolClaim - некоторые провайдеры передают email_verified строкой
+func boolClaim(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
+
+func (p *Provider) metadata() (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	var m Metadata
+	if err := p.getJSON(p.cfg.Issuer+"/.well-known/openid-configuration", &m); err != nil {
+		return nil, fmt.Errorf("oidc: discovery for %s: %w", p.cfg.Name, err)
+	}
+	if strings.TrimRight(m.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", m.Issuer, p.cfg.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: discovery for %s is missing endpoints", p.cfg.Name)
+	}
+	p.meta = &m
+	return p.meta, nil
+}
+
+func (p *Provider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+	}
+	var set JWKSet
+	if err := p.getJSON(p.meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc: jwks: %w", err)
+	}
+	p.keys = make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if pub, err := k.rsaPublicKey(); err == nil {
+			p.keys[k.Kid] = pub
+		}
+	}
+	p.keysAt = time.Now()
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+}
+
+// lookupKey - токен без kid допустим, только если у провайдера один ключ
+func (p *Provider) lookupKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k
+		}
+	}
+	return p.keys[kid]
+}
+
+func (k JWK) rsaPublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+		return nil, errors.New("oidc: not an RSA signing key")
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("oidc: invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+func (p *Provider) getJSON(u string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	return p.doJSON(req, out)
+}
+
+func (p *Provider) doJSON(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL.Redacted())
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(out)
+}
@@ -0,0 +1,147 @@
+// Package oidc содержит обе стороны OpenID Connect: криптографию собственного провайдера
+// (RSA-ключ для подписи ID-токенов, JWKS, проверка PKCE по RFC 7636) и клиент
+// для входа через внешние провайдеры (client.go).
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyBits = 2048
+
+// Signer подписывает ID-токены алгоритмом RS256
+type Signer struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+// JWK - открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewSigner(key *rsa.PrivateKey) *Signer {
+	sum := sha256.Sum256(key.PublicKey.N.Bytes())
+	return &Signer{key: key, kid: base64.RawURLEncoding.EncodeToString(sum[:12])}
+}
+
+// LoadOrCreateKey читает PKCS#8 PEM-ключ из path, а если файла нет - создаёт новый.
+// Ключ должен переживать перезапуски, иначе выданные ID-токены перестанут проверяться.
+func LoadOrCreateKey(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("oidc: key file is not PEM")
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("oidc: key is not RSA")
+		}
+		return NewSigner(key), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+	}
+	out := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		return nil, err
+	}
+	return NewSigner(key), nil
+}
+
+func (s *Signer) KeyID() string { return s.kid }
+
+func (s *Signer) PublicKey() *rsa.PublicKey { return &s.key.PublicKey }
+
+func (s *Signer) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+	return token.SignedString(s.key)
+}
+
+func (s *Signer) JWKS() JWKSet {
+	pub := s.key.PublicKey
+	return JWKSet{Keys: []JWK{{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: s.kid,
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}}
+}
+
+// Default - ключ провайдера, загружается в Init
+var Default *Signer
+
+func Init(path string) error {
+	s, err := LoadOrCreateKey(path)
+	if err != nil {
+		return err
+	}
+	Default = s
+	return nil
+}
+
+// PKCE
+
+const MethodS256 = "S256"
+
+// verifier: 43-128 символов из [A-Za-z0-9-._~]
+var verifierRe = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// ChallengeS256 - BASE64URL(SHA256(verifier))
+func ChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPKCE проверяет code_verifier против сохранённого code_challenge.
+// Поддерживается только S256: plain не защищает от перехвата кода.
+func VerifyPKCE(verifier, challenge, method string) bool {
+	if method != MethodS256 || !verifierRe.MatchString(verifier) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(ChallengeS256(verifier)), []byte(challenge)) == 1
+}
@@ -0,0 +1,65 @@
+package oidc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Пример из приложения B RFC 7636
+func TestVerifyPKCE(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if got := ChallengeS256(verifier); got != challenge {
+		t.Fatalf("ChallengeS256: ожидалось %s, получено %s", challenge, got)
+	}
+	if !VerifyPKCE(verifier, challenge, MethodS256) {
+		t.Errorf("Корректный verifier должен приниматься")
+	}
+	if VerifyPKCE(verifier, verifier, "plain") {
+		t.Errorf("Метод plain не поддерживается")
+	}
+	if VerifyPKCE("short", ChallengeS256("short"), MethodS256) {
+		t.Errorf("Слишком короткий verifier должен отклоняться")
+	}
+	if VerifyPKCE(verifier+"x", challenge, MethodS256) {
+		t.Errorf("Чужой verifier должен отклоняться")
+	}
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "oidc.pem")
+
+	s1, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("Создание ключа: %v", err)
+	}
+	s2, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("Загрузка ключа: %v", err)
+	}
+	if s1.KeyID() != s2.KeyID() {
+		t.Errorf("После перезагрузки ключ должен совпадать")
+	}
+
+	token, err := s1.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	parsed, err := jwt.Parse(token, func(tk *jwt.Token) (interface{}, error) {
+		if tk.Header["kid"] != s2.KeyID() {
+			t.Errorf("Неверный kid в заголовке: %v", tk.Header["kid"])
+		}
+		return s2.PublicKey(), nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil || !parsed.Valid {
+		t.Fatalf("Подпись не проверяется открытым ключом: %v", err)
+	}
+
+	jwks := s1.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].E != "AQAB" || jwks.Keys[0].Kid != s1.KeyID() {
+		t.Errorf("Неверный JWKS: %+v", jwks)
+	}
+}
@@ -0,0 +1,55 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// breachedSet - SHA-1 утёкших паролей. Формат файла совпадает с выгрузкой
+// Have I Been Pwned: "HASH" или "HASH:count" в строке, регистр не важен.
+// Список целиком держится в памяти, поэтому стоит брать верхние N записей, а не всю базу.
+type breachedSet map[[sha1.Size]byte]struct{}
+
+func loadBreached(path string) (breachedSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	set := breachedSet{}
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		var key [sha1.Size]byte
+		if n, err := hex.Decode(key[:], []byte(hash)); err != nil || n != sha1.Size {
+			return nil, fmt.Errorf("breached password list %s:%d: expected SHA-1 hex", path, line)
+		}
+		set[key] = struct{}{}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read breached password list: %w", err)
+	}
+	return set, nil
+}
+
+func (s breachedSet) contains(plain string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	_, ok := s[sha1.Sum([]byte(plain))]
+	return ok
+}
+
+// Breached сообщает, есть ли пароль в загруженном списке утёкших
+func Breached(plain string) bool {
+	return breached.contains(plain)
+}
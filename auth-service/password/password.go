@@ -0,0 +1,155 @@
+// Package password хеширует пароли и проверяет их по политике.
+//
+// Новые хеши - argon2id в формате PHC ($argon2id$v=19$m=...,t=...,p=...$salt$hash)
+// или bcrypt с заданной стоимостью. Verify принимает оба формата и сообщает, что хеш
+// устарел: алгоритм или параметры отличаются от текущих. Так старые bcrypt-хеши
+// незаметно переводятся на argon2id при следующем входе.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgoArgon2id = "argon2id"
+	AlgoBcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// Config задаётся из переменных окружения в main
+type Config struct {
+	Algorithm     string // argon2id или bcrypt
+	BcryptCost    int
+	Argon2Memory  uint32 // KiB
+	Argon2Time    uint32
+	Argon2Threads uint8
+	Policy        Policy
+	BreachedList  string // файл SHA-1 хешей утёкших паролей, пусто - проверка выключена
+}
+
+// DefaultConfig действует до вызова Init: argon2id с параметрами RFC 9106
+func DefaultConfig() Config {
+	return Config{
+		Algorithm:     AlgoArgon2id,
+		BcryptCost:    12,
+		Argon2Memory:  64 * 1024,
+		Argon2Time:    3,
+		Argon2Threads: 2,
+		Policy:        DefaultPolicy(),
+	}
+}
+
+var (
+	current  = DefaultConfig()
+	breached breachedSet
+)
+
+// Init применяет настройки и загружает список утёкших паролей
+func Init(cfg Config) error {
+	switch cfg.Algorithm {
+	case AlgoArgon2id:
+		if cfg.Argon2Memory == 0 || cfg.Argon2Time == 0 || cfg.Argon2Threads == 0 {
+			return errors.New("argon2id parameters must be positive")
+		}
+	case AlgoBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return fmt.Errorf("unknown password hash algorithm %q", cfg.Algorithm)
+	}
+	if err := validateClasses(cfg.Policy.RequiredClasses); err != nil {
+		return err
+	}
+	set := breachedSet{}
+	if cfg.BreachedList != "" {
+		var err error
+		if set, err = loadBreached(cfg.BreachedList); err != nil {
+			return err
+		}
+	}
+	current, breached = cfg, set
+	return nil
+}
+
+// Hash хеширует пароль текущим алгоритмом
+func Hash(plain string) (string, error) {
+	if current.Algorithm == AlgoBcrypt {
+		b, err := bcrypt.GenerateFromPassword([]byte(plain), current.BcryptCost)
+		return string(b), err
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plain), salt, current.Argon2Time, current.Argon2Memory, current.Argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		current.Argon2Memory, current.Argon2Time, current.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify сверяет пароль с хешем; rehash - хеш верный, но создан не текущими настройками
+func Verify(plain, hash string) (ok, rehash bool) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		p, err := parseArgon2(hash)
+		if err != nil {
+			return false, false
+		}
+		key := argon2.IDKey([]byte(plain), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+		if subtle.ConstantTimeCompare(key, p.key) != 1 {
+			return false, false
+		}
+		return true, current.Algorithm != AlgoArgon2id || p.memory < current.Argon2Memory ||
+			p.time < current.Argon2Time || p.threads < current.Argon2Threads
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || current.Algorithm != AlgoBcrypt || cost < current.BcryptCost
+}
+
+type argon2Hash struct {
+	memory, time uint32
+	threads      uint8
+	salt, key    []byte
+}
+
+func parseArgon2(hash string) (*argon2Hash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, errors.New("malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errors.New("unsupported argon2 version")
+	}
+	p := &argon2Hash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, err
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, err
+	}
+	if p.memory == 0 || p.time == 0 || p.threads == 0 || len(p.salt) == 0 || len(p.key) == 0 {
+		return nil, errors.New("malformed argon2id hash")
+	}
+	return p, nil
+}
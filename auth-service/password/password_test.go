@@ -0,0 +1,102 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads = 64, 1, 1
+	return cfg
+}
+
+func TestHashAndVerify(t *testing.T) {
+	if err := Init(testConfig()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	hash, err := Hash("correct horse 1")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Ожидался хеш argon2id в формате PHC, получено %s", hash)
+	}
+	if ok, rehash := Verify("correct horse 1", hash); !ok || rehash {
+		t.Errorf("Верный пароль с текущими параметрами: ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, _ := Verify("wrong horse 1", hash); ok {
+		t.Errorf("Неверный пароль не должен подходить")
+	}
+
+	// старый bcrypt-хеш принимается, но требует пересчёта
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse 1"), bcrypt.MinCost)
+	if ok, rehash := Verify("correct horse 1", string(legacy)); !ok || !rehash {
+		t.Errorf("bcrypt-хеш должен подходить и требовать пересчёта: ok=%v rehash=%v", ok, rehash)
+	}
+
+	// увеличение параметров делает прежние argon2id-хеши устаревшими
+	cfg := testConfig()
+	cfg.Argon2Time = 2
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if ok, rehash := Verify("correct horse 1", hash); !ok || !rehash {
+		t.Errorf("Хеш со слабыми параметрами должен требовать пересчёта: ok=%v rehash=%v", ok, rehash)
+	}
+
+	for _, bad := range []string{"", "$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=64,t=1,p=1$$"} {
+		if ok, _ := Verify("", bad); ok {
+			t.Errorf("Повреждённый хеш %q не должен подходить", bad)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("password1"))
+	list := "# top breached\n" + strings.ToUpper(hex.EncodeToString(sum[:])) + ":2254650\n"
+	path := filepath.Join(dir, "breached.txt")
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.Policy = Policy{MinLength: 8, MaxLength: 64, RequiredClasses: []string{ClassLower, ClassUpper, ClassDigit}}
+	cfg.BreachedList = path
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer Init(testConfig())
+
+	var policy *PolicyError
+	if err := Validate("abc"); !errors.As(err, &policy) || len(policy.Violations) != 3 {
+		t.Errorf("Ожидались нарушения длины, заглавной буквы и цифры, получено: %v", err)
+	}
+	if err := Validate("Password1"); err != nil {
+		t.Errorf("Пароль по политике должен приниматься: %v", err)
+	}
+	cfg.Policy.RequiredClasses = []string{ClassLetter, ClassDigit}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := Validate("password1"); !errors.As(err, &policy) || !strings.Contains(err.Error(), "breach") {
+		t.Errorf("Утёкший пароль должен отклоняться, получено: %v", err)
+	}
+
+	cfg.Policy.RequiredClasses = []string{"emoji"}
+	if err := Init(cfg); err == nil {
+		t.Errorf("Неизвестный класс символов должен отклоняться")
+	}
+	cfg = testConfig()
+	cfg.BreachedList = filepath.Join(dir, "missing.txt")
+	if err := Init(cfg); err == nil {
+		t.Errorf("Отсутствующий файл списка должен давать ошибку")
+	}
+}
@@ -0,0 +1,81 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Классы символов для Policy.RequiredClasses
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassLetter = "letter"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+var classCheck = map[string]func(rune) bool{
+	ClassLower:  unicode.IsLower,
+	ClassUpper:  unicode.IsUpper,
+	ClassLetter: unicode.IsLetter,
+	ClassDigit:  unicode.IsDigit,
+	ClassSymbol: func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r) },
+}
+
+// Policy - требования к новому паролю. Повтор прежних паролей проверяет
+// services.PasswordService: для этого нужна история из БД.
+type Policy struct {
+	MinLength       int
+	MaxLength       int // защита от дорогого хеширования очень длинных строк
+	RequiredClasses []string
+}
+
+func DefaultPolicy() Policy {
+	return Policy{MinLength: 8, MaxLength: 128, RequiredClasses: []string{ClassLetter, ClassDigit}}
+}
+
+// validateClasses проверяет названия классов из конфигурации
+func validateClasses(classes []string) error {
+	for _, c := range classes {
+		if _, ok := classCheck[c]; !ok {
+			return fmt.Errorf("unknown password character class %q", c)
+		}
+	}
+	return nil
+}
+
+// PolicyError перечисляет все нарушенные требования, чтобы показать их пользователю разом
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet policy: " + strings.Join(e.Violations, "; ")
+}
+
+// Validate проверяет пароль по текущей политике и списку утёкших паролей
+func Validate(plain string) error {
+	p := current.Policy
+	var violations []string
+	n := utf8.RuneCountInString(plain)
+	if n < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+	for _, class := range p.RequiredClasses {
+		if !strings.ContainsFunc(plain, classCheck[class]) {
+			violations = append(violations, "must contain a "+class+" character")
+		}
+	}
+	if len(violations) == 0 && breached.contains(plain) {
+		violations = append(violations, "appears in a known data breach, choose another one")
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepo() *APIKeyRepo {
+	return &APIKeyRepo{db: db.DB}
+}
+
+func (r *APIKeyRepo) Create(k *models.APIKey) error {
+	return r.db.Create(k).Error
+}
+
+func (r *APIKeyRepo) FindByHash(hash string) (*models.APIKey, error) {
+	var k models.APIKey
+	if err := r.db.Where("key_hash = ?", hash).First(&k).Error; err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *APIKeyRepo) FindForUser(id, userID uint) (*models.APIKey, error) {
+	var k models.APIKey
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&k).Error; err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListActive - неотозванные ключи пользователя, включая истёкшие: их видно в списке до удаления
+func (r *APIKeyRepo) ListActive(userID uint) ([]models.APIKey, error) {
+	var items []models.APIKey
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("id").Find(&items).Error
+	return items, err
+}
+
+func (r *APIKeyRepo) CountActive(userID uint, now time.Time) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).Count(&n).Error
+	return n, err
+}
+
+func (r *APIKeyRepo) Touch(id uint, now time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", now).Error
+}
+
+func (r *APIKeyRepo) Revoke(k *models.APIKey, now time.Time) error {
+	k.RevokedAt = &now
+	return r.db.Model(k).Update("revoked_at", now).Error
+}
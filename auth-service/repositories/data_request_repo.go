@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrDataRequestNotFound = errors.New("data request not found")
+
+type DataRequestRepo struct {
+	db *gorm.DB
+}
+
+func NewDataRequestRepo() *DataRequestRepo {
+	return &DataRequestRepo{db: db.DB}
+}
+
+func (r *DataRequestRepo) Create(d *models.DataRequest) error {
+	return r.db.Create(d).Error
+}
+
+func (r *DataRequestRepo) Update(d *models.DataRequest) error {
+	return r.db.Save(d).Error
+}
+
+// FindForUser ищет запрос, принадлежащий пользователю
+func (r *DataRequestRepo) FindForUser(id, userID uint) (*models.DataRequest, error) {
+	var d models.DataRequest
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&d).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDataRequestNotFound
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *DataRequestRepo) ListByUser(userID uint) ([]models.DataRequest, error) {
+	var items []models.DataRequest
+	err := r.db.Where("user_id = ?", userID).Order("id desc").Find(&items).Error
+	return items, err
+}
+
+// WithFiles - выгрузки пользователя, архивы которых ещё лежат на диске
+func (r *DataRequestRepo) WithFiles(userID uint) ([]models.DataRequest, error) {
+	var items []models.DataRequest
+	err := r.db.Where("user_id = ? AND kind = ? AND file <> ''", userID, models.DataRequestExport).Find(&items).Error
+	return items, err
+}
+
+// Expired - выгрузки с архивом, срок которых истёк к моменту now
+func (r *DataRequestRepo) Expired(now time.Time) ([]models.DataRequest, error) {
+	var items []models.DataRequest
+	err := r.db.Where("kind = ? AND file <> '' AND expires_at <= ?", models.DataRequestExport, now).Find(&items).Error
+	return items, err
+}
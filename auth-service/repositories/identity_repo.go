@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrStateInvalid - state не найден, истёк или уже использован
+	ErrStateInvalid = errors.New("invalid or expired login state")
+)
+
+type IdentityRepo struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepo() *IdentityRepo {
+	return &IdentityRepo{db: db.DB}
+}
+
+func (r *IdentityRepo) Create(i *models.ExternalIdentity) error {
+	return r.db.Create(i).Error
+}
+
+func (r *IdentityRepo) Update(i *models.ExternalIdentity) error {
+	return r.db.Save(i).Error
+}
+
+func (r *IdentityRepo) FindBySubject(provider, subject string) (*models.ExternalIdentity, error) {
+	var i models.ExternalIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&i).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+// FindForUser находит привязку только среди привязок userID
+func (r *IdentityRepo) FindForUser(id, userID uint) (*models.ExternalIdentity, error) {
+	var i models.ExternalIdentity
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&i).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *IdentityRepo) ListByUser(userID uint) ([]models.ExternalIdentity, error) {
+	var items []models.ExternalIdentity
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&items).Error
+	return items, err
+}
+
+func (r *IdentityRepo) Delete(i *models.ExternalIdentity) error {
+	return r.db.Delete(i).Error
+}
+
+func (r *IdentityRepo) CreateState(s *models.SSOState) error {
+	return r.db.Create(s).Error
+}
+
+// ConsumeState удаляет и возвращает state; повторное использование даёт ErrStateInvalid
+func (r *IdentityRepo) ConsumeState(hash string, now time.Time) (*models.SSOState, error) {
+	var s models.SSOState
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state_hash = ?", hash).First(&s).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrStateInvalid
+			}
+			return err
+		}
+		res := tx.Delete(&models.SSOState{}, s.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 || !now.Before(s.ExpiresAt) {
+			return ErrStateInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteExpiredStates убирает брошенные попытки входа
+func (r *IdentityRepo) DeleteExpiredStates(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&models.SSOState{}).Error
+}
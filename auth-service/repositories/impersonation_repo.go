@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrImpersonationNotFound = errors.New("impersonation not found")
+
+type ImpersonationRepo struct {
+	db *gorm.DB
+}
+
+func NewImpersonationRepo() *ImpersonationRepo {
+	return &ImpersonationRepo{db: db.DB}
+}
+
+func (r *ImpersonationRepo) Create(i *models.Impersonation) error {
+	return r.db.Create(i).Error
+}
+
+func (r *ImpersonationRepo) FindByID(id uint) (*models.Impersonation, error) {
+	var i models.Impersonation
+	if err := r.db.First(&i, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImpersonationNotFound
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *ImpersonationRepo) FindBySID(sid string) (*models.Impersonation, error) {
+	var i models.Impersonation
+	if err := r.db.Where("sid = ?", sid).First(&i).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImpersonationNotFound
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+// End отмечает завершение и отзывает сессию имперсонации в одной транзакции
+func (r *ImpersonationRepo) End(i *models.Impersonation, now time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(i).Update("ended_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("sid = ? AND revoked_at IS NULL", i.SID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// ImpersonationFilter - параметры выборки журнала; пустые поля не ограничивают выборку
+type ImpersonationFilter struct {
+	ActorID *uint
+	UserID  *uint
+}
+
+func (r *ImpersonationRepo) List(f ImpersonationFilter, offset, limit int) ([]models.Impersonation, int64, error) {
+	var items []models.Impersonation
+	var total int64
+
+	q := r.db.Model(&models.Impersonation{})
+	if f.ActorID != nil {
+		q = q.Where("actor_id = ?", *f.ActorID)
+	}
+	if f.UserID != nil {
+		q = q.Where("user_id = ?", *f.UserID)
+	}
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := q.Order("created_at desc, id desc").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (r *ImpersonationRepo) RecordAction(a *models.ImpersonationAction) error {
+	return r.db.Create(a).Error
+}
+
+// Actions - действия в хронологическом порядке
+func (r *ImpersonationRepo) Actions(impersonationID uint) ([]models.ImpersonationAction, error) {
+	var items []models.ImpersonationAction
+	err := r.db.Where("impersonation_id = ?", impersonationID).Order("created_at, id").Find(&items).Error
+	return items, err
+}
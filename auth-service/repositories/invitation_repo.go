@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+type InvitationRepo struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepo() *InvitationRepo {
+	return &InvitationRepo{db: db.DB}
+}
+
+// Transaction выполняет fn в одной транзакции с репозиториями приглашений и пользователей
+func (r *InvitationRepo) Transaction(fn func(invitations *InvitationRepo, users *UserRepo) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&InvitationRepo{db: tx}, &UserRepo{db: tx})
+	})
+}
+
+func (r *InvitationRepo) Create(i *models.Invitation) error {
+	return r.db.Create(i).Error
+}
+
+func (r *InvitationRepo) Update(i *models.Invitation) error {
+	return r.db.Save(i).Error
+}
+
+func (r *InvitationRepo) FindByID(id uint) (*models.Invitation, error) {
+	var i models.Invitation
+	if err := r.db.First(&i, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+// FindPending - действующее приглашение по ссылке из письма
+func (r *InvitationRepo) FindPending(hash string, now time.Time) (*models.Invitation, error) {
+	var i models.Invitation
+	err := r.db.Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", hash, now).
+		First(&i).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// HasPending сообщает, есть ли на email действующее приглашение
+func (r *InvitationRepo) HasPending(email string, now time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Invitation{}).
+		Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", email, now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Accept помечает действующее приглашение принятым; повторное принятие даёт ErrInvitationNotFound
+func (r *InvitationRepo) Accept(hash string, now time.Time) (*models.Invitation, error) {
+	var i models.Invitation
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", hash, now).
+			First(&i).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvitationNotFound
+			}
+			return err
+		}
+		res := tx.Model(&models.Invitation{}).Where("id = ? AND accepted_at IS NULL", i.ID).Update("accepted_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvitationNotFound
+		}
+		i.AcceptedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// List выбирает приглашения в состоянии status (models.Invitation*), пустой status - все
+func (r *InvitationRepo) List(status string, now time.Time, offset, limit int) ([]models.Invitation, int64, error) {
+	var items []models.Invitation
+	var total int64
+
+	q := r.db.Model(&models.Invitation{})
+	switch status {
+	case models.InvitationPending:
+		q = q.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	case models.InvitationAccepted:
+		q = q.Where("accepted_at IS NOT NULL")
+	case models.InvitationRevoked:
+		q = q.Where("revoked_at IS NOT NULL")
+	case models.InvitationExpired:
+		q = q.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	}
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := q.Order("created_at desc, id desc").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginRepo struct {
+	db *gorm.DB
+}
+
+func NewLoginRepo() *LoginRepo {
+	return &LoginRepo{db: db.DB}
+}
+
+func (r *LoginRepo) RecordAttempt(a *models.LoginAttempt) error {
+	return r.db.Create(a).Error
+}
+
+// AttemptFilter - параметры выборки журнала входов; пустые поля не ограничивают выборку
+type AttemptFilter struct {
+	UserID  *uint
+	Email   string
+	IP      string
+	Success *bool
+	From    *time.Time
+	To      *time.Time
+}
+
+func (r *LoginRepo) ListAttempts(f AttemptFilter, offset, limit int) ([]models.LoginAttempt, int64, error) {
+	var items []models.LoginAttempt
+	var total int64
+
+	q := r.db.Model(&models.LoginAttempt{})
+	if f.UserID != nil {
+		q = q.Where("user_id = ?", *f.UserID)
+	}
+	if f.Email != "" {
+		q = q.Where("email = ?", f.Email)
+	}
+	if f.IP != "" {
+		q = q.Where("ip = ?", f.IP)
+	}
+	if f.Success != nil {
+		q = q.Where("success = ?", *f.Success)
+	}
+	if f.From != nil {
+		q = q.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("created_at <= ?", *f.To)
+	}
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := q.Order("created_at desc, id desc").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// FindThrottle возвращает счётчик по ключу или пустой, если его ещё нет
+func (r *LoginRepo) FindThrottle(key string) (*models.LoginThrottle, error) {
+	var t models.LoginThrottle
+	err := r.db.Where("key = ?", key).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.LoginThrottle{Key: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// AddFailure атомарно увеличивает счётчик key и возвращает его новое состояние. Счётчик
+// начинается заново, если блокировка истекла к now или последняя ошибка была раньше forgetBefore.
+func (r *LoginRepo) AddFailure(key string, now, forgetBefore time.Time) (*models.LoginThrottle, error) {
+	t := models.LoginThrottle{Key: key, Failures: 1, LastFailureAt: now}
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures": gorm.Expr("CASE WHEN locked_until <= ? OR last_failure_at < ? THEN 1 ELSE failures + 1 END",
+				now, forgetBefore),
+			"locked_until":    gorm.Expr("CASE WHEN locked_until <= ? THEN NULL ELSE locked_until END", now),
+			"last_failure_at": now,
+			"updated_at":      now,
+		}),
+	}).Create(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.FindThrottle(key)
+}
+
+// LockThrottle блокирует ключ до until
+func (r *LoginRepo) LockThrottle(key string, until time.Time) error {
+	return r.db.Model(&models.LoginThrottle{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (r *LoginRepo) DeleteThrottle(key string) error {
+	return r.db.Where("key = ?", key).Delete(&models.LoginThrottle{}).Error
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrCodeInvalid - код не найден, истёк или уже обменян
+var ErrCodeInvalid = errors.New("invalid or expired authorization code")
+
+type OAuthRepo struct {
+	db *gorm.DB
+}
+
+func NewOAuthRepo() *OAuthRepo {
+	return &OAuthRepo{db: db.DB}
+}
+
+func (r *OAuthRepo) CreateClient(c *models.OAuthClient) error {
+	return r.db.Create(c).Error
+}
+
+func (r *OAuthRepo) FindClient(clientID string) (*models.OAuthClient, error) {
+	var c models.OAuthClient
+	if err := r.db.Where("client_id = ?", clientID).First(&c).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *OAuthRepo) ListClients() ([]models.OAuthClient, error) {
+	var items []models.OAuthClient
+	err := r.db.Order("id").Find(&items).Error
+	return items, err
+}
+
+func (r *OAuthRepo) UpdateClient(c *models.OAuthClient) error {
+	return r.db.Save(c).Error
+}
+
+// DeleteClient удаляет клиента вместе с его необменянными кодами
+func (r *OAuthRepo) DeleteClient(c *models.OAuthClient) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("client_id = ?", c.ClientID).Delete(&models.AuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(c).Error
+	})
+}
+
+func (r *OAuthRepo) CreateCode(code *models.AuthorizationCode) error {
+	return r.db.Create(code).Error
+}
+
+// ConsumeCode помечает код использованным; повторный обмен возвращает ErrCodeInvalid
+func (r *OAuthRepo) ConsumeCode(hash string, now time.Time) (*models.AuthorizationCode, error) {
+	var code models.AuthorizationCode
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("code_hash = ? AND used_at IS NULL AND expires_at > ?", hash, now).
+			First(&code).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCodeInvalid
+			}
+			return err
+		}
+		res := tx.Model(&models.AuthorizationCode{}).Where("id = ? AND used_at IS NULL", code.ID).Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrCodeInvalid
+		}
+		code.UsedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
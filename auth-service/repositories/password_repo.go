@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+type PasswordRepo struct {
+	db *gorm.DB
+}
+
+func NewPasswordRepo() *PasswordRepo {
+	return &PasswordRepo{db: db.DB}
+}
+
+// Recent - последние limit прежних хешей, новые первыми
+func (r *PasswordRepo) Recent(userID uint, limit int) ([]models.PasswordHistory, error) {
+	var items []models.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).Order("id desc").Limit(limit).Find(&items).Error
+	return items, err
+}
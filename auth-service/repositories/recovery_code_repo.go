@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepo struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepo() *RecoveryCodeRepo {
+	return &RecoveryCodeRepo{db: db.DB}
+}
+
+// Replace удаляет все коды пользователя и сохраняет новые
+func (r *RecoveryCodeRepo) Replace(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.RecoveryCode, len(hashes))
+		for i, h := range hashes {
+			codes[i] = models.RecoveryCode{UserID: userID, CodeHash: h}
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *RecoveryCodeRepo) ListUnused(userID uint) ([]models.RecoveryCode, error) {
+	var codes []models.RecoveryCode
+	err := r.db.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error
+	return codes, err
+}
+
+func (r *RecoveryCodeRepo) CountUnused(userID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+// MarkUsed гасит код; false, если его уже использовали параллельно
+func (r *RecoveryCodeRepo) MarkUsed(id uint, now time.Time) (bool, error) {
+	res := r.db.Model(&models.RecoveryCode{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", now)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *RecoveryCodeRepo) DeleteAll(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
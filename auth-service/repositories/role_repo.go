@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+type RoleRepo struct {
+	db *gorm.DB
+}
+
+func NewRoleRepo() *RoleRepo {
+	return &RoleRepo{db: db.DB}
+}
+
+func (r *RoleRepo) List() ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *RoleRepo) FindByName(name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepo) ListPermissions() ([]models.Permission, error) {
+	var perms []models.Permission
+	err := r.db.Order("name").Find(&perms).Error
+	return perms, err
+}
+
+// FindPermissions возвращает права по именам; неизвестные имена пропускаются
+func (r *RoleRepo) FindPermissions(names []string) ([]models.Permission, error) {
+	var perms []models.Permission
+	if len(names) == 0 {
+		return perms, nil
+	}
+	err := r.db.Where("name IN ?", names).Find(&perms).Error
+	return perms, err
+}
+
+func (r *RoleRepo) Create(role *models.Role) error {
+	return r.db.Create(role).Error
+}
+
+// Update сохраняет описание и заменяет набор прав роли
+func (r *RoleRepo) Update(role *models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Update("description", role.Description).Error; err != nil {
+			return err
+		}
+		assoc := tx.Model(role).Association("Permissions")
+		if len(role.Permissions) == 0 {
+			return assoc.Clear()
+		}
+		return assoc.Replace(role.Permissions)
+	})
+}
+
+func (r *RoleRepo) Delete(role *models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+}
+
+// CountUsers - сколько учётных записей (включая удалённые) имеют роль
+func (r *RoleRepo) CountUsers(name string) (int64, error) {
+	var n int64
+	err := r.db.Unscoped().Model(&models.User{}).Where("role = ?", name).Count(&n).Error
+	return n, err
+}
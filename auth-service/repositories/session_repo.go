@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepo struct {
+	db *gorm.DB
+}
+
+func NewSessionRepo() *SessionRepo {
+	return &SessionRepo{db: db.DB}
+}
+
+func (r *SessionRepo) Create(s *models.Session) error {
+	return r.db.Create(s).Error
+}
+
+func (r *SessionRepo) FindBySID(sid string) (*models.Session, error) {
+	var s models.Session
+	if err := r.db.Where("sid = ?", sid).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SessionRepo) FindForUser(id, userID uint) (*models.Session, error) {
+	var s models.Session
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListActive - неотозванные и неистёкшие сессии, последние активные первыми
+func (r *SessionRepo) ListActive(userID uint, now time.Time) ([]models.Session, error) {
+	var items []models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at desc").Find(&items).Error
+	return items, err
+}
+
+// ListByUser - все сессии пользователя, включая завершённые
+func (r *SessionRepo) ListByUser(userID uint) ([]models.Session, error) {
+	var items []models.Session
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&items).Error
+	return items, err
+}
+
+func (r *SessionRepo) Touch(id uint, now time.Time) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Update("last_seen_at", now).Error
+}
+
+func (r *SessionRepo) Revoke(s *models.Session, now time.Time) error {
+	s.RevokedAt = &now
+	return r.db.Model(s).Update("revoked_at", now).Error
+}
+
+// RevokeAll отзывает все активные сессии пользователя и возвращает их количество
+func (r *SessionRepo) RevokeAll(userID uint, now time.Time) (int64, error) {
+	res := r.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Update("revoked_at", now)
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrTokenInvalid = errors.New("invalid or expired token")
+
+type TokenRepo struct {
+	db *gorm.DB
+}
+
+func NewTokenRepo() *TokenRepo {
+	return &TokenRepo{db: db.DB}
+}
+
+// Transaction выполняет fn в одной транзакции с репозиториями токенов и пользователей
+func (r *TokenRepo) Transaction(fn func(tokens *TokenRepo, users *UserRepo) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&TokenRepo{db: tx}, &UserRepo{db: tx})
+	})
+}
+
+func (r *TokenRepo) Create(t *models.ActionToken) error {
+	return r.db.Create(t).Error
+}
+
+// FindActive возвращает неиспользованный и неистёкший токен, не помечая его использованным
+func (r *TokenRepo) FindActive(hash, purpose string, now time.Time) (*models.ActionToken, error) {
+	var t models.ActionToken
+	if err := r.db.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Consume помечает неиспользованный и неистёкший токен как использованный и возвращает его.
+// Повторное использование возвращает ErrTokenInvalid.
+func (r *TokenRepo) Consume(hash, purpose string, now time.Time) (*models.ActionToken, error) {
+	var t models.ActionToken
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+			First(&t).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTokenInvalid
+			}
+			return err
+		}
+		// условие used_at IS NULL защищает от гонки двух одновременных запросов
+		res := tx.Model(&models.ActionToken{}).Where("id = ? AND used_at IS NULL", t.ID).Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTokenInvalid
+		}
+		t.UsedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// InvalidateAll гасит все активные токены пользователя с данным назначением
+func (r *TokenRepo) InvalidateAll(userID uint, purpose string, now time.Time) error {
+	return r.db.Model(&models.ActionToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", now).Error
+}
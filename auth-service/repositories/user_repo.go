@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"auth-service/db"
 	"auth-service/models"
@@ -45,6 +47,18 @@ func (r *UserRepo) FindByID(id uint) (*models.User, error) {
 	return &u, nil
 }
 
+// FindByIDUnscoped находит пользователя, в том числе мягко удалённого
+func (r *UserRepo) FindByIDUnscoped(id uint) (*models.User, error) {
+	var u models.User
+	if err := r.db.Unscoped().First(&u, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
 func (r *UserRepo) Update(u *models.User) error {
 	return r.db.Save(u).Error
 }
@@ -53,13 +67,146 @@ func (r *UserRepo) Delete(u *models.User) error {
 	return r.db.Delete(u).Error
 }
 
-func (r *UserRepo) List(offset, limit int) ([]models.User, int64, error) {
+// Restore снимает отметку мягкого удаления
+func (r *UserRepo) Restore(u *models.User) error {
+	if err := r.db.Unscoped().Model(u).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	u.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// Purge удаляет запись пользователя физически вместе со связанными данными: привязками
+// внешних провайдеров, историей паролей, сессиями, ключами, одноразовыми токенами, приглашениями
+// и счётчиками неудачных входов по email и по IP из его журнала входов.
+// Журнал входов обезличивается, журнал имперсонаций остаётся с одним ID.
+func (r *UserRepo) Purge(u *models.User) error {
+	email := strings.ToLower(strings.TrimSpace(u.Email))
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{
+			&models.ExternalIdentity{}, &models.PasswordHistory{}, &models.Session{},
+			&models.APIKey{}, &models.ActionToken{}, &models.RecoveryCode{},
+		} {
+			if err := tx.Where("user_id = ?", u.ID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_id = ? OR LOWER(email) = ?", u.ID, email).Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		// IP берём из журнала входов до обезличивания
+		var ips []string
+		if err := tx.Model(&models.LoginAttempt{}).Where("(user_id = ? OR email = ?) AND ip <> ''", u.ID, u.Email).
+			Distinct().Pluck("ip", &ips).Error; err != nil {
+			return err
+		}
+		keys := []string{"account:" + email}
+		for _, ip := range ips {
+			keys = append(keys, "ip:"+ip)
+		}
+		if err := tx.Where("key IN ?", keys).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.LoginAttempt{}).Where("user_id = ? OR email = ?", u.ID, u.Email).
+			Updates(map[string]interface{}{"user_id": nil, "email": "", "ip": "", "user_agent": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(u).Error
+	})
+}
+
+// Значения UserFilter.State
+const (
+	StateActive  = "active"
+	StateDeleted = "deleted"
+	StateAll     = "all"
+)
+
+// UserFilter - параметры выборки для List. Пустые поля не ограничивают выборку.
+type UserFilter struct {
+	IDs         []uint // выборка по списку id, например для пакетного поиска из других сервисов
+	Role        string
+	Email       string // подстрока email
+	Search      string // подстрока email или full_name
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	State       string // active (по умолчанию), deleted, all
+	SortBy      string
+	SortDesc    bool
+}
+
+// sortableColumns - белый список колонок, по которым разрешена сортировка
+var sortableColumns = map[string]string{
+	"id":         "id",
+	"email":      "email",
+	"full_name":  "full_name",
+	"role":       "role",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// IsSortable сообщает, можно ли сортировать по колонке
+func IsSortable(column string) bool {
+	_, ok := sortableColumns[column]
+	return ok
+}
+
+func (r *UserRepo) List(f UserFilter, offset, limit int) ([]models.User, int64, error) {
 	var users []models.User
 	var total int64
+
 	q := r.db.Model(&models.User{})
-	q.Count(&total)
-	if err := q.Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+	switch f.State {
+	case StateDeleted:
+		q = q.Unscoped().Where("deleted_at IS NOT NULL")
+	case StateAll:
+		q = q.Unscoped()
+	}
+	if len(f.IDs) > 0 {
+		q = q.Where("id IN ?", f.IDs)
+	}
+	if f.Role != "" {
+		q = q.Where("role = ?", f.Role)
+	}
+	if f.Email != "" {
+		q = q.Where(`LOWER(email) LIKE ? ESCAPE '\'`, likePattern(f.Email))
+	}
+	if f.Search != "" {
+		p := likePattern(f.Search)
+		q = q.Where(`LOWER(email) LIKE ? ESCAPE '\' OR LOWER(full_name) LIKE ? ESCAPE '\'`, p, p)
+	}
+	if f.CreatedFrom != nil {
+		q = q.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q = q.Where("created_at <= ?", *f.CreatedTo)
+	}
+
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := sortableColumns[f.SortBy]
+	if !ok {
+		column = "id"
+	}
+	order := column + " ASC"
+	if f.SortDesc {
+		order = column + " DESC"
+	}
+	// id как второй ключ делает пагинацию стабильной при одинаковых значениях
+	if column != "id" {
+		order += ", id ASC"
+	}
+
+	if err := q.Order(order).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 	return users, total, nil
 }
+
+func likePattern(s string) string {
+	s = strings.ToLower(s)
+	s = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+	return "%" + s + "%"
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/db"
+	"auth-service/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *UserRepo {
+	var err error
+	db.DB, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect test database: %v", err)
+	}
+	if err := db.DB.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	return NewUserRepo()
+}
+
+func seedUsers(t *testing.T, repo *UserRepo) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := []models.User{
+		{Email: "alice@example.com", FullName: "Alice Smith", Role: "admin", CreatedAt: base},
+		{Email: "bob@example.com", FullName: "Bob Jones", Role: "user", CreatedAt: base.AddDate(0, 1, 0)},
+		{Email: "carol@shop.io", FullName: "Carol Smith", Role: "user", CreatedAt: base.AddDate(0, 2, 0)},
+		{Email: "dave_x@shop.io", FullName: "Dave", Role: "user", CreatedAt: base.AddDate(0, 3, 0)},
+	}
+	for i := range users {
+		users[i].Password = "x"
+		if err := repo.Create(&users[i]); err != nil {
+			t.Fatalf("Не удалось создать пользователя: %v", err)
+		}
+	}
+	// Dave удалён (soft delete)
+	if err := repo.Delete(&users[3]); err != nil {
+		t.Fatalf("Не удалось удалить пользователя: %v", err)
+	}
+}
+
+func emails(users []models.User) []string {
+	out := make([]string, len(users))
+	for i, u := range users {
+		out[i] = u.Email
+	}
+	return out
+}
+
+func TestUserRepo_ListFilters(t *testing.T) {
+	repo := setupTestDB(t)
+	seedUsers(t, repo)
+
+	from := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter UserFilter
+		want   []string
+	}{
+		{
+			name:   "По умолчанию только активные, сортировка по id",
+			filter: UserFilter{},
+			want:   []string{"alice@example.com", "bob@example.com", "carol@shop.io"},
+		},
+		{
+			name:   "Выборка по списку id, включая удалённых",
+			filter: UserFilter{IDs: []uint{1, 4}, State: StateAll},
+			want:   []string{"alice@example.com", "dave_x@shop.io"},
+		},
+		{
+			name:   "Фильтр по роли",
+			filter: UserFilter{Role: "user"},
+			want:   []string{"bob@example.com", "carol@shop.io"},
+		},
+		{
+			name:   "Подстрока email без учёта регистра",
+			filter: UserFilter{Email: "SHOP"},
+			want:   []string{"carol@shop.io"},
+		},
+		{
+			name:   "Поиск по имени",
+			filter: UserFilter{Search: "smith"},
+			want:   []string{"alice@example.com", "carol@shop.io"},
+		},
+		{
+			name:   "Подчёркивание в поиске не является шаблоном",
+			filter: UserFilter{Search: "_", State: StateAll},
+			want:   []string{"dave_x@shop.io"},
+		},
+		{
+			name:   "Диапазон даты регистрации",
+			filter: UserFilter{CreatedFrom: &from, CreatedTo: &to},
+			want:   []string{"bob@example.com"},
+		},
+		{
+			name:   "Только удалённые",
+			filter: UserFilter{State: StateDeleted},
+			want:   []string{"dave_x@shop.io"},
+		},
+		{
+			name:   "Сортировка по дате регистрации по убыванию",
+			filter: UserFilter{State: StateAll, SortBy: "created_at", SortDesc: true},
+			want:   []string{"dave_x@shop.io", "carol@shop.io", "bob@example.com", "alice@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, total, err := repo.List(tt.filter, 0, 20)
+			if err != nil {
+				t.Fatalf("Неожиданная ошибка: %v", err)
+			}
+			got := emails(users)
+			if int(total) != len(tt.want) {
+				t.Errorf("total: ожидалось %d, получено %d", len(tt.want), total)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Ожидалось %v, получено %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Ожидалось %v, получено %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRepo_PurgeRemovesInvitationsAndThrottles(t *testing.T) {
+	repo := setupTestDB(t)
+	seedUsers(t, repo)
+	bob, err := repo.FindByEmail("bob@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := db.DB
+	d.Create(&[]models.Invitation{
+		{Email: "Bob@Example.com", Role: "user", TokenHash: "h1", UserID: &bob.ID},
+		{Email: "carol@shop.io", Role: "user", TokenHash: "h2"},
+	})
+	d.Create(&[]models.LoginAttempt{
+		{UserID: &bob.ID, Email: "bob@example.com", IP: "10.0.0.1"},
+		{Email: "bob@example.com", IP: "10.0.0.2"},
+		{Email: "carol@shop.io", IP: "10.0.0.3"},
+	})
+	for _, key := range []string{"account:bob@example.com", "ip:10.0.0.1", "ip:10.0.0.2", "account:carol@shop.io", "ip:10.0.0.3"} {
+		d.Create(&models.LoginThrottle{Key: key, Failures: 1})
+	}
+
+	if err := repo.Purge(bob); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+
+	var invitations []models.Invitation
+	d.Find(&invitations)
+	if len(invitations) != 1 || invitations[0].Email != "carol@shop.io" {
+		t.Errorf("Должно остаться только чужое приглашение, получено %+v", invitations)
+	}
+	var keys []string
+	d.Model(&models.LoginThrottle{}).Order("key").Pluck("key", &keys)
+	if want := []string{"account:carol@shop.io", "ip:10.0.0.3"}; strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("Счётчики входов: ожидалось %v, получено %v", want, keys)
+	}
+	var left int64
+	d.Model(&models.LoginAttempt{}).Where("email = ? OR ip IN ?", "bob@example.com", []string{"10.0.0.1", "10.0.0.2"}).Count(&left)
+	if left != 0 {
+		t.Errorf("Журнал входов не обезличен, записей: %d", left)
+	}
+}
@@ -1,8 +1,10 @@
 package routes
 
 import (
+	"auth-service/config"
 	"auth-service/handlers"
 	"auth-service/middleware"
+	"auth-service/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,25 +12,149 @@ import (
 func Setup(r *gin.Engine) {
 	authHandler := handlers.NewAuthHandler()
 	userHandler := handlers.NewUserHandler()
+	mfaHandler := handlers.NewMFAHandler()
+	roleHandler := handlers.NewRoleHandler()
+	sessionHandler := handlers.NewSessionHandler()
+	oidcHandler := handlers.NewOIDCHandler()
+	identityHandler := handlers.NewIdentityHandler()
+	apiKeyHandler := handlers.NewAPIKeyHandler()
+	impersonationHandler := handlers.NewImpersonationHandler()
+	invitationHandler := handlers.NewInvitationHandler()
+	privacyHandler := handlers.NewPrivacyHandler()
 
 	// public
 	r.POST("/api/v1/register", authHandler.Register)
 	r.POST("/api/v1/login", authHandler.Login)
+	r.POST("/api/v1/login/2fa", authHandler.LoginMFA)
+	r.POST("/api/v1/login/2fa/enroll", authHandler.LoginEnroll)
+	r.POST("/api/v1/login/2fa/enroll/confirm", authHandler.LoginEnrollConfirm)
+	r.GET("/api/v1/email/verify", authHandler.VerifyEmail)
+	r.POST("/api/v1/email/verify", authHandler.VerifyEmail)
+	r.POST("/api/v1/email/verify/resend", authHandler.ResendVerification)
+	r.POST("/api/v1/password/forgot", authHandler.ForgotPassword)
+	r.POST("/api/v1/password/reset", authHandler.ResetPassword)
+	r.GET("/api/v1/invitations/accept", invitationHandler.Lookup)
+	r.POST("/api/v1/invitations/accept", invitationHandler.Accept)
 
-	r.GET("/api/v1/internal/users/:id", userHandler.GetUserInternal)
-	r.GET("/api/v1/internal/users/:id/role", userHandler.GetUserRole)
-	r.PATCH("/api/v1/internal/users/:id/role", userHandler.UpdateUserRoleInternal)
+	// вход через внешние OpenID Connect провайдеры
+	r.GET("/api/v1/sso/providers", authHandler.SSOProviders)
+	r.GET("/api/v1/sso/:provider/login", authHandler.SSOLogin)
+	r.POST("/api/v1/sso/:provider/login", authHandler.SSOLogin)
+	r.GET("/api/v1/sso/:provider/callback", authHandler.SSOCallback)
+	r.POST("/api/v1/sso/:provider/callback", authHandler.SSOCallback)
+
+	// OpenID Connect provider
+	r.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+	r.GET("/.well-known/jwks.json", oidcHandler.JWKS)
+	r.POST("/api/v1/oauth/token", oidcHandler.Token)
+
+	// internal endpoints that require X-Internal-Token
+	internal := r.Group("/api/v1/internal")
+	internal.Use(middleware.InternalAuthMiddleware(config.InternalToken))
+	{
+		internal.GET("/users", userHandler.ListUsersInternal)
+		internal.GET("/users/:id", userHandler.GetUserInternal)
+		internal.GET("/users/:id/role", userHandler.GetUserRole)
+		internal.PATCH("/users/:id/role", userHandler.UpdateUserRoleInternal)
+		internal.POST("/api-keys/verify", apiKeyHandler.VerifyInternal)
+		internal.POST("/impersonation/actions", impersonationHandler.RecordInternal)
+	}
 
 	auth := r.Group("/api/v1")
 	auth.Use(middleware.JWTAuthMiddleware())
 
 	{
 		auth.GET("/me", userHandler.GetMe)
-		// admin-only endpoints
-		auth.GET("/users", userHandler.ListUsers)         // admin
-		auth.POST("/users", userHandler.CreateUser)       // admin can create admin/user
-		auth.GET("/users/:id", userHandler.GetUser)       // admin or owner
-		auth.PUT("/users/:id", userHandler.UpdateUser)    // admin or owner
-		auth.DELETE("/users/:id", userHandler.DeleteUser) // admin or owner
+		auth.GET("/oauth/userinfo", oidcHandler.UserInfo)
+		auth.POST("/oauth/userinfo", oidcHandler.UserInfo)
+		// user management
+		auth.GET("/users", userHandler.ListUsers)         // users.read
+		auth.POST("/users", invitationHandler.Create)     // users.manage, roles.manage for non-user roles; sends an invitation
+		auth.GET("/users/:id", userHandler.GetUser)       // permission or owner
+		auth.PUT("/users/:id", userHandler.UpdateUser)    // permission or owner
+		auth.DELETE("/users/:id", userHandler.DeleteUser) // permission or owner
+
+		// account moderation (users.manage)
+		auth.GET("/users/deleted", userHandler.ListDeletedUsers)
+		auth.POST("/users/:id/suspend", userHandler.SuspendUser)
+		auth.POST("/users/:id/unsuspend", userHandler.UnsuspendUser)
+		auth.POST("/users/:id/restore", userHandler.RestoreUser)
+		auth.DELETE("/users/:id/purge", userHandler.PurgeUser)
+
+		// login protection (users.manage, audit.read)
+		auth.GET("/users/:id/login-status", userHandler.LoginStatus)
+		auth.POST("/users/:id/unlock", userHandler.UnlockUser)
+		auth.GET("/login-attempts", userHandler.ListLoginAttempts)
+
+		// invitations (users.manage)
+		auth.POST("/invitations", invitationHandler.Create)
+		auth.GET("/invitations", invitationHandler.List)
+		auth.POST("/invitations/:id/resend", invitationHandler.Resend)
+		auth.DELETE("/invitations/:id", invitationHandler.Revoke)
+
+		// sessions (users.read, users.manage)
+		auth.GET("/users/:id/sessions", sessionHandler.UserSessions)
+		auth.POST("/users/:id/logout-all", sessionHandler.RevokeUserSessions)
+
+		// завершение имперсонации - с токеном имперсонации
+		auth.POST("/impersonation/end", impersonationHandler.End)
+	}
+
+	// вход, 2FA, API-ключи, имперсонация и персональные данные - только по своей сессии,
+	// не по API-ключу и не от имени другого пользователя
+	session := r.Group("/api/v1")
+	session.Use(middleware.JWTAuthMiddleware(), middleware.RequireSession())
+	{
+		session.GET("/me/2fa", mfaHandler.Status)
+		session.POST("/me/2fa/setup", mfaHandler.Setup)
+		session.POST("/me/2fa/confirm", mfaHandler.Confirm)
+		session.POST("/me/2fa/disable", mfaHandler.Disable)
+		session.POST("/me/2fa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+		session.GET("/me/sessions", sessionHandler.MySessions)
+		session.DELETE("/me/sessions/:id", sessionHandler.RevokeMySession)
+		session.GET("/me/identities", identityHandler.List)
+		session.POST("/me/identities/:provider", identityHandler.Link)
+		session.POST("/me/identities/:provider/callback", identityHandler.LinkCallback)
+		session.DELETE("/me/identities/:id", identityHandler.Unlink)
+		session.GET("/me/api-keys", apiKeyHandler.List)
+		session.POST("/me/api-keys", apiKeyHandler.Create)
+		session.DELETE("/me/api-keys/:id", apiKeyHandler.Revoke)
+		session.GET("/oauth/authorize", oidcHandler.Authorize)
+		session.POST("/oauth/authorize", oidcHandler.Authorize)
+		session.POST("/users/:id/impersonate", impersonationHandler.Start) // users.impersonate
+		session.POST("/me/data-export", privacyHandler.Export)
+		session.GET("/me/data-export/:id", privacyHandler.Download)
+		session.GET("/me/data-requests", privacyHandler.List)
+		session.POST("/me/data-erasure", privacyHandler.Erase)
+	}
+
+	// журнал имперсонаций
+	impersonations := r.Group("/api/v1/impersonations")
+	impersonations.Use(middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermAuditRead))
+	{
+		impersonations.GET("", impersonationHandler.List)
+		impersonations.GET("/:id", impersonationHandler.Get)
+	}
+
+	// roles and permissions
+	rbac := r.Group("/api/v1")
+	rbac.Use(middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermRolesManage))
+	{
+		rbac.GET("/permissions", roleHandler.ListPermissions)
+		rbac.GET("/roles", roleHandler.ListRoles)
+		rbac.POST("/roles", roleHandler.CreateRole)
+		rbac.GET("/roles/:name", roleHandler.GetRole)
+		rbac.PATCH("/roles/:name", roleHandler.UpdateRole)
+		rbac.DELETE("/roles/:name", roleHandler.DeleteRole)
+	}
+
+	// OpenID Connect clients
+	oauthClients := r.Group("/api/v1/oauth/clients")
+	oauthClients.Use(middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermOAuthManage))
+	{
+		oauthClients.GET("", oidcHandler.ListClients)
+		oauthClients.POST("", oidcHandler.CreateClient)
+		oauthClients.PUT("/:client_id", oidcHandler.UpdateClient)
+		oauthClients.DELETE("/:client_id", oidcHandler.DeleteClient)
 	}
 }
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"auth-service/config"
+	"auth-service/mailer"
+	"auth-service/models"
+	"auth-service/password"
+	"auth-service/repositories"
+	"auth-service/utils"
+)
+
+// AccountService - подтверждение email и сброс пароля по одноразовым ссылкам
+type AccountService struct {
+	users     *repositories.UserRepo
+	tokens    *repositories.TokenRepo
+	mail      mailer.Mailer
+	sessions  *SessionService
+	passwords *PasswordService
+}
+
+func NewAccountService() *AccountService {
+	return &AccountService{
+		users:     repositories.NewUserRepo(),
+		tokens:    repositories.NewTokenRepo(),
+		mail:      mailer.Default,
+		sessions:  NewSessionService(),
+		passwords: NewPasswordService(),
+	}
+}
+
+// SendVerification выпускает новую ссылку подтверждения; предыдущие ссылки перестают работать
+func (s *AccountService) SendVerification(u *models.User) error {
+	ttl := time.Duration(config.EmailVerifyTTLMin) * time.Minute
+	token, err := s.issue(u.ID, models.TokenEmailVerification, ttl)
+	if err != nil {
+		return err
+	}
+	return s.mail.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Здравствуйте!\n\nЧтобы подтвердить адрес, перейдите по ссылке:\n%s\n\nСсылка действует до %s.",
+			link("/verify-email", token), time.Now().Add(ttl).Format(time.RFC1123)),
+	})
+}
+
+// ResendVerification повторно отправляет ссылку. Неизвестный или уже подтверждённый
+// адрес не считается ошибкой, чтобы по ответу нельзя было перебирать email.
+func (s *AccountService) ResendVerification(email string) error {
+	u, err := s.users.FindByEmail(email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if u.EmailVerified {
+		return nil
+	}
+	return s.SendVerification(u)
+}
+
+func (s *AccountService) VerifyEmail(token string) (*models.User, error) {
+	now := time.Now()
+	t, err := s.tokens.Consume(utils.HashToken(token), models.TokenEmailVerification, now)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.FindByID(t.UserID)
+	if err != nil {
+		return nil, repositories.ErrTokenInvalid
+	}
+	if !u.EmailVerified {
+		u.MarkEmailVerified(now)
+		if err := s.users.Update(u); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// ForgotPassword отправляет ссылку сброса. Для неизвестного адреса молча ничего не делает.
+func (s *AccountService) ForgotPassword(email string) error {
+	u, err := s.users.FindByEmail(email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(config.PasswordResetTTLMin) * time.Minute
+	token, err := s.issue(u.ID, models.TokenPasswordReset, ttl)
+	if err != nil {
+		return err
+	}
+	return s.mail.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте!\n\nДля смены пароля перейдите по ссылке:\n%s\n\nСсылка действует %d мин. Если вы не запрашивали сброс, просто проигнорируйте письмо.",
+			link("/reset-password", token), config.PasswordResetTTLMin),
+	})
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма.
+// Переход по ссылке доказывает владение адресом, поэтому email заодно подтверждается.
+func (s *AccountService) ResetPassword(token, newPassword string) error {
+	// политику проверяем до использования токена, чтобы слабый пароль не сжигал ссылку
+	if err := password.Validate(newPassword); err != nil {
+		return err
+	}
+	now := time.Now()
+	hash := utils.HashToken(token)
+	t, err := s.tokens.FindActive(hash, models.TokenPasswordReset, now)
+	if err != nil {
+		return err
+	}
+	u, err := s.users.FindByID(t.UserID)
+	if err != nil {
+		return repositories.ErrTokenInvalid
+	}
+	// повтор недавнего пароля тоже проверяется до использования ссылки
+	if err := s.passwords.Set(u, newPassword); err != nil {
+		return err
+	}
+	if !u.EmailVerified {
+		u.MarkEmailVerified(now)
+	}
+	// ссылка гасится только вместе с сохранением пароля: если пользователь не сохранён,
+	// ссылка остаётся действующей
+	if err := s.tokens.Transaction(func(tokens *repositories.TokenRepo, users *repositories.UserRepo) error {
+		if _, err := tokens.Consume(hash, models.TokenPasswordReset, now); err != nil {
+			return err
+		}
+		return users.Update(u)
+	}); err != nil {
+		return err
+	}
+	// после сброса пароля старые входы недействительны
+	if _, err := s.sessions.RevokeAll(u.ID); err != nil {
+		return err
+	}
+	return s.tokens.InvalidateAll(u.ID, models.TokenPasswordReset, now)
+}
+
+func (s *AccountService) issue(userID uint, purpose string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := s.tokens.InvalidateAll(userID, purpose, now); err != nil {
+		return "", err
+	}
+	plain, hash, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.tokens.Create(&models.ActionToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: now.Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+func link(path, token string) string {
+	return config.AppBaseURL + path + "?token=" + url.QueryEscape(token)
+}
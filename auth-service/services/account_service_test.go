@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"auth-service/config"
+	"auth-service/db"
+	"auth-service/mailer"
+	"auth-service/models"
+	"auth-service/repositories"
+)
+
+// captureMailer запоминает отправленные письма
+type captureMailer struct {
+	sent []mailer.Message
+}
+
+func (m *captureMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var tokenRe = regexp.MustCompile(`\?token=(\S+)`)
+
+// tokenFrom достаёт токен из ссылки в последнем письме
+func (m *captureMailer) tokenFrom(t *testing.T) string {
+	t.Helper()
+	if len(m.sent) == 0 {
+		t.Fatalf("Письмо не было отправлено")
+	}
+	match := tokenRe.FindStringSubmatch(m.sent[len(m.sent)-1].Body)
+	if match == nil {
+		t.Fatalf("В письме нет ссылки с токеном: %s", m.sent[len(m.sent)-1].Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatalf("Некорректный токен в ссылке: %v", err)
+	}
+	return token
+}
+
+func setupAccountService(t *testing.T) (*AccountService, *captureMailer) {
+	setupTestDB()
+	config.EmailVerifyTTLMin = 60
+	config.PasswordResetTTLMin = 30
+	config.AppBaseURL = "http://app.test"
+
+	m := &captureMailer{}
+	mailer.Default = m
+	return NewAccountService(), m
+}
+
+func TestAccountService_EmailVerification(t *testing.T) {
+	accounts, m := setupAccountService(t)
+	config.RequireEmailVerification = true
+	defer func() { config.RequireEmailVerification = false }()
+
+	auth := NewAuthService()
+	user, err := auth.Register("verify@example.com", "password123", "Verify User")
+	if err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя: %v", err)
+	}
+	if err := accounts.SendVerification(user); err != nil {
+		t.Fatalf("Не удалось отправить письмо: %v", err)
+	}
+	if m.sent[0].To != "verify@example.com" {
+		t.Errorf("Письмо отправлено не тому адресату: %s", m.sent[0].To)
+	}
+
+	if _, err := auth.Authenticate("verify@example.com", "password123"); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("Вход без подтверждения должен быть запрещён, получено: %v", err)
+	}
+
+	token := m.tokenFrom(t)
+	if _, err := accounts.VerifyEmail(token); err != nil {
+		t.Fatalf("Неожиданная ошибка подтверждения: %v", err)
+	}
+	if _, err := accounts.VerifyEmail(token); !errors.Is(err, repositories.ErrTokenInvalid) {
+		t.Errorf("Токен должен быть одноразовым, получено: %v", err)
+	}
+	if _, err := auth.Authenticate("verify@example.com", "password123"); err != nil {
+		t.Errorf("После подтверждения вход должен работать: %v", err)
+	}
+}
+
+func TestAccountService_PasswordReset(t *testing.T) {
+	accounts, m := setupAccountService(t)
+
+	auth := NewAuthService()
+	if _, err := auth.Register("reset@example.com", "oldpassword1", "Reset User"); err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя: %v", err)
+	}
+
+	// Неизвестный адрес - без ошибки и без письма
+	if err := accounts.ForgotPassword("nobody@example.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(m.sent) != 0 {
+		t.Fatalf("Для неизвестного адреса письмо отправляться не должно")
+	}
+
+	if err := accounts.ForgotPassword("reset@example.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	first := m.tokenFrom(t)
+
+	// Новый запрос гасит предыдущую ссылку
+	if err := accounts.ForgotPassword("reset@example.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	second := m.tokenFrom(t)
+	if err := accounts.ResetPassword(first, "newpassword1"); !errors.Is(err, repositories.ErrTokenInvalid) {
+		t.Errorf("Старая ссылка должна быть недействительна, получено: %v", err)
+	}
+
+	if err := accounts.ResetPassword(second, "newpassword1"); err != nil {
+		t.Fatalf("Неожиданная ошибка сброса: %v", err)
+	}
+	if err := accounts.ResetPassword(second, "another1"); !errors.Is(err, repositories.ErrTokenInvalid) {
+		t.Errorf("Токен должен быть одноразовым, получено: %v", err)
+	}
+
+	u, err := auth.Authenticate("reset@example.com", "newpassword1")
+	if err != nil {
+		t.Fatalf("Вход с новым паролем не работает: %v", err)
+	}
+	if !u.EmailVerified {
+		t.Errorf("Сброс пароля по ссылке должен подтверждать email")
+	}
+	if _, err := auth.Authenticate("reset@example.com", "oldpassword1"); err == nil {
+		t.Errorf("Старый пароль не должен подходить")
+	}
+}
+
+func TestAccountService_RejectedPasswordKeepsLink(t *testing.T) {
+	accounts, m := setupAccountService(t)
+	config.PasswordHistory = 3
+
+	auth := NewAuthService()
+	if _, err := auth.Register("reuse@example.com", "oldpassword1", "Reuse User"); err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя: %v", err)
+	}
+	if err := accounts.ForgotPassword("reuse@example.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	token := m.tokenFrom(t)
+
+	if err := accounts.ResetPassword(token, "short"); err == nil {
+		t.Fatalf("Слабый пароль должен отклоняться")
+	}
+	if err := accounts.ResetPassword(token, "oldpassword1"); !errors.Is(err, ErrPasswordReused) {
+		t.Fatalf("Ожидалась ошибка повтора пароля, получено: %v", err)
+	}
+	if err := accounts.ResetPassword(token, "newpassword1"); err != nil {
+		t.Fatalf("Отклонённый пароль не должен сжигать ссылку: %v", err)
+	}
+	if _, err := auth.Authenticate("reuse@example.com", "newpassword1"); err != nil {
+		t.Errorf("Вход с новым паролем не работает: %v", err)
+	}
+}
+
+func TestAccountService_ExpiredToken(t *testing.T) {
+	accounts, m := setupAccountService(t)
+
+	auth := NewAuthService()
+	if _, err := auth.Register("expired@example.com", "password123", ""); err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя: %v", err)
+	}
+	if err := accounts.ForgotPassword("expired@example.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	token := m.tokenFrom(t)
+
+	// Сдвигаем срок действия в прошлое
+	if err := db.DB.Model(&models.ActionToken{}).Where("1 = 1").
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("Не удалось обновить токен: %v", err)
+	}
+	if err := accounts.ResetPassword(token, "newpassword1"); !errors.Is(err, repositories.ErrTokenInvalid) {
+		t.Errorf("Истёкший токен должен отклоняться, получено: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAPIKeyInvalid   = errors.New("invalid, expired or revoked api key")
+	ErrAPIKeyNotFound  = errors.New("api key not found")
+	ErrAPIKeyLimit     = errors.New("too many active api keys")
+	ErrAPIKeyTTL       = errors.New("invalid api key lifetime")
+	ErrScopeNotAllowed = errors.New("scope is not granted to your role")
+)
+
+// last_used_at обновляется не чаще раза в минуту, как и last_seen_at сессий
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyAccess - результат проверки ключа: владелец и права, действующие для запроса
+type APIKeyAccess struct {
+	User        *models.User
+	Key         *models.APIKey
+	Permissions []string
+}
+
+type APIKeyService struct {
+	repo  *repositories.APIKeyRepo
+	users *repositories.UserRepo
+	auth  *AuthService
+	rbac  *RBACService
+	now   func() time.Time
+}
+
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{
+		repo:  repositories.NewAPIKeyRepo(),
+		users: repositories.NewUserRepo(),
+		auth:  NewAuthService(),
+		rbac:  NewRBACService(),
+		now:   time.Now,
+	}
+}
+
+// Create выпускает ключ. Открытый ключ возвращается только здесь, в БД остаётся хеш.
+// scopes не могут превышать права роли пользователя; ttlDays == 0 - срок по умолчанию.
+func (s *APIKeyService) Create(u *models.User, name string, scopes []string, ttlDays int) (*models.APIKey, string, error) {
+	if ttlDays == 0 {
+		ttlDays = config.APIKeyDefaultTTLDays
+	}
+	if ttlDays < 1 || ttlDays > config.APIKeyMaxTTLDays {
+		return nil, "", fmt.Errorf("%w: expires_in_days must be between 1 and %d", ErrAPIKeyTTL, config.APIKeyMaxTTLDays)
+	}
+	granted, err := s.rbac.Permissions(u.Role)
+	if err != nil {
+		return nil, "", err
+	}
+	scopes = uniqueSorted(scopes)
+	for _, scope := range scopes {
+		if !contains(granted, scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+
+	now := s.now()
+	n, err := s.repo.CountActive(u.ID, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if n >= int64(config.APIKeyMaxPerUser) {
+		return nil, "", ErrAPIKeyLimit
+	}
+
+	secret, _, err := utils.NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plain := models.APIKeyPrefix + secret
+	key := &models.APIKey{
+		UserID:    u.ID,
+		Name:      name,
+		Prefix:    plain[:len(models.APIKeyPrefix)+8],
+		KeyHash:   utils.HashToken(plain),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: now.AddDate(0, 0, ttlDays),
+	}
+	if err := s.repo.Create(key); err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+func (s *APIKeyService) List(userID uint) ([]models.APIKey, error) {
+	return s.repo.ListActive(userID)
+}
+
+func (s *APIKeyService) Revoke(userID, id uint) error {
+	key, err := s.repo.FindForUser(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return s.repo.Revoke(key, s.now())
+}
+
+// Authenticate проверяет ключ из запроса. Права - пересечение scopes ключа и текущей роли
+// владельца: понижение роли сразу ограничивает и выпущенные ранее ключи.
+// Для заблокированного владельца возвращается SuspendedError.
+func (s *APIKeyService) Authenticate(plain string) (*APIKeyAccess, error) {
+	if !strings.HasPrefix(plain, models.APIKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+	key, err := s.repo.FindByHash(utils.HashToken(plain))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !key.Active(now) {
+		return nil, ErrAPIKeyInvalid
+	}
+	u, err := s.users.FindByID(key.UserID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.auth.checkSuspension(u); err != nil {
+		return nil, err
+	}
+	granted, err := s.rbac.Permissions(u.Role)
+	if err != nil {
+		return nil, err
+	}
+	perms := []string{}
+	for _, scope := range key.ScopeList() {
+		if contains(granted, scope) {
+			perms = append(perms, scope)
+		}
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.Touch(key.ID, now); err != nil {
+			return nil, err
+		}
+		key.LastUsedAt = &now
+	}
+	return &APIKeyAccess{User: u, Key: key, Permissions: perms}, nil
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueSorted(list []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, item := range list {
+		if item = strings.TrimSpace(item); item != "" && !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
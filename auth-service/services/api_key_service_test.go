@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/config"
+	"auth-service/repositories"
+)
+
+func TestAPIKeyService_Lifecycle(t *testing.T) {
+	setupTestDB()
+	config.APIKeyDefaultTTLDays = 90
+	config.APIKeyMaxTTLDays = 365
+	config.APIKeyMaxPerUser = 2
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewAPIKeyService()
+	s.now = func() time.Time { return now }
+	users := repositories.NewUserRepo()
+
+	u, _ := NewAuthService().Register("support@example.com", "password123", "Support")
+	u.Role = "support"
+	_ = users.Update(u)
+
+	if _, _, err := s.Create(u, "ci", []string{"products.manage"}, 0); !errors.Is(err, ErrScopeNotAllowed) {
+		t.Errorf("Ключ не может получить право, которого нет у роли, получено: %v", err)
+	}
+	if _, _, err := s.Create(u, "ci", nil, 400); !errors.Is(err, ErrAPIKeyTTL) {
+		t.Errorf("Срок больше максимального должен отклоняться, получено: %v", err)
+	}
+
+	key, plain, err := s.Create(u, "ci", []string{"users.read", "users.read"}, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(plain, "ak_") || !strings.HasPrefix(plain, key.Prefix) || key.KeyHash == plain {
+		t.Errorf("Неверный формат ключа: %q, prefix %q", plain, key.Prefix)
+	}
+	if !key.ExpiresAt.Equal(now.AddDate(0, 0, 90)) || key.Scopes != "users.read" {
+		t.Errorf("Ожидался срок по умолчанию и один scope: %+v", key)
+	}
+
+	access, err := s.Authenticate(plain)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if access.User.ID != u.ID || len(access.Permissions) != 1 || access.Permissions[0] != "users.read" {
+		t.Errorf("Права ключа должны быть ограничены scopes: %v", access.Permissions)
+	}
+	if access.Key.LastUsedAt == nil || !access.Key.LastUsedAt.Equal(now) {
+		t.Errorf("last_used_at должен обновиться")
+	}
+	if _, err := s.Authenticate(plain + "x"); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("Неизвестный ключ должен отклоняться, получено: %v", err)
+	}
+
+	// понижение роли сразу сужает права выпущенного ключа
+	u.Role = "user"
+	_ = users.Update(u)
+	if access, _ := s.Authenticate(plain); len(access.Permissions) != 0 {
+		t.Errorf("После смены роли права должны пропасть, получено %v", access.Permissions)
+	}
+
+	_, second, _ := s.Create(u, "backup", nil, 1)
+	if _, _, err := s.Create(u, "third", nil, 1); !errors.Is(err, ErrAPIKeyLimit) {
+		t.Errorf("Ожидалось ограничение числа ключей, получено: %v", err)
+	}
+	now = now.Add(25 * time.Hour)
+	if _, err := s.Authenticate(second); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("Истёкший ключ должен отклоняться, получено: %v", err)
+	}
+
+	u.Suspend("abuse", nil)
+	_ = users.Update(u)
+	var suspended *SuspendedError
+	if _, err := s.Authenticate(plain); !errors.As(err, &suspended) {
+		t.Errorf("Ключ заблокированного пользователя должен отклоняться, получено: %v", err)
+	}
+	u.Unsuspend()
+	_ = users.Update(u)
+
+	if err := s.Revoke(u.ID+1, key.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Нельзя отозвать чужой ключ, получено: %v", err)
+	}
+	if err := s.Revoke(u.ID, key.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := s.Authenticate(plain); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("Отозванный ключ должен отклоняться, получено: %v", err)
+	}
+	if list, _ := s.List(u.ID); len(list) != 1 {
+		t.Errorf("В списке должен остаться один ключ, получено %d", len(list))
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"log"
 	"time"
 
 	"auth-service/config"
@@ -13,28 +14,43 @@ import (
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrEmailNotVerified   = errors.New("email not verified")
 )
 
+// SuspendedError возвращается при входе заблокированного пользователя
+type SuspendedError struct {
+	Reason string
+	Until  *time.Time
+}
+
+func (e *SuspendedError) Error() string {
+	return "account suspended"
+}
+
 type AuthService struct {
-	repo *repositories.UserRepo
+	repo      *repositories.UserRepo
+	rbac      *RBACService
+	passwords *PasswordService
 }
 
 func NewAuthService() *AuthService {
 	return &AuthService{
-		repo: repositories.NewUserRepo(),
+		repo:      repositories.NewUserRepo(),
+		rbac:      NewRBACService(),
+		passwords: NewPasswordService(),
 	}
 }
 
 func (s *AuthService) Register(email, password, fullName string) (*models.User, error) {
 	if _, err := s.repo.FindByEmail(email); err == nil {
-		return nil, errors.New("email already used")
+		return nil, ErrEmailTaken
 	}
 	user := &models.User{
 		Email:    email,
 		FullName: fullName,
-		Role:     "user",
+		Role:     models.RoleUser,
 	}
-	if err := user.SetPassword(password); err != nil {
+	if err := s.passwords.Set(user, password); err != nil {
 		return nil, err
 	}
 	if err := s.repo.Create(user); err != nil {
@@ -48,27 +64,78 @@ func (s *AuthService) Authenticate(email, password string) (*models.User, error)
 	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
-	if !u.CheckPassword(password) {
+	ok, rehashed := u.CheckPassword(password)
+	if !ok {
 		return nil, ErrInvalidCredentials
 	}
+	if rehashed {
+		// не критично: хеш обновится при следующем входе
+		if err := s.repo.Update(u); err != nil {
+			log.Printf("failed to store upgraded password hash for user %d: %v", u.ID, err)
+		}
+	}
+	if err := s.checkSuspension(u); err != nil {
+		return nil, err
+	}
+	if config.RequireEmailVerification && !u.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
 	return u, nil
 }
 
+// checkSuspension возвращает SuspendedError для действующей блокировки
+// и снимает блокировку, срок которой истёк
+func (s *AuthService) checkSuspension(u *models.User) error {
+	if u.IsSuspended(time.Now()) {
+		return &SuspendedError{Reason: u.SuspendedReason, Until: u.SuspendedUntil}
+	}
+	if u.Status == models.StatusSuspended {
+		u.Unsuspend()
+		if err := s.repo.Update(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type TokenResponse struct {
 	Token string `json:"token"`
 }
 
-func (s *AuthService) GenerateJWT(u *models.User) (string, error) {
-	ttl := time.Duration(config.JWTTTLMin) * time.Minute
-	claims := jwt.MapClaims{
-		"sub":      u.ID,
-		"user_id":  u.ID, // для совместимости с другими сервисами
-		"role":     u.Role,
-		"is_admin": u.Role == "admin", // для совместимости с другими сервисами
-		"exp":      time.Now().Add(ttl).Unix(),
-		"iat":      time.Now().Unix(),
-		"email":    u.Email,
+// GenerateJWT подписывает токен сессии sid (см. SessionService.Start)
+func (s *AuthService) GenerateJWT(u *models.User, sid string) (string, error) {
+	claims, err := s.userClaims(u, sid, time.Now().Add(time.Duration(config.JWTTTLMin)*time.Minute))
+	if err != nil {
+		return "", err
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(config.JWTSecret)
+}
+
+// GenerateImpersonationJWT - токен пользователя u для администратора actor.
+// Claim "act" (RFC 8693) говорит сервисам, кто на самом деле выполняет запрос.
+func (s *AuthService) GenerateImpersonationJWT(u, actor *models.User, sid string, expiresAt time.Time) (string, error) {
+	claims, err := s.userClaims(u, sid, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	claims["act"] = map[string]interface{}{"sub": actor.ID, "email": actor.Email}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(config.JWTSecret)
+}
+
+func (s *AuthService) userClaims(u *models.User, sid string, expiresAt time.Time) (jwt.MapClaims, error) {
+	perms, err := s.rbac.Permissions(u.Role)
+	if err != nil {
+		return nil, err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(config.JWTSecret)
+	return jwt.MapClaims{
+		"sub":         u.ID,
+		"sid":         sid,
+		"user_id":     u.ID, // для совместимости с другими сервисами
+		"role":        u.Role,
+		"permissions": perms,
+		"is_admin":    u.Role == models.RoleAdmin, // для совместимости с другими сервисами
+		"exp":         expiresAt.Unix(),
+		"iat":         time.Now().Unix(),
+		"email":       u.Email,
+	}, nil
 }
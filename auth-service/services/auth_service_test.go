@@ -1,12 +1,15 @@
 package services
 
 import (
+	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"auth-service/config"
 	"auth-service/db"
 	"auth-service/models"
+	"auth-service/password"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -22,6 +25,8 @@ func setupTestDB() {
 	config.SQLitePath = ":memory:"
 	config.JWTSecret = []byte("test-secret-key-for-testing-only")
 	config.JWTTTLMin = 60
+	// подтверждение email проверяется отдельно в TestAccountService_*
+	config.RequireEmailVerification = false
 
 	// Инициализируем тестовую БД напрямую
 	var err error
@@ -31,9 +36,18 @@ func setupTestDB() {
 	}
 
 	// Миграция схемы
-	if err := db.DB.AutoMigrate(&models.User{}); err != nil {
+	if err := db.DB.AutoMigrate(models.All()...); err != nil {
 		panic("failed to migrate test database")
 	}
+	if err := db.SeedRBAC(db.DB); err != nil {
+		panic("failed to seed roles")
+	}
+	// дешёвые параметры argon2id, чтобы тесты не тратили 64 МБ на каждый хеш
+	cfg := password.DefaultConfig()
+	cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads = 64, 1, 1
+	if err := password.Init(cfg); err != nil {
+		panic("failed to init password hashing")
+	}
 }
 
 func TestAuthService_Register(t *testing.T) {
@@ -176,3 +190,49 @@ func TestAuthService_Authenticate(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_AuthenticateSuspended(t *testing.T) {
+	setupTestDB()
+
+	svc := NewAuthService()
+
+	email := "suspended@example.com"
+	password := "testpass123"
+	user, err := svc.Register(email, password, "Suspended User")
+	if err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя для теста: %v", err)
+	}
+
+	// Бессрочная блокировка
+	user.Suspend("spam", nil)
+	if err := db.DB.Save(user).Error; err != nil {
+		t.Fatalf("Не удалось заблокировать пользователя: %v", err)
+	}
+	_, err = svc.Authenticate(email, password)
+	var suspended *SuspendedError
+	if !errors.As(err, &suspended) {
+		t.Fatalf("Ожидалась ошибка блокировки, получено: %v", err)
+	}
+	if suspended.Reason != "spam" {
+		t.Errorf("Причина не совпадает. Ожидалось: 'spam', получено: '%s'", suspended.Reason)
+	}
+
+	// Неверный пароль не раскрывает факт блокировки
+	if _, err := svc.Authenticate(email, "wrongpassword"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Ожидалась ошибка неверных данных, получено: %v", err)
+	}
+
+	// Истёкшая блокировка снимается при входе
+	past := time.Now().Add(-time.Hour)
+	user.Suspend("spam", &past)
+	if err := db.DB.Save(user).Error; err != nil {
+		t.Fatalf("Не удалось обновить пользователя: %v", err)
+	}
+	u, err := svc.Authenticate(email, password)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if u.Status != models.StatusActive {
+		t.Errorf("Статус должен быть 'active', получено: %s", u.Status)
+	}
+}
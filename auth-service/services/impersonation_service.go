@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"auth-service/models"
+	"auth-service/repositories"
+)
+
+var (
+	ErrImpersonateSelf = errors.New("cannot impersonate yourself")
+	// ErrImpersonatePrivileged - под администратором войти нельзя: имперсонация не должна
+	// давать прав больше, чем есть у самого сотрудника поддержки
+	ErrImpersonatePrivileged  = errors.New("cannot impersonate a privileged user")
+	ErrImpersonationReason    = errors.New("reason is required")
+	ErrImpersonationNotFound  = errors.New("impersonation not found")
+	ErrImpersonationFinished  = errors.New("impersonation already ended")
+	ErrImpersonationForbidden = errors.New("impersonation belongs to another actor")
+)
+
+// privilegedPermissions - права, владельцев которых нельзя имперсонировать
+var privilegedPermissions = []string{models.PermUsersImpersonate, models.PermUsersManage, models.PermRolesManage}
+
+// ImpersonationResult - выданный токен и запись журнала
+type ImpersonationResult struct {
+	Impersonation *models.Impersonation
+	Token         string
+}
+
+type ImpersonationService struct {
+	repo     *repositories.ImpersonationRepo
+	users    *repositories.UserRepo
+	auth     *AuthService
+	rbac     *RBACService
+	sessions *SessionService
+	now      func() time.Time
+}
+
+func NewImpersonationService() *ImpersonationService {
+	return &ImpersonationService{
+		repo:     repositories.NewImpersonationRepo(),
+		users:    repositories.NewUserRepo(),
+		auth:     NewAuthService(),
+		rbac:     NewRBACService(),
+		sessions: NewSessionService(),
+		now:      time.Now,
+	}
+}
+
+// Start выдаёт администратору actorID короткий токен пользователя targetID.
+// Причина обязательна и попадает в журнал вместе с обеими личностями.
+func (s *ImpersonationService) Start(actorID, targetID uint, reason, ip, userAgent string) (*ImpersonationResult, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrImpersonationReason
+	}
+	if actorID == targetID {
+		return nil, ErrImpersonateSelf
+	}
+	actor, err := s.users.FindByID(actorID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.users.FindByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.auth.checkSuspension(target); err != nil {
+		return nil, err
+	}
+	actorPerms, err := s.rbac.Permissions(actor.Role)
+	if err != nil {
+		return nil, err
+	}
+	targetPerms, err := s.rbac.Permissions(target.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range targetPerms {
+		if contains(privilegedPermissions, p) || !contains(actorPerms, p) {
+			return nil, ErrImpersonatePrivileged
+		}
+	}
+
+	sess, err := s.sessions.StartImpersonation(target.ID, actor.ID, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	imp := &models.Impersonation{
+		ActorID:   actor.ID,
+		UserID:    target.ID,
+		Reason:    reason,
+		SID:       sess.SID,
+		IP:        ip,
+		ExpiresAt: sess.ExpiresAt,
+	}
+	if err := s.repo.Create(imp); err != nil {
+		return nil, err
+	}
+	token, err := s.auth.GenerateImpersonationJWT(target, actor, sess.SID, sess.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ImpersonationResult{Impersonation: imp, Token: token}, nil
+}
+
+// End завершает имперсонацию по sid её токена; токен сразу перестаёт приниматься
+func (s *ImpersonationService) End(sid string, actorID uint) (*models.Impersonation, error) {
+	imp, err := s.bySID(sid)
+	if err != nil {
+		return nil, err
+	}
+	if imp.ActorID != actorID {
+		return nil, ErrImpersonationForbidden
+	}
+	if imp.EndedAt != nil {
+		return nil, ErrImpersonationFinished
+	}
+	now := s.now()
+	if err := s.repo.End(imp, now); err != nil {
+		return nil, err
+	}
+	imp.EndedAt = &now
+	return imp, nil
+}
+
+// RecordAction добавляет запрос в журнал имперсонации с этим sid.
+// Действие без совпадающих actor и user не записывается: иначе журнал можно засорить.
+func (s *ImpersonationService) RecordAction(sid string, a *models.ImpersonationAction) error {
+	imp, err := s.bySID(sid)
+	if err != nil {
+		return err
+	}
+	if imp.ActorID != a.ActorID || imp.UserID != a.UserID {
+		return ErrImpersonationNotFound
+	}
+	a.ImpersonationID = imp.ID
+	a.CreatedAt = s.now()
+	return s.repo.RecordAction(a)
+}
+
+func (s *ImpersonationService) List(f repositories.ImpersonationFilter, offset, limit int) ([]models.Impersonation, int64, error) {
+	return s.repo.List(f, offset, limit)
+}
+
+// Get возвращает запись журнала и все действия, выполненные в её рамках
+func (s *ImpersonationService) Get(id uint) (*models.Impersonation, []models.ImpersonationAction, error) {
+	imp, err := s.repo.FindByID(id)
+	if errors.Is(err, repositories.ErrImpersonationNotFound) {
+		return nil, nil, ErrImpersonationNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	actions, err := s.repo.Actions(imp.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return imp, actions, nil
+}
+
+func (s *ImpersonationService) bySID(sid string) (*models.Impersonation, error) {
+	imp, err := s.repo.FindBySID(sid)
+	if errors.Is(err, repositories.ErrImpersonationNotFound) {
+		return nil, ErrImpersonationNotFound
+	}
+	return imp, err
+}
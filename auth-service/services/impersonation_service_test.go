@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/repositories"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestImpersonationService_StartAndEnd(t *testing.T) {
+	setupTestDB()
+	config.ImpersonationTTLMin = 15
+	auth := NewAuthService()
+	svc := NewImpersonationService()
+
+	admin, _ := auth.Register("admin@example.com", "password123", "Admin")
+	admin.Role = models.RoleAdmin
+	_ = auth.repo.Update(admin)
+	other, _ := auth.Register("admin2@example.com", "password123", "Other admin")
+	other.Role = models.RoleAdmin
+	_ = auth.repo.Update(other)
+	customer, _ := auth.Register("customer@example.com", "password123", "Customer")
+
+	if _, err := svc.Start(admin.ID, customer.ID, " ", "", ""); !errors.Is(err, ErrImpersonationReason) {
+		t.Errorf("Без причины имперсонация запрещена, получено: %v", err)
+	}
+	if _, err := svc.Start(admin.ID, admin.ID, "debug", "", ""); !errors.Is(err, ErrImpersonateSelf) {
+		t.Errorf("Ожидалась ErrImpersonateSelf, получено: %v", err)
+	}
+	if _, err := svc.Start(admin.ID, other.ID, "debug", "", ""); !errors.Is(err, ErrImpersonatePrivileged) {
+		t.Errorf("Под администратором входить нельзя, получено: %v", err)
+	}
+
+	res, err := svc.Start(admin.ID, customer.ID, "order #42 is not shown", "10.0.0.1", "curl/8.0")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(res.Token, claims, func(*jwt.Token) (interface{}, error) { return config.JWTSecret, nil }); err != nil {
+		t.Fatalf("Не удалось разобрать токен: %v", err)
+	}
+	act, _ := claims["act"].(map[string]interface{})
+	if sub, _ := claims["sub"].(float64); uint(sub) != customer.ID {
+		t.Errorf("sub должен быть пользователем, получено %v", claims["sub"])
+	}
+	if sub, _ := act["sub"].(float64); uint(sub) != admin.ID {
+		t.Errorf("act.sub должен быть администратором, получено %v", claims["act"])
+	}
+	sid, _ := claims["sid"].(string)
+	if _, err := svc.sessions.Validate(sid, customer.ID); err != nil {
+		t.Errorf("Сессия имперсонации должна быть активна: %v", err)
+	}
+
+	// действия записываются только для совпадающей пары actor/user
+	if err := svc.RecordAction(sid, &models.ImpersonationAction{ActorID: other.ID, UserID: customer.ID, Service: "project-service"}); !errors.Is(err, ErrImpersonationNotFound) {
+		t.Errorf("Чужой actor не должен попадать в журнал, получено: %v", err)
+	}
+	if err := svc.RecordAction(sid, &models.ImpersonationAction{ActorID: admin.ID, UserID: customer.ID, Service: "project-service", Method: "GET", Path: "/api/me/projects", Status: 200}); err != nil {
+		t.Fatalf("RecordAction: %v", err)
+	}
+
+	if _, err := svc.End(sid, other.ID); !errors.Is(err, ErrImpersonationForbidden) {
+		t.Errorf("Чужую имперсонацию завершать нельзя, получено: %v", err)
+	}
+	if _, err := svc.End(sid, admin.ID); err != nil {
+		t.Fatalf("End: %v", err)
+	}
+	if _, err := svc.sessions.Validate(sid, customer.ID); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("После завершения токен не должен приниматься, получено: %v", err)
+	}
+	if _, err := svc.End(sid, admin.ID); !errors.Is(err, ErrImpersonationFinished) {
+		t.Errorf("Ожидалась ErrImpersonationFinished, получено: %v", err)
+	}
+
+	imp, actions, err := svc.Get(res.Impersonation.ID)
+	if err != nil || imp.EndedAt == nil || len(actions) != 1 || actions[0].Service != "project-service" {
+		t.Errorf("Журнал: %+v, %+v, %v", imp, actions, err)
+	}
+	actorID := admin.ID
+	if items, total, _ := svc.List(repositories.ImpersonationFilter{ActorID: &actorID}, 0, 10); total != 1 || items[0].Reason != "order #42 is not shown" {
+		t.Errorf("Ожидалась 1 запись в журнале, получено %d", total)
+	}
+}
+
+func TestImpersonationService_RejectsSuspendedUser(t *testing.T) {
+	setupTestDB()
+	auth := NewAuthService()
+	svc := NewImpersonationService()
+
+	admin, _ := auth.Register("admin@example.com", "password123", "Admin")
+	admin.Role = models.RoleAdmin
+	_ = auth.repo.Update(admin)
+	customer, _ := auth.Register("customer@example.com", "password123", "Customer")
+	customer.Suspend("fraud", nil)
+	_ = auth.repo.Update(customer)
+
+	var suspended *SuspendedError
+	if _, err := svc.Start(admin.ID, customer.ID, "debug", "", ""); !errors.As(err, &suspended) {
+		t.Errorf("Ожидалась SuspendedError, получено: %v", err)
+	}
+}
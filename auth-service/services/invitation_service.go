@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/config"
+	"auth-service/mailer"
+	"auth-service/models"
+	"auth-service/password"
+	"auth-service/repositories"
+	"auth-service/utils"
+)
+
+var (
+	ErrEmailTaken = errors.New("email already used")
+	// ErrInvitationPending - на этот email уже есть действующее приглашение, его можно отправить повторно
+	ErrInvitationPending  = errors.New("invitation for this email is already pending")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrInvitationInvalid - ссылка неизвестна, истекла, отозвана или уже использована
+	ErrInvitationInvalid = errors.New("invalid or expired invitation")
+	ErrInvitationClosed  = errors.New("invitation is already accepted or revoked")
+	// ErrInvitationRole - роль из приглашения удалили до его принятия
+	ErrInvitationRole = errors.New("invited role no longer exists, ask for a new invitation")
+	// ErrInvitationNotSent - приглашение сохранено, но письмо не ушло; его можно отправить повторно
+	ErrInvitationNotSent = errors.New("invitation saved but email was not sent")
+)
+
+// InvitationService - создание учётных записей по приглашению: администратор указывает
+// email и роль, пароль задаёт сам приглашённый по одноразовой ссылке
+type InvitationService struct {
+	repo      *repositories.InvitationRepo
+	users     *repositories.UserRepo
+	rbac      *RBACService
+	passwords *PasswordService
+	mail      mailer.Mailer
+	now       func() time.Time
+}
+
+func NewInvitationService() *InvitationService {
+	return &InvitationService{
+		repo:      repositories.NewInvitationRepo(),
+		users:     repositories.NewUserRepo(),
+		rbac:      NewRBACService(),
+		passwords: NewPasswordService(),
+		mail:      mailer.Default,
+		now:       time.Now,
+	}
+}
+
+// Invite создаёт приглашение и отправляет ссылку. Право назначать роль проверяет вызывающий.
+func (s *InvitationService) Invite(inviterID uint, email, fullName, role string) (*models.Invitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := s.rbac.ValidateRole(role); err != nil {
+		return nil, err
+	}
+	if _, err := s.users.FindByEmail(email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	pending, err := s.repo.HasPending(email, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, ErrInvitationPending
+	}
+	inv := &models.Invitation{Email: email, FullName: fullName, Role: role, InvitedBy: inviterID}
+	token, err := s.renew(inv)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(inv); err != nil {
+		return nil, err
+	}
+	return inv, s.send(inv, token)
+}
+
+// Resend выпускает новую ссылку и продлевает срок; прежняя ссылка перестаёт работать.
+// Истёкшее приглашение тоже можно отправить повторно.
+func (s *InvitationService) Resend(id uint) (*models.Invitation, error) {
+	inv, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.AcceptedAt != nil || inv.RevokedAt != nil {
+		return nil, ErrInvitationClosed
+	}
+	token, err := s.renew(inv)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(inv); err != nil {
+		return nil, err
+	}
+	return inv, s.send(inv, token)
+}
+
+func (s *InvitationService) Revoke(id uint) (*models.Invitation, error) {
+	inv, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.AcceptedAt != nil {
+		return nil, ErrInvitationClosed
+	}
+	if inv.RevokedAt == nil {
+		now := s.now()
+		inv.RevokedAt = &now
+		if err := s.repo.Update(inv); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+func (s *InvitationService) List(status string, offset, limit int) ([]models.Invitation, int64, error) {
+	return s.repo.List(status, s.now(), offset, limit)
+}
+
+// Lookup - приглашение по ссылке, чтобы показать email и роль на странице принятия
+func (s *InvitationService) Lookup(token string) (*models.Invitation, error) {
+	inv, err := s.repo.FindPending(utils.HashToken(token), s.now())
+	if errors.Is(err, repositories.ErrInvitationNotFound) {
+		return nil, ErrInvitationInvalid
+	}
+	return inv, err
+}
+
+// Accept создаёт пользователя с паролем приглашённого. Переход по ссылке из письма
+// доказывает владение адресом, поэтому email сразу подтверждён.
+func (s *InvitationService) Accept(token, plainPassword, fullName string) (*models.User, error) {
+	// политику проверяем до использования ссылки, чтобы слабый пароль её не сжигал
+	if err := password.Validate(plainPassword); err != nil {
+		return nil, err
+	}
+	inv, err := s.Lookup(token)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.users.FindByEmail(inv.Email); err == nil {
+		return nil, ErrEmailTaken
+	}
+	if err := s.rbac.ValidateRole(inv.Role); err != nil {
+		return nil, ErrInvitationRole
+	}
+
+	now := s.now()
+	if fullName = strings.TrimSpace(fullName); fullName == "" {
+		fullName = inv.FullName
+	}
+	u := &models.User{Email: inv.Email, FullName: fullName, Role: inv.Role}
+	u.MarkEmailVerified(now)
+	if err := s.passwords.Set(u, plainPassword); err != nil {
+		return nil, err
+	}
+	// приглашение принимается только вместе с созданием пользователя: если пользователь
+	// не создан, ссылка остаётся действующей
+	err = s.repo.Transaction(func(invitations *repositories.InvitationRepo, users *repositories.UserRepo) error {
+		accepted, err := invitations.Accept(inv.TokenHash, now)
+		if err != nil {
+			return err
+		}
+		if err := users.Create(u); err != nil {
+			return err
+		}
+		accepted.UserID = &u.ID
+		return invitations.Update(accepted)
+	})
+	if errors.Is(err, repositories.ErrInvitationNotFound) {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *InvitationService) find(id uint) (*models.Invitation, error) {
+	inv, err := s.repo.FindByID(id)
+	if errors.Is(err, repositories.ErrInvitationNotFound) {
+		return nil, ErrInvitationNotFound
+	}
+	return inv, err
+}
+
+// renew выдаёт приглашению новый токен и срок; возвращает токен для ссылки
+func (s *InvitationService) renew(inv *models.Invitation) (string, error) {
+	plain, hash, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+	now := s.now()
+	inv.TokenHash = hash
+	inv.SentAt = now
+	inv.ExpiresAt = now.Add(time.Duration(config.InvitationTTLHours) * time.Hour)
+	return plain, nil
+}
+
+func (s *InvitationService) send(inv *models.Invitation, token string) error {
+	err := s.mail.Send(mailer.Message{
+		To:      inv.Email,
+		Subject: "Приглашение",
+		Body: fmt.Sprintf("Здравствуйте!\n\nВас пригласили создать учётную запись. Чтобы задать пароль, перейдите по ссылке:\n%s\n\nСсылка действует до %s.",
+			link("/accept-invite", token), inv.ExpiresAt.Format(time.RFC1123)),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvitationNotSent, err)
+	}
+	return nil
+}
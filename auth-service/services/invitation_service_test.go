@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/password"
+)
+
+func setupInvitationService(t *testing.T) (*InvitationService, *captureMailer) {
+	_, m := setupAccountService(t)
+	config.InvitationTTLHours = 72
+	return NewInvitationService(), m
+}
+
+func TestInvitationService_InviteAndAccept(t *testing.T) {
+	svc, m := setupInvitationService(t)
+
+	inv, err := svc.Invite(1, "new@example.com", "New Manager", "order-manager")
+	if err != nil {
+		t.Fatalf("Invite: %v", err)
+	}
+	if m.sent[0].To != "new@example.com" {
+		t.Errorf("Письмо ушло не тому адресату: %s", m.sent[0].To)
+	}
+	token := m.tokenFrom(t)
+	if _, err := svc.Invite(1, "new@example.com", "", models.RoleUser); !errors.Is(err, ErrInvitationPending) {
+		t.Errorf("Повторное приглашение на тот же email, получено: %v", err)
+	}
+	if _, err := svc.Invite(1, "x@example.com", "", "no-such-role"); err == nil {
+		t.Errorf("Несуществующая роль должна отклоняться")
+	}
+
+	if got, err := svc.Lookup(token); err != nil || got.ID != inv.ID {
+		t.Errorf("Lookup: %+v, %v", got, err)
+	}
+	var policy *password.PolicyError
+	if _, err := svc.Accept(token, "weak", ""); !errors.As(err, &policy) {
+		t.Errorf("Слабый пароль должен отклоняться, получено: %v", err)
+	}
+	u, err := svc.Accept(token, "password123", "")
+	if err != nil {
+		t.Fatalf("Слабый пароль не должен сжигать ссылку: %v", err)
+	}
+	if u.Role != "order-manager" || u.FullName != "New Manager" || !u.EmailVerified {
+		t.Errorf("Неожиданный пользователь: %+v", u)
+	}
+	if _, err := svc.Accept(token, "password123", ""); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Ссылка одноразовая, получено: %v", err)
+	}
+	if _, err := NewAuthService().Authenticate("new@example.com", "password123"); err != nil {
+		t.Errorf("Вход с выбранным паролем: %v", err)
+	}
+	if _, err := svc.Invite(1, "new@example.com", "", models.RoleUser); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Существующего пользователя приглашать нельзя, получено: %v", err)
+	}
+	if items, total, _ := svc.List(models.InvitationAccepted, 0, 10); total != 1 || *items[0].UserID != u.ID {
+		t.Errorf("Ожидалось 1 принятое приглашение, получено %d", total)
+	}
+}
+
+func TestInvitationService_NormalizesEmail(t *testing.T) {
+	svc, _ := setupInvitationService(t)
+
+	inv, err := svc.Invite(1, "  New.Manager@Example.COM ", "", models.RoleUser)
+	if err != nil {
+		t.Fatalf("Invite: %v", err)
+	}
+	if inv.Email != "new.manager@example.com" {
+		t.Errorf("Email приглашения не нормализован: %q", inv.Email)
+	}
+	if _, err := svc.Invite(1, "new.manager@example.com", "", models.RoleUser); !errors.Is(err, ErrInvitationPending) {
+		t.Errorf("Тот же email в другом регистре, получено: %v", err)
+	}
+}
+
+func TestInvitationService_AcceptRollsBack(t *testing.T) {
+	svc, m := setupInvitationService(t)
+
+	if _, err := svc.Invite(1, "taken@example.com", "", models.RoleUser); err != nil {
+		t.Fatalf("Invite: %v", err)
+	}
+	token := m.tokenFrom(t)
+
+	// удалённый пользователь не мешает приглашению, но держит email в уникальном индексе
+	deleted := &models.User{Email: "taken@example.com", Password: "x"}
+	if err := svc.users.Create(deleted); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := svc.users.Delete(deleted); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := svc.Accept(token, "password123", ""); err == nil {
+		t.Fatal("Ожидалась ошибка создания пользователя")
+	}
+	inv, err := svc.Lookup(token)
+	if err != nil {
+		t.Fatalf("Приглашение не должно считаться принятым, если пользователь не создан: %v", err)
+	}
+	if inv.AcceptedAt != nil || inv.UserID != nil {
+		t.Errorf("Приглашение изменено: %+v", inv)
+	}
+}
+
+func TestInvitationService_ResendAndRevoke(t *testing.T) {
+	svc, m := setupInvitationService(t)
+
+	inv, _ := svc.Invite(1, "late@example.com", "", models.RoleUser)
+	first := m.tokenFrom(t)
+
+	// истёкшее приглашение можно отправить заново, старая ссылка перестаёт работать
+	svc.now = func() time.Time { return time.Now().Add(73 * time.Hour) }
+	if items, _, _ := svc.List(models.InvitationExpired, 0, 10); len(items) != 1 {
+		t.Errorf("Ожидалось истёкшее приглашение, получено %d", len(items))
+	}
+	if _, err := svc.Lookup(first); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Истёкшая ссылка должна отклоняться, получено: %v", err)
+	}
+	if _, err := svc.Resend(inv.ID); err != nil {
+		t.Fatalf("Resend: %v", err)
+	}
+	second := m.tokenFrom(t)
+	if _, err := svc.Lookup(first); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Прежняя ссылка не должна работать после повторной отправки, получено: %v", err)
+	}
+	if _, err := svc.Lookup(second); err != nil {
+		t.Errorf("Новая ссылка: %v", err)
+	}
+
+	if _, err := svc.Revoke(inv.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := svc.Accept(second, "password123", ""); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Отозванное приглашение нельзя принять, получено: %v", err)
+	}
+	if _, err := svc.Resend(inv.ID); !errors.Is(err, ErrInvitationClosed) {
+		t.Errorf("Отозванное приглашение нельзя отправить, получено: %v", err)
+	}
+	if _, err := svc.Revoke(999); !errors.Is(err, ErrInvitationNotFound) {
+		t.Errorf("Ожидалась ErrInvitationNotFound, получено: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/repositories"
+)
+
+const (
+	backoffBaseDelay = time.Second
+	backoffMaxDelay  = 5 * time.Minute
+)
+
+// ThrottledError - попытка входа отклонена до проверки пароля
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed attempts, account temporarily locked"
+	}
+	return "too many failed attempts, try again later"
+}
+
+// limits - пороги для одного вида ключа
+type limits struct {
+	backoffAfter int
+	lockoutAfter int
+}
+
+// LoginGuard считает неудачные входы по учётной записи и по IP.
+// После backoffAfter ошибок каждая следующая попытка ждёт экспоненциально дольше,
+// после lockoutAfter ключ блокируется на LoginLockoutMin минут.
+type LoginGuard struct {
+	repo *repositories.LoginRepo
+	now  func() time.Time
+}
+
+func NewLoginGuard() *LoginGuard {
+	return &LoginGuard{repo: repositories.NewLoginRepo(), now: time.Now}
+}
+
+func accountKey(email string) string { return "account:" + strings.ToLower(strings.TrimSpace(email)) }
+func ipKey(ip string) string         { return "ip:" + ip }
+
+func accountLimits() limits {
+	return limits{backoffAfter: config.LoginBackoffAfter, lockoutAfter: config.LoginLockoutAfter}
+}
+
+func ipLimits() limits {
+	return limits{backoffAfter: config.LoginIPBackoffAfter, lockoutAfter: config.LoginIPLockoutAfter}
+}
+
+// Check возвращает *ThrottledError, если попытку нужно отклонить
+func (g *LoginGuard) Check(email, ip string) error {
+	now := g.now()
+	var worst *ThrottledError
+	for _, k := range []struct {
+		key string
+		lim limits
+	}{{accountKey(email), accountLimits()}, {ipKey(ip), ipLimits()}} {
+		t, err := g.repo.FindThrottle(k.key)
+		if err != nil {
+			return err
+		}
+		if e := g.blocked(t, k.lim, now); e != nil && (worst == nil || e.RetryAfter > worst.RetryAfter) {
+			worst = e
+		}
+	}
+	if worst != nil {
+		return worst
+	}
+	return nil
+}
+
+func (g *LoginGuard) blocked(t *models.LoginThrottle, lim limits, now time.Time) *ThrottledError {
+	if t.LockedUntil != nil {
+		if now.Before(*t.LockedUntil) {
+			return &ThrottledError{RetryAfter: t.LockedUntil.Sub(now), Locked: true}
+		}
+		// блокировка истекла, счётчик обнулится при следующей ошибке
+		return nil
+	}
+	if t.Failures < lim.backoffAfter || g.expired(t, now) {
+		return nil
+	}
+	next := t.LastFailureAt.Add(backoffDelay(t.Failures - lim.backoffAfter))
+	if now.Before(next) {
+		return &ThrottledError{RetryAfter: next.Sub(now)}
+	}
+	return nil
+}
+
+// RecordFailure увеличивает счётчики учётной записи и IP
+func (g *LoginGuard) RecordFailure(email, ip string) error {
+	if err := g.fail(accountKey(email), accountLimits()); err != nil {
+		return err
+	}
+	return g.fail(ipKey(ip), ipLimits())
+}
+
+// RecordSuccess сбрасывает счётчик учётной записи. Счётчик IP не сбрасывается,
+// иначе перебор можно было бы перемежать входами в собственный аккаунт.
+func (g *LoginGuard) RecordSuccess(email string) error {
+	return g.repo.DeleteThrottle(accountKey(email))
+}
+
+// Unlock снимает блокировку учётной записи (действие администратора)
+func (g *LoginGuard) Unlock(email string) error {
+	return g.repo.DeleteThrottle(accountKey(email))
+}
+
+// Status возвращает текущий счётчик учётной записи
+func (g *LoginGuard) Status(email string) (*models.LoginThrottle, error) {
+	return g.repo.FindThrottle(accountKey(email))
+}
+
+// fail увеличивает счётчик одним запросом к базе, чтобы одновременные неудачные входы
+// не затирали друг друга, и блокирует ключ по новому значению
+func (g *LoginGuard) fail(key string, lim limits) error {
+	now := g.now()
+	window := time.Duration(config.LoginFailureWindowMin) * time.Minute
+	t, err := g.repo.AddFailure(key, now, now.Add(-window))
+	if err != nil {
+		return err
+	}
+	if lim.lockoutAfter > 0 && t.Failures >= lim.lockoutAfter {
+		return g.repo.LockThrottle(key, now.Add(time.Duration(config.LoginLockoutMin)*time.Minute))
+	}
+	return nil
+}
+
+// expired - ошибки старше окна забываются
+func (g *LoginGuard) expired(t *models.LoginThrottle, now time.Time) bool {
+	window := time.Duration(config.LoginFailureWindowMin) * time.Minute
+	return t.Failures > 0 && now.Sub(t.LastFailureAt) > window
+}
+
+func backoffDelay(n int) time.Duration {
+	if n >= 20 {
+		return backoffMaxDelay
+	}
+	d := backoffBaseDelay << n
+	if d > backoffMaxDelay {
+		return backoffMaxDelay
+	}
+	return d
+}
+
+// Audit пишет попытку входа в журнал
+func (g *LoginGuard) Audit(userID *uint, email, ip, userAgent, reason string) error {
+	return g.repo.RecordAttempt(&models.LoginAttempt{
+		UserID:    userID,
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		IP:        ip,
+		UserAgent: userAgent,
+		Success:   reason == models.LoginOK,
+		Reason:    reason,
+	})
+}
+
+// IsThrottled - удобная проверка для обработчиков
+func IsThrottled(err error) (*ThrottledError, bool) {
+	var te *ThrottledError
+	ok := errors.As(err, &te)
+	return te, ok
+}
+
+// Attempts - выборка журнала входов для администратора
+func (g *LoginGuard) Attempts(f repositories.AttemptFilter, offset, limit int) ([]models.LoginAttempt, int64, error) {
+	return g.repo.ListAttempts(f, offset, limit)
+}
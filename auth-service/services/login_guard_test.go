@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"auth-service/config"
+	"auth-service/db"
+	"auth-service/repositories"
+)
+
+func setupLoginGuard(now *time.Time) *LoginGuard {
+	setupTestDB()
+	config.LoginBackoffAfter = 3
+	config.LoginLockoutAfter = 5
+	config.LoginIPBackoffAfter = 100
+	config.LoginIPLockoutAfter = 200
+	config.LoginLockoutMin = 15
+	config.LoginFailureWindowMin = 15
+	return &LoginGuard{repo: repositories.NewLoginRepo(), now: func() time.Time { return *now }}
+}
+
+func TestLoginGuard_BackoffAndLockout(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := setupLoginGuard(&now)
+	const email, ip = "victim@example.com", "10.0.0.1"
+
+	for i := 0; i < 3; i++ {
+		if err := g.Check(email, ip); err != nil {
+			t.Fatalf("Попытка %d не должна ограничиваться: %v", i+1, err)
+		}
+		if err := g.RecordFailure(email, ip); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+	}
+
+	// После порога включается задержка
+	te, ok := IsThrottled(g.Check(email, ip))
+	if !ok || te.Locked || te.RetryAfter != time.Second {
+		t.Fatalf("Ожидалась задержка 1s без блокировки, получено %+v", te)
+	}
+	now = now.Add(time.Second)
+	if err := g.Check(email, ip); err != nil {
+		t.Fatalf("После задержки попытка должна разрешаться: %v", err)
+	}
+	g.RecordFailure(email, ip)
+	te, _ = IsThrottled(g.Check(email, ip))
+	if te == nil || te.RetryAfter != 2*time.Second {
+		t.Fatalf("Задержка должна удваиваться, получено %+v", te)
+	}
+
+	now = now.Add(2 * time.Second)
+	g.RecordFailure(email, ip)
+	te, _ = IsThrottled(g.Check(email, ip))
+	if te == nil || !te.Locked || te.RetryAfter != 15*time.Minute {
+		t.Fatalf("Ожидалась блокировка на 15 минут, получено %+v", te)
+	}
+
+	// Другая учётная запись с другого IP не затронута
+	if err := g.Check("other@example.com", "10.0.0.2"); err != nil {
+		t.Errorf("Другая учётная запись не должна блокироваться: %v", err)
+	}
+
+	// Блокировка истекает сама
+	now = now.Add(15 * time.Minute)
+	if err := g.Check(email, ip); err != nil {
+		t.Errorf("Блокировка должна истечь: %v", err)
+	}
+	g.RecordFailure(email, ip)
+	st, _ := g.Status(email)
+	if st.Failures != 1 || st.LockedUntil != nil {
+		t.Errorf("После истечения блокировки счётчик должен начаться заново, получено %+v", st)
+	}
+}
+
+func TestLoginGuard_UnlockAndWindow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := setupLoginGuard(&now)
+	const email, ip = "Victim@Example.com", "10.0.0.1"
+
+	for i := 0; i < 5; i++ {
+		g.RecordFailure(email, ip)
+	}
+	if te, ok := IsThrottled(g.Check("victim@example.com", ip)); !ok || !te.Locked {
+		t.Fatalf("Учётная запись должна быть заблокирована независимо от регистра email")
+	}
+	if err := g.Unlock(email); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := g.Check(email, ip); err != nil {
+		t.Errorf("После разблокировки вход должен разрешаться: %v", err)
+	}
+
+	// Ошибки старше окна забываются
+	for i := 0; i < 3; i++ {
+		g.RecordFailure(email, ip)
+	}
+	now = now.Add(16 * time.Minute)
+	if err := g.Check(email, ip); err != nil {
+		t.Errorf("Старые ошибки не должны учитываться: %v", err)
+	}
+	g.RecordFailure(email, ip)
+	if st, _ := g.Status(email); st.Failures != 1 {
+		t.Errorf("После окна счётчик должен начаться заново, получено %d", st.Failures)
+	}
+}
+
+func TestLoginGuard_ConcurrentFailures(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := setupLoginGuard(&now)
+	// одно соединение: у каждого соединения с :memory: своя база
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	config.LoginLockoutAfter = 1000
+
+	const email, attempts = "victim@example.com", 200
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := g.RecordFailure(email, fmt.Sprintf("10.0.0.%d", i)); err != nil {
+				t.Errorf("RecordFailure: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	st, err := g.Status(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Failures != attempts {
+		t.Errorf("Одновременные ошибки не должны теряться: ожидалось %d, получено %d", attempts, st.Failures)
+	}
+}
+
+func TestLoginGuard_IPCounterSurvivesSuccess(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := setupLoginGuard(&now)
+	config.LoginIPBackoffAfter = 2
+	const ip = "10.0.0.9"
+
+	g.RecordFailure("a@example.com", ip)
+	g.RecordFailure("b@example.com", ip)
+	g.RecordSuccess("attacker@example.com")
+
+	if _, ok := IsThrottled(g.Check("c@example.com", ip)); !ok {
+		t.Errorf("Успешный вход не должен сбрасывать счётчик IP")
+	}
+}
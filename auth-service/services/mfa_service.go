@@ -0,0 +1,268 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/totp"
+	"auth-service/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Назначения challenge-токенов второго шага входа
+const (
+	ChallengeVerify = "mfa_verify" // у пользователя включена 2FA, нужен код
+	ChallengeEnroll = "mfa_enroll" // 2FA обязательна для роли, но ещё не настроена
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrInvalidMFACode     = errors.New("invalid two-factor code")
+	ErrInvalidChallenge   = errors.New("invalid or expired challenge token")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrMFASetupNotStarted = errors.New("two-factor setup not started")
+	ErrMFARequired        = errors.New("two-factor authentication is required for this role")
+)
+
+// mfaPermissions - права, с которыми 2FA обязательна для любой роли, а не только из MFA_REQUIRED_ROLES
+var mfaPermissions = []string{models.PermRolesManage, models.PermUsersManage}
+
+type MFAService struct {
+	users *repositories.UserRepo
+	codes *repositories.RecoveryCodeRepo
+	rbac  *RBACService
+}
+
+func NewMFAService() *MFAService {
+	return &MFAService{
+		users: repositories.NewUserRepo(),
+		codes: repositories.NewRecoveryCodeRepo(),
+		rbac:  NewRBACService(),
+	}
+}
+
+// Required сообщает, обязательна ли 2FA для роли пользователя: роль указана в MFA_REQUIRED_ROLES
+// или может управлять пользователями и ролями. Если права не удалось получить, 2FA обязательна.
+func (s *MFAService) Required(u *models.User) bool {
+	if slices.Contains(config.MFARequiredRoles, u.Role) {
+		return true
+	}
+	perms, err := s.rbac.Permissions(u.Role)
+	if err != nil {
+		return true
+	}
+	for _, p := range mfaPermissions {
+		if slices.Contains(perms, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// BeginSetup создаёт новый (пока не активный) секрет и ссылку для QR-кода
+func (s *MFAService) BeginSetup(u *models.User) (secret, uri string, err error) {
+	if u.TOTPEnabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	u.TOTPSecret = secret
+	u.TOTPLastStep = 0
+	if err := s.users.Update(u); err != nil {
+		return "", "", err
+	}
+	return secret, totp.ProvisioningURI(config.TOTPIssuer, u.Email, secret), nil
+}
+
+// Confirm включает 2FA после проверки первого кода и возвращает резервные коды
+func (s *MFAService) Confirm(u *models.User, code string) ([]string, error) {
+	if u.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if u.TOTPSecret == "" {
+		return nil, ErrMFASetupNotStarted
+	}
+	step, ok := totp.Validate(u.TOTPSecret, code, time.Now(), u.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	u.TOTPEnabled = true
+	u.TOTPLastStep = step
+	if err := s.users.Update(u); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(u.ID)
+}
+
+// Verify принимает TOTP-код или неиспользованный резервный код
+func (s *MFAService) Verify(u *models.User, code string) error {
+	if !u.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if step, ok := totp.Validate(u.TOTPSecret, code, time.Now(), u.TOTPLastStep); ok {
+		u.TOTPLastStep = step
+		return s.users.Update(u)
+	}
+	return s.useRecoveryCode(u.ID, code)
+}
+
+// Disable отключает 2FA; для ролей с обязательной 2FA это запрещено
+func (s *MFAService) Disable(u *models.User, code string) error {
+	if s.Required(u) {
+		return ErrMFARequired
+	}
+	if err := s.Verify(u, code); err != nil {
+		return err
+	}
+	u.TOTPEnabled = false
+	u.TOTPSecret = ""
+	u.TOTPLastStep = 0
+	if err := s.users.Update(u); err != nil {
+		return err
+	}
+	return s.codes.DeleteAll(u.ID)
+}
+
+// RegenerateRecoveryCodes выпускает новый набор резервных кодов, старые перестают работать
+func (s *MFAService) RegenerateRecoveryCodes(u *models.User, code string) ([]string, error) {
+	if err := s.Verify(u, code); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(u.ID)
+}
+
+func (s *MFAService) RecoveryCodesLeft(u *models.User) (int64, error) {
+	return s.codes.CountUnused(u.ID)
+}
+
+// IssueChallenge выпускает короткоживущий токен второго шага входа.
+// Он подписан отдельным ключом, поэтому middleware сервисов не примут его вместо JWT.
+func (s *MFAService) IssueChallenge(u *models.User, purpose string) (string, error) {
+	ttl := time.Duration(config.MFAChallengeTTLMin) * time.Minute
+	claims := jwt.MapClaims{
+		"sub":     u.ID,
+		"purpose": purpose,
+		"exp":     time.Now().Add(ttl).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(challengeKey())
+}
+
+// ParseChallenge проверяет challenge-токен и возвращает пользователя
+func (s *MFAService) ParseChallenge(token, purpose string) (*models.User, error) {
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrTokenInvalidClaims
+		}
+		return challengeKey(), nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidChallenge
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, ErrInvalidChallenge
+	}
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return nil, ErrInvalidChallenge
+	}
+	u, err := s.users.FindByID(uint(sub))
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	return u, nil
+}
+
+func (s *MFAService) newRecoveryCodes(userID uint) ([]string, error) {
+	plain := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range plain {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := utils.HashPassword(normalizeRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+		plain[i], hashes[i] = code, hash
+	}
+	if err := s.codes.Replace(userID, hashes); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+func (s *MFAService) useRecoveryCode(userID uint, code string) error {
+	code = normalizeRecoveryCode(code)
+	if len(code) != 10 {
+		return ErrInvalidMFACode
+	}
+	codes, err := s.codes.ListUnused(userID)
+	if err != nil {
+		return err
+	}
+	for _, rc := range codes {
+		if !utils.CheckPasswordHash(code, rc.CodeHash) {
+			continue
+		}
+		ok, err := s.codes.MarkUsed(rc.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+	return ErrInvalidMFACode
+}
+
+// recoveryAlphabet без похожих символов (0/O, 1/I/L)
+const recoveryAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// randomRecoveryCode - 10 символов в виде XXXXX-XXXXX
+func randomRecoveryCode() (string, error) {
+	// байты >= limit отбрасываются, чтобы символы алфавита были равновероятны
+	limit := byte(256 - 256%len(recoveryAlphabet))
+	out := make([]byte, 0, 11)
+	buf := make([]byte, 1)
+	for len(out) < 11 {
+		if len(out) == 5 {
+			out = append(out, '-')
+			continue
+		}
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		if buf[0] >= limit {
+			continue
+		}
+		out = append(out, recoveryAlphabet[int(buf[0])%len(recoveryAlphabet)])
+	}
+	return string(out), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func challengeKey() []byte {
+	mac := hmac.New(sha256.New, config.JWTSecret)
+	mac.Write([]byte("mfa-challenge"))
+	return mac.Sum(nil)
+}
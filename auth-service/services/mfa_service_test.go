@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/totp"
+)
+
+func TestMFAService_EnrollAndVerify(t *testing.T) {
+	setupTestDB()
+	config.MFARequiredRoles = []string{"admin"}
+
+	auth := NewAuthService()
+	mfa := NewMFAService()
+
+	user, err := auth.Register("mfa@example.com", "password123", "MFA User")
+	if err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя: %v", err)
+	}
+	if mfa.Required(user) {
+		t.Errorf("Для роли user 2FA не должна быть обязательной")
+	}
+
+	secret, uri, err := mfa.BeginSetup(user)
+	if err != nil {
+		t.Fatalf("BeginSetup: %v", err)
+	}
+	if uri == "" || secret == "" {
+		t.Fatalf("Ожидались секрет и otpauth-ссылка")
+	}
+
+	if _, err := mfa.Confirm(user, "000000"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("Неверный код должен отклоняться, получено: %v", err)
+	}
+
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+	recovery, err := mfa.Confirm(user, code)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("Ожидалось %d резервных кодов, получено %d", recoveryCodeCount, len(recovery))
+	}
+
+	// Тот же код повторно не принимается
+	if err := mfa.Verify(user, code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("Повторный TOTP-код должен отклоняться, получено: %v", err)
+	}
+
+	// Резервный код одноразовый и принимается в любом регистре
+	if err := mfa.Verify(user, recovery[0]); err != nil {
+		t.Errorf("Резервный код должен приниматься: %v", err)
+	}
+	if err := mfa.Verify(user, recovery[0]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("Резервный код должен быть одноразовым, получено: %v", err)
+	}
+	left, _ := mfa.RecoveryCodesLeft(user)
+	if left != recoveryCodeCount-1 {
+		t.Errorf("Ожидалось %d оставшихся кодов, получено %d", recoveryCodeCount-1, left)
+	}
+}
+
+func TestMFAService_AdminCannotDisable(t *testing.T) {
+	setupTestDB()
+	config.MFARequiredRoles = []string{"admin"}
+
+	auth := NewAuthService()
+	mfa := NewMFAService()
+
+	admin, err := auth.Register("admin2fa@example.com", "password123", "")
+	if err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя: %v", err)
+	}
+	admin.Role = "admin"
+	if !mfa.Required(admin) {
+		t.Fatalf("Для администратора 2FA должна быть обязательной")
+	}
+	secret, _, _ := mfa.BeginSetup(admin)
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+	recovery, err := mfa.Confirm(admin, code)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if err := mfa.Disable(admin, recovery[0]); !errors.Is(err, ErrMFARequired) {
+		t.Errorf("Администратор не должен отключать 2FA, получено: %v", err)
+	}
+}
+
+func TestMFAService_RequiredForManagingRoles(t *testing.T) {
+	setupTestDB()
+	config.MFARequiredRoles = []string{"admin"}
+	rbac := NewRBACService()
+	mfa := NewMFAService()
+
+	for _, r := range []struct{ name, perm string }{
+		{"moderator", "users.manage"},
+		{"security", "roles.manage"},
+		{"editor", "products.manage"},
+	} {
+		if _, err := rbac.CreateRole(r.name, "", []string{r.perm}); err != nil {
+			t.Fatalf("CreateRole %s: %v", r.name, err)
+		}
+	}
+
+	tests := []struct {
+		role string
+		want bool
+	}{
+		{"admin", true},
+		{"moderator", true},
+		{"security", true},
+		{"editor", false},
+		{"user", false},
+	}
+	for _, tt := range tests {
+		if got := mfa.Required(&models.User{Role: tt.role}); got != tt.want {
+			t.Errorf("Required(%s) = %v, ожидалось %v", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestMFAService_Challenge(t *testing.T) {
+	setupTestDB()
+
+	auth := NewAuthService()
+	mfa := NewMFAService()
+
+	user, err := auth.Register("challenge@example.com", "password123", "")
+	if err != nil {
+		t.Fatalf("Не удалось зарегистрировать пользователя: %v", err)
+	}
+	token, err := mfa.IssueChallenge(user, ChallengeVerify)
+	if err != nil {
+		t.Fatalf("IssueChallenge: %v", err)
+	}
+
+	got, err := mfa.ParseChallenge(token, ChallengeVerify)
+	if err != nil || got.ID != user.ID {
+		t.Fatalf("Challenge должен разбираться: %v", err)
+	}
+	if _, err := mfa.ParseChallenge(token, ChallengeEnroll); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("Challenge другого назначения должен отклоняться, получено: %v", err)
+	}
+
+	// Обычный JWT нельзя использовать как challenge
+	jwtToken, _ := auth.GenerateJWT(user, "test-session")
+	if _, err := mfa.ParseChallenge(jwtToken, ChallengeVerify); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("JWT доступа не должен приниматься как challenge, получено: %v", err)
+	}
+}
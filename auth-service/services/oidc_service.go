@@ -0,0 +1,401 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/oidc"
+	"auth-service/repositories"
+	"auth-service/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// OAuthError - ошибка в формате RFC 6749 (поля error и error_description)
+type OAuthError struct {
+	Code        string
+	Description string
+	Status      int
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthErr(status int, code, desc string) *OAuthError {
+	return &OAuthError{Code: code, Description: desc, Status: status}
+}
+
+var ErrOAuthClientNotFound = errors.New("oauth client not found")
+
+var supportedScopes = map[string]bool{"openid": true, "email": true, "profile": true}
+
+type OIDCService struct {
+	repo     *repositories.OAuthRepo
+	users    *repositories.UserRepo
+	auth     *AuthService
+	sessions *SessionService
+	signer   *oidc.Signer
+	now      func() time.Time
+}
+
+func NewOIDCService() *OIDCService {
+	return &OIDCService{
+		repo:     repositories.NewOAuthRepo(),
+		users:    repositories.NewUserRepo(),
+		auth:     NewAuthService(),
+		sessions: NewSessionService(),
+		signer:   oidc.Default,
+		now:      time.Now,
+	}
+}
+
+// Clients
+
+// RegisterClient создаёт first-party клиента. Для конфиденциального клиента
+// возвращается секрет - он показывается один раз и хранится только как хеш.
+func (s *OIDCService) RegisterClient(name string, redirectURIs []string, public bool) (*models.OAuthClient, string, error) {
+	if err := validateRedirectURIs(redirectURIs); err != nil {
+		return nil, "", err
+	}
+	clientID, _, err := utils.NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+	c := &models.OAuthClient{
+		ClientID:     clientID[:24],
+		Name:         name,
+		Public:       public,
+		RedirectURIs: strings.Join(redirectURIs, "\n"),
+	}
+	secret := ""
+	if !public {
+		var hash string
+		if secret, hash, err = utils.NewToken(); err != nil {
+			return nil, "", err
+		}
+		c.SecretHash = hash
+	}
+	if err := s.repo.CreateClient(c); err != nil {
+		return nil, "", err
+	}
+	return c, secret, nil
+}
+
+func (s *OIDCService) ListClients() ([]models.OAuthClient, error) {
+	return s.repo.ListClients()
+}
+
+func (s *OIDCService) UpdateRedirectURIs(clientID string, redirectURIs []string) (*models.OAuthClient, error) {
+	if err := validateRedirectURIs(redirectURIs); err != nil {
+		return nil, err
+	}
+	c, err := s.findClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	c.RedirectURIs = strings.Join(redirectURIs, "\n")
+	if err := s.repo.UpdateClient(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *OIDCService) DeleteClient(clientID string) error {
+	c, err := s.findClient(clientID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteClient(c)
+}
+
+func (s *OIDCService) findClient(clientID string) (*models.OAuthClient, error) {
+	c, err := s.repo.FindClient(clientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOAuthClientNotFound
+	}
+	return c, err
+}
+
+// validateRedirectURIs: абсолютные URI без фрагмента; http разрешён только для localhost,
+// собственные схемы (com.example.app:/callback) - для мобильных приложений
+func validateRedirectURIs(uris []string) error {
+	if len(uris) == 0 {
+		return errors.New("at least one redirect_uri is required")
+	}
+	for _, raw := range uris {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Fragment != "" || strings.ContainsAny(raw, "\n\r") {
+			return errors.New("invalid redirect_uri: " + raw)
+		}
+		if u.Scheme == "http" && u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1" {
+			return errors.New("redirect_uri must use https: " + raw)
+		}
+		if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+			return errors.New("invalid redirect_uri: " + raw)
+		}
+	}
+	return nil
+}
+
+// Authorization code flow
+
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// AuthorizeRedirectError - ошибка, о которой сообщаем клиенту через redirect_uri.
+// Если клиент или redirect_uri некорректны, возвращается обычный *OAuthError:
+// перенаправлять на непроверенный адрес нельзя.
+type AuthorizeRedirectError struct {
+	*OAuthError
+	RedirectURI string
+}
+
+// Authorize выдаёт код для уже вошедшего пользователя и возвращает адрес перенаправления
+func (s *OIDCService) Authorize(req AuthorizeRequest, userID uint) (string, error) {
+	client, err := s.findClient(req.ClientID)
+	if errors.Is(err, ErrOAuthClientNotFound) {
+		return "", oauthErr(http.StatusBadRequest, "invalid_client", "unknown client_id")
+	}
+	if err != nil {
+		return "", err
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		return "", oauthErr(http.StatusBadRequest, "invalid_request", "redirect_uri is not registered for this client")
+	}
+
+	fail := func(code, desc string) error {
+		return &AuthorizeRedirectError{
+			OAuthError:  oauthErr(http.StatusFound, code, desc),
+			RedirectURI: redirectWith(req.RedirectURI, url.Values{"error": {code}, "error_description": {desc}}, req.State),
+		}
+	}
+	if req.ResponseType != "code" {
+		return "", fail("unsupported_response_type", "only response_type=code is supported")
+	}
+	scopes := strings.Fields(req.Scope)
+	hasOpenID := false
+	for _, sc := range scopes {
+		if !supportedScopes[sc] {
+			return "", fail("invalid_scope", "unsupported scope "+sc)
+		}
+		hasOpenID = hasOpenID || sc == "openid"
+	}
+	if !hasOpenID {
+		return "", fail("invalid_scope", "scope must include openid")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != oidc.MethodS256 {
+		return "", fail("invalid_request", "PKCE with code_challenge_method=S256 is required")
+	}
+
+	u, err := s.users.FindByID(userID)
+	if err != nil || u.IsSuspended(s.now()) {
+		return "", fail("access_denied", "account is not active")
+	}
+
+	plain, hash, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+	now := s.now()
+	code := &models.AuthorizationCode{
+		CodeHash:            hash,
+		ClientID:            client.ClientID,
+		UserID:              u.ID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               strings.Join(scopes, " "),
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		ExpiresAt:           now.Add(time.Duration(config.OIDCCodeTTLSec) * time.Second),
+	}
+	if err := s.repo.CreateCode(code); err != nil {
+		return "", err
+	}
+	return redirectWith(req.RedirectURI, url.Values{"code": {plain}}, req.State), nil
+}
+
+func redirectWith(base string, params url.Values, state string) string {
+	if state != "" {
+		params.Set("state", state)
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + params.Encode()
+}
+
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+	IP           string
+	UserAgent    string
+}
+
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// Exchange обменивает код на токены. access_token - обычный JWT auth-service
+// (с role, permissions и sid), его принимают все сервисы; id_token подписан RS256.
+func (s *OIDCService) Exchange(req TokenRequest) (*OAuthTokenResponse, error) {
+	if req.GrantType != "authorization_code" {
+		return nil, oauthErr(http.StatusBadRequest, "unsupported_grant_type", "only authorization_code is supported")
+	}
+	client, err := s.findClient(req.ClientID)
+	if errors.Is(err, ErrOAuthClientNotFound) {
+		return nil, oauthErr(http.StatusUnauthorized, "invalid_client", "unknown client_id")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !client.Public {
+		got := utils.HashToken(req.ClientSecret)
+		if req.ClientSecret == "" || subtle.ConstantTimeCompare([]byte(got), []byte(client.SecretHash)) != 1 {
+			return nil, oauthErr(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		}
+	}
+
+	now := s.now()
+	code, err := s.repo.ConsumeCode(utils.HashToken(req.Code), now)
+	if errors.Is(err, repositories.ErrCodeInvalid) {
+		return nil, oauthErr(http.StatusBadRequest, "invalid_grant", err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, oauthErr(http.StatusBadRequest, "invalid_grant", "code was issued to another client or redirect_uri")
+	}
+	if !oidc.VerifyPKCE(req.CodeVerifier, code.CodeChallenge, code.CodeChallengeMethod) {
+		return nil, oauthErr(http.StatusBadRequest, "invalid_grant", "code_verifier does not match code_challenge")
+	}
+
+	u, err := s.users.FindByID(code.UserID)
+	if err != nil || u.IsSuspended(now) {
+		return nil, oauthErr(http.StatusBadRequest, "invalid_grant", "account is not active")
+	}
+
+	sess, err := s.sessions.Start(u.ID, req.IP, req.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.auth.GenerateJWT(u, sess.SID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(config.JWTTTLMin) * time.Minute
+	claims := jwt.MapClaims{
+		"iss":       config.OIDCIssuer,
+		"sub":       strconv.FormatUint(uint64(u.ID), 10),
+		"aud":       client.ClientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(ttl).Unix(),
+		"auth_time": code.CreatedAt.Unix(),
+		"sid":       sess.SID,
+		"role":      u.Role,
+	}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	for k, v := range s.scopedClaims(u, code.Scope) {
+		claims[k] = v
+	}
+	idToken, err := s.signer.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthTokenResponse{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl / time.Second),
+		IDToken:     idToken,
+		Scope:       code.Scope,
+	}, nil
+}
+
+// UserInfo - claims для /userinfo; access_token не хранит scope, поэтому отдаём email и profile
+func (s *OIDCService) UserInfo(userID uint) (map[string]interface{}, error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := s.auth.rbac.Permissions(u.Role)
+	if err != nil {
+		return nil, err
+	}
+	info := s.scopedClaims(u, "email profile")
+	info["sub"] = strconv.FormatUint(uint64(u.ID), 10)
+	info["role"] = u.Role
+	info["permissions"] = perms
+	return info, nil
+}
+
+func (s *OIDCService) scopedClaims(u *models.User, scope string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, sc := range strings.Fields(scope) {
+		switch sc {
+		case "email":
+			out["email"] = u.Email
+			out["email_verified"] = u.EmailVerified
+		case "profile":
+			out["name"] = u.FullName
+			out["updated_at"] = u.UpdatedAt.Unix()
+		}
+	}
+	return out
+}
+
+// Discovery - документ /.well-known/openid-configuration
+func (s *OIDCService) Discovery() map[string]interface{} {
+	iss := config.OIDCIssuer
+	return map[string]interface{}{
+		"issuer":                                iss,
+		"authorization_endpoint":                iss + "/api/v1/oauth/authorize",
+		"token_endpoint":                        iss + "/api/v1/oauth/token",
+		"userinfo_endpoint":                     iss + "/api/v1/oauth/userinfo",
+		"jwks_uri":                              iss + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+		"token_endpoint_auth_methods_supported": []string{"none", "client_secret_post", "client_secret_basic"},
+		"code_challenge_methods_supported":      []string{oidc.MethodS256},
+		"claims_supported": []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "sid",
+			"email", "email_verified", "name", "role", "permissions",
+		},
+	}
+}
+
+func (s *OIDCService) JWKS() oidc.JWKSet {
+	return s.signer.JWKS()
+}
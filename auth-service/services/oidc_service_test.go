@@ -0,0 +1,171 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/url"
+	"testing"
+
+	"auth-service/config"
+	"auth-service/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func setupOIDCService(t *testing.T) *OIDCService {
+	setupTestDB()
+	config.OIDCIssuer = "https://auth.example.com"
+	config.OIDCCodeTTLSec = 60
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Не удалось создать ключ: %v", err)
+	}
+	oidc.Default = oidc.NewSigner(key)
+	return NewOIDCService()
+}
+
+func authorizeRequest(clientID string) AuthorizeRequest {
+	return AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectURI:         "https://app.example.com/callback",
+		Scope:               "openid email profile",
+		State:               "xyz",
+		Nonce:               "n-0S6",
+		CodeChallenge:       oidc.ChallengeS256(testVerifier),
+		CodeChallengeMethod: oidc.MethodS256,
+	}
+}
+
+func TestOIDCService_AuthorizationCodeFlow(t *testing.T) {
+	svc := setupOIDCService(t)
+	user, err := NewAuthService().Register("spa@example.com", "password123", "SPA User")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if _, _, err := svc.RegisterClient("SPA", []string{"http://app.example.com/cb"}, true); err == nil {
+		t.Errorf("http redirect_uri вне localhost должен отклоняться")
+	}
+	client, secret, err := svc.RegisterClient("SPA", []string{"https://app.example.com/callback"}, true)
+	if err != nil {
+		t.Fatalf("RegisterClient: %v", err)
+	}
+	if secret != "" {
+		t.Errorf("У публичного клиента не должно быть секрета")
+	}
+
+	// Неизвестный redirect_uri - ошибка без перенаправления
+	bad := authorizeRequest(client.ClientID)
+	bad.RedirectURI = "https://evil.example.com/callback"
+	var oe *OAuthError
+	if _, err := svc.Authorize(bad, user.ID); !errors.As(err, &oe) || oe.Code != "invalid_request" {
+		t.Errorf("Ожидалась invalid_request, получено: %v", err)
+	}
+	var re *AuthorizeRedirectError
+	if errors.As(err, &re) {
+		t.Errorf("На незарегистрированный адрес перенаправлять нельзя")
+	}
+
+	// Без PKCE - ошибка через redirect_uri
+	noPKCE := authorizeRequest(client.ClientID)
+	noPKCE.CodeChallenge = ""
+	if _, err := svc.Authorize(noPKCE, user.ID); !errors.As(err, &re) || re.Code != "invalid_request" {
+		t.Errorf("Ожидалась ошибка PKCE через redirect, получено: %v", err)
+	}
+
+	location, err := svc.Authorize(authorizeRequest(client.ClientID), user.ID)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	u, _ := url.Parse(location)
+	code := u.Query().Get("code")
+	if code == "" || u.Query().Get("state") != "xyz" {
+		t.Fatalf("Ожидались code и state в %s", location)
+	}
+
+	tokenReq := TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  "https://app.example.com/callback",
+		ClientID:     client.ClientID,
+		CodeVerifier: "wrong-verifier-wrong-verifier-wrong-verifier-x",
+	}
+	if _, err := svc.Exchange(tokenReq); !errors.As(err, &oe) || oe.Code != "invalid_grant" {
+		t.Errorf("Неверный code_verifier должен отклоняться, получено: %v", err)
+	}
+
+	// Код одноразовый: после неудачной попытки он уже израсходован
+	tokenReq.CodeVerifier = testVerifier
+	if _, err := svc.Exchange(tokenReq); !errors.As(err, &oe) || oe.Code != "invalid_grant" {
+		t.Errorf("Использованный код должен отклоняться, получено: %v", err)
+	}
+
+	location, _ = svc.Authorize(authorizeRequest(client.ClientID), user.ID)
+	u, _ = url.Parse(location)
+	tokenReq.Code = u.Query().Get("code")
+	resp, err := svc.Exchange(tokenReq)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if resp.TokenType != "Bearer" || resp.AccessToken == "" || resp.Scope != "openid email profile" {
+		t.Errorf("Неожиданный ответ: %+v", resp)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(resp.IDToken, claims, func(*jwt.Token) (interface{}, error) {
+		return oidc.Default.PublicKey(), nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuer("https://auth.example.com"), jwt.WithAudience(client.ClientID))
+	if err != nil {
+		t.Fatalf("ID-токен не проверяется: %v", err)
+	}
+	if claims["nonce"] != "n-0S6" || claims["email"] != "spa@example.com" || claims["role"] != "user" {
+		t.Errorf("Неверные claims ID-токена: %v", claims)
+	}
+
+	// access_token - обычный JWT сервиса, привязанный к сессии
+	access := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(resp.AccessToken, access, func(*jwt.Token) (interface{}, error) { return config.JWTSecret, nil }); err != nil {
+		t.Fatalf("access_token не проверяется: %v", err)
+	}
+	if access["sid"] != claims["sid"] || access["role"] != "user" {
+		t.Errorf("access_token должен содержать role и ту же сессию: %v", access)
+	}
+}
+
+func TestOIDCService_ConfidentialClient(t *testing.T) {
+	svc := setupOIDCService(t)
+	user, _ := NewAuthService().Register("backend@example.com", "password123", "Backend")
+
+	client, secret, err := svc.RegisterClient("Admin panel", []string{"https://admin.example.com/callback"}, false)
+	if err != nil || secret == "" {
+		t.Fatalf("Ожидался секрет конфиденциального клиента: %v", err)
+	}
+	req := authorizeRequest(client.ClientID)
+	req.RedirectURI = "https://admin.example.com/callback"
+	location, err := svc.Authorize(req, user.ID)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	u, _ := url.Parse(location)
+
+	tokenReq := TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         u.Query().Get("code"),
+		RedirectURI:  req.RedirectURI,
+		ClientID:     client.ClientID,
+		ClientSecret: "wrong",
+		CodeVerifier: testVerifier,
+	}
+	var oe *OAuthError
+	if _, err := svc.Exchange(tokenReq); !errors.As(err, &oe) || oe.Code != "invalid_client" {
+		t.Errorf("Неверный секрет должен отклоняться, получено: %v", err)
+	}
+	tokenReq.ClientSecret = secret
+	if _, err := svc.Exchange(tokenReq); err != nil {
+		t.Errorf("Exchange с верным секретом: %v", err)
+	}
+}
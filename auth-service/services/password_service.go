@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/password"
+	"auth-service/repositories"
+)
+
+// ErrPasswordReused - новый пароль совпадает с одним из последних PASSWORD_HISTORY
+var ErrPasswordReused = errors.New("password was used recently, choose another one")
+
+// PasswordService проверяет новый пароль по политике и истории перед сменой
+type PasswordService struct {
+	history *repositories.PasswordRepo
+}
+
+func NewPasswordService() *PasswordService {
+	return &PasswordService{history: repositories.NewPasswordRepo()}
+}
+
+// Set проверяет пароль и записывает его хеш в u. Сохраняет пользователя вызывающий;
+// прежний хеш уходит в историю в той же транзакции (см. models.User.AfterSave). Политика ошибок - *password.PolicyError или ErrPasswordReused.
+func (s *PasswordService) Set(u *models.User, plain string) error {
+	if err := password.Validate(plain); err != nil {
+		return err
+	}
+	if u.ID != 0 && config.PasswordHistory > 0 {
+		if ok, _ := password.Verify(plain, u.Password); ok {
+			return ErrPasswordReused
+		}
+		previous, err := s.history.Recent(u.ID, config.PasswordHistory-1)
+		if err != nil {
+			return err
+		}
+		for _, h := range previous {
+			if ok, _ := password.Verify(plain, h.Hash); ok {
+				return ErrPasswordReused
+			}
+		}
+	}
+	old := u.Password
+	if err := u.SetPassword(plain); err != nil {
+		return err
+	}
+	if u.ID != 0 && old != "" && config.PasswordHistory > 1 {
+		u.RetirePassword(old, config.PasswordHistory-1)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"auth-service/config"
+	"auth-service/db"
+	"auth-service/models"
+	"auth-service/password"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func TestPasswordService_RejectsRecentPasswords(t *testing.T) {
+	setupTestDB()
+	config.PasswordHistory = 3
+	auth := NewAuthService()
+	svc := NewPasswordService()
+
+	var policy *password.PolicyError
+	if _, err := auth.Register("weak@example.com", "short", "Weak"); !errors.As(err, &policy) {
+		t.Errorf("Слабый пароль при регистрации должен отклоняться, получено: %v", err)
+	}
+
+	u, err := auth.Register("history@example.com", "password1", "History")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := svc.Set(u, "password1"); !errors.Is(err, ErrPasswordReused) {
+		t.Errorf("Текущий пароль нельзя установить повторно, получено: %v", err)
+	}
+	for _, p := range []string{"password2", "password3"} {
+		if err := svc.Set(u, p); err != nil {
+			t.Fatalf("Set %s: %v", p, err)
+		}
+		_ = auth.repo.Update(u)
+	}
+	// помним 3 последних: password3 (текущий), password2, password1
+	if err := svc.Set(u, "password1"); !errors.Is(err, ErrPasswordReused) {
+		t.Errorf("Недавний пароль должен отклоняться, получено: %v", err)
+	}
+	if err := svc.Set(u, "password4"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	_ = auth.repo.Update(u)
+	if err := svc.Set(u, "password1"); err != nil {
+		t.Errorf("Пароль старше истории снова разрешён: %v", err)
+	}
+}
+
+func TestPasswordService_HistoryWrittenOnSave(t *testing.T) {
+	setupTestDB()
+	config.PasswordHistory = 3
+	auth := NewAuthService()
+	svc := NewPasswordService()
+	u, err := auth.Register("unsaved@example.com", "password1", "Unsaved")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	count := func() int64 {
+		var n int64
+		db.DB.Model(&models.PasswordHistory{}).Where("user_id = ?", u.ID).Count(&n)
+		return n
+	}
+	if err := svc.Set(u, "password2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("До сохранения пользователя история не меняется, записей: %d", n)
+	}
+
+	// пользователь не сохранился - прежний хеш в историю не попадает
+	db.DB.Callback().Update().Before("gorm:update").Register("test:fail", func(tx *gorm.DB) {
+		tx.AddError(errors.New("save failed"))
+	})
+	err = auth.repo.Update(u)
+	db.DB.Callback().Update().Remove("test:fail")
+	if err == nil {
+		t.Fatal("Ожидалась ошибка сохранения")
+	}
+	if n := count(); n != 0 {
+		t.Errorf("Несохранённая смена пароля попала в историю, записей: %d", n)
+	}
+
+	if err := auth.repo.Update(u); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("После сохранения в истории должна быть 1 запись, получено %d", n)
+	}
+	if err := auth.repo.Update(u); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("Повторное сохранение не дублирует историю, записей: %d", n)
+	}
+}
+
+func TestAuthService_UpgradesLegacyHashOnLogin(t *testing.T) {
+	setupTestDB()
+	auth := NewAuthService()
+	u, err := auth.Register("legacy@example.com", "password123", "Legacy")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	u.Password = string(legacy)
+	_ = auth.repo.Update(u)
+
+	if _, err := auth.Authenticate("legacy@example.com", "password123"); err != nil {
+		t.Fatalf("Вход со старым хешем: %v", err)
+	}
+	stored, _ := auth.repo.FindByID(u.ID)
+	if !strings.HasPrefix(stored.Password, "$argon2id$") {
+		t.Errorf("После входа хеш должен быть argon2id, получено %s", stored.Password[:7])
+	}
+	if _, err := auth.Authenticate("legacy@example.com", "password123"); err != nil {
+		t.Errorf("Вход с новым хешем: %v", err)
+	}
+}
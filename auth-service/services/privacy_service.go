@@ -0,0 +1,281 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"auth-service/clients"
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/utils"
+)
+
+const authServiceName = "auth-service"
+
+var (
+	ErrDataRequestNotFound = errors.New("data request not found")
+	ErrDataExportExpired   = errors.New("data export has expired, request a new one")
+	// ErrErasureIncomplete - часть сервисов не обезличила данные; учётная запись сохранена, запрос можно повторить
+	ErrErasureIncomplete = errors.New("some services failed to anonymize user data")
+)
+
+// PrivacyService - выгрузка и удаление персональных данных пользователя во всех сервисах.
+// Каждый сервис отдаёт свои данные через GET /api/internal/users/:id/export
+// и обезличивает их через POST /api/internal/users/:id/anonymize.
+type PrivacyService struct {
+	repo           *repositories.DataRequestRepo
+	users          *repositories.UserRepo
+	identities     *repositories.IdentityRepo
+	sessions       *repositories.SessionRepo
+	logins         *repositories.LoginRepo
+	apiKeys        *repositories.APIKeyRepo
+	impersonations *repositories.ImpersonationRepo
+	peers          *clients.PeerClient
+	dir            string
+	now            func() time.Time
+}
+
+func NewPrivacyService() *PrivacyService {
+	return &PrivacyService{
+		repo:           repositories.NewDataRequestRepo(),
+		users:          repositories.NewUserRepo(),
+		identities:     repositories.NewIdentityRepo(),
+		sessions:       repositories.NewSessionRepo(),
+		logins:         repositories.NewLoginRepo(),
+		apiKeys:        repositories.NewAPIKeyRepo(),
+		impersonations: repositories.NewImpersonationRepo(),
+		peers:          clients.NewPeerClient(),
+		dir:            config.DataExportDir,
+		now:            time.Now,
+	}
+}
+
+// authExport - данные пользователя, которые хранит сам auth-service
+type authExport struct {
+	User           *models.User              `json:"user"`
+	Identities     []models.ExternalIdentity `json:"identities"`
+	Sessions       []models.Session          `json:"sessions"`
+	LoginAttempts  []models.LoginAttempt     `json:"login_attempts"`
+	APIKeys        []models.APIKey           `json:"api_keys"`
+	Impersonations []models.Impersonation    `json:"impersonations"` // входы администраторов от имени пользователя
+}
+
+type exportManifest struct {
+	UserID      uint                        `json:"user_id"`
+	GeneratedAt time.Time                   `json:"generated_at"`
+	Services    []models.DataRequestService `json:"services"`
+}
+
+// Export собирает данные пользователя из всех сервисов в zip-архив: по файлу на сервис
+// и manifest.json с результатом по каждому. Недоступный сервис не прерывает выгрузку,
+// запрос получает статус partial.
+func (s *PrivacyService) Export(u *models.User, requestedBy uint) (*models.DataRequest, error) {
+	s.cleanup()
+
+	files := map[string][]byte{}
+	own, err := s.collect(u)
+	if err != nil {
+		return nil, err
+	}
+	files[authServiceName] = own
+	services := []models.DataRequestService{{Service: authServiceName, OK: true}}
+	for _, p := range s.peers.ExportUser(u.ID, u.Email) {
+		services = append(services, models.DataRequestService(p.PeerResult))
+		if p.OK {
+			files[p.Service] = p.Data
+		}
+	}
+
+	now := s.now()
+	expires := now.Add(time.Duration(config.DataExportTTLHours) * time.Hour)
+	req := &models.DataRequest{
+		UserID:      u.ID,
+		RequestedBy: requestedBy,
+		Kind:        models.DataRequestExport,
+		Status:      requestStatus(services),
+		Services:    services,
+		ExpiresAt:   &expires,
+		CompletedAt: &now,
+	}
+	if err := s.repo.Create(req); err != nil {
+		return nil, err
+	}
+	path, err := s.writeArchive(req, files)
+	if err != nil {
+		return nil, err
+	}
+	req.File = path
+	if err := s.repo.Update(req); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return req, nil
+}
+
+// Archive - путь к архиву выгрузки пользователя
+func (s *PrivacyService) Archive(userID, id uint) (*models.DataRequest, error) {
+	req, err := s.repo.FindForUser(id, userID)
+	if errors.Is(err, repositories.ErrDataRequestNotFound) || (err == nil && req.Kind != models.DataRequestExport) {
+		return nil, ErrDataRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !req.Downloadable(s.now()) {
+		return nil, ErrDataExportExpired
+	}
+	return req, nil
+}
+
+func (s *PrivacyService) List(userID uint) ([]models.DataRequest, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// Erase обезличивает данные пользователя во всех сервисах и затем удаляет учётную запись.
+// Если хотя бы один сервис не ответил, учётная запись сохраняется и возвращается
+// ErrErasureIncomplete вместе с результатом по каждому сервису.
+func (s *PrivacyService) Erase(u *models.User, requestedBy uint) (*models.DataRequest, error) {
+	var services []models.DataRequestService
+	for _, r := range s.peers.AnonymizeUser(u.ID, u.Email) {
+		services = append(services, models.DataRequestService(r))
+	}
+	req := &models.DataRequest{
+		UserID:      u.ID,
+		RequestedBy: requestedBy,
+		Kind:        models.DataRequestErasure,
+		Status:      requestStatus(services),
+		Services:    services,
+	}
+	if req.Status != models.DataRequestCompleted {
+		req.Services = append(req.Services, models.DataRequestService{Service: authServiceName, Error: "skipped until other services succeed"})
+		if err := s.repo.Create(req); err != nil {
+			return nil, err
+		}
+		return req, ErrErasureIncomplete
+	}
+
+	s.removeArchives(u.ID)
+	if err := s.users.Purge(u); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	req.Services = append(req.Services, models.DataRequestService{Service: authServiceName, OK: true})
+	req.CompletedAt = &now
+	if err := s.repo.Create(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (s *PrivacyService) collect(u *models.User) ([]byte, error) {
+	out := authExport{User: u}
+	var err error
+	if out.Identities, err = s.identities.ListByUser(u.ID); err != nil {
+		return nil, err
+	}
+	if out.Sessions, err = s.sessions.ListByUser(u.ID); err != nil {
+		return nil, err
+	}
+	if out.LoginAttempts, _, err = s.logins.ListAttempts(repositories.AttemptFilter{UserID: &u.ID}, 0, -1); err != nil {
+		return nil, err
+	}
+	if out.APIKeys, err = s.apiKeys.ListActive(u.ID); err != nil {
+		return nil, err
+	}
+	if out.Impersonations, _, err = s.impersonations.List(repositories.ImpersonationFilter{UserID: &u.ID}, 0, -1); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func (s *PrivacyService) writeArchive(req *models.DataRequest, files map[string][]byte) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", err
+	}
+	// имя не угадывается по ID, архив отдаётся только владельцу
+	_, suffix, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("%d-%s.zip", req.ID, suffix[:16]))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	manifest, _ := json.MarshalIndent(exportManifest{UserID: req.UserID, GeneratedAt: req.CreatedAt, Services: req.Services}, "", "  ")
+	zw := zip.NewWriter(f)
+	err = writeZipFile(zw, "manifest.json", manifest)
+	for _, svc := range req.Services {
+		if data, ok := files[svc.Service]; ok && err == nil {
+			err = writeZipFile(zw, svc.Service+".json", data)
+		}
+	}
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// cleanup удаляет архивы с истёкшим сроком
+func (s *PrivacyService) cleanup() {
+	expired, err := s.repo.Expired(s.now())
+	if err != nil {
+		log.Printf("data export cleanup: %v", err)
+		return
+	}
+	s.dropFiles(expired)
+}
+
+func (s *PrivacyService) removeArchives(userID uint) {
+	items, err := s.repo.WithFiles(userID)
+	if err != nil {
+		log.Printf("data export cleanup for user %d: %v", userID, err)
+		return
+	}
+	s.dropFiles(items)
+}
+
+func (s *PrivacyService) dropFiles(items []models.DataRequest) {
+	for i := range items {
+		if err := os.Remove(items[i].File); err != nil && !os.IsNotExist(err) {
+			log.Printf("data export %d: %v", items[i].ID, err)
+			continue
+		}
+		items[i].File = ""
+		if err := s.repo.Update(&items[i]); err != nil {
+			log.Printf("data export %d: %v", items[i].ID, err)
+		}
+	}
+}
+
+func requestStatus(services []models.DataRequestService) string {
+	for _, svc := range services {
+		if !svc.OK {
+			return models.DataRequestPartial
+		}
+	}
+	return models.DataRequestCompleted
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+)
+
+// fakePeer - сервис с эндпоинтами export и anonymize, который можно «уронить»
+type fakePeer struct {
+	srv        *httptest.Server
+	down       bool
+	anonymized []string // значения ?email из запросов anonymize
+}
+
+func newFakePeer(t *testing.T) *fakePeer {
+	p := &fakePeer{}
+	p.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.down || r.Header.Get("X-Internal-Token") != config.InternalToken {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/export"):
+			w.Write([]byte(`{"profile":{"phone":"+100"}}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/anonymize"):
+			p.anonymized = append(p.anonymized, r.URL.Query().Get("email"))
+			w.Write([]byte(`{"anonymized":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+func setupPrivacyService(t *testing.T) (*PrivacyService, *fakePeer, *fakePeer) {
+	setupTestDB()
+	prevPeers, prevToken := config.PeerServices, config.InternalToken
+	t.Cleanup(func() { config.PeerServices, config.InternalToken = prevPeers, prevToken })
+	config.InternalToken = "internal"
+	config.DataExportDir = t.TempDir()
+	config.DataExportTTLHours = 24
+	users, contacts := newFakePeer(t), newFakePeer(t)
+	config.PeerServices = map[string]string{"user-service": users.srv.URL, "contact-service": contacts.srv.URL}
+	return NewPrivacyService(), users, contacts
+}
+
+func archiveFiles(t *testing.T, path string) map[string]string {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Архив не читается: %v", err)
+	}
+	defer zr.Close()
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func TestPrivacyService_Export(t *testing.T) {
+	svc, _, contacts := setupPrivacyService(t)
+	u, err := NewAuthService().Register("gdpr@example.com", "password123", "GDPR")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	contacts.down = true
+	req, err := svc.Export(u, u.ID)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if req.Status != models.DataRequestPartial || len(req.Services) != 3 {
+		t.Errorf("Недоступный сервис должен давать partial: %+v", req)
+	}
+	files := archiveFiles(t, req.File)
+	if !strings.Contains(files["auth-service.json"], "gdpr@example.com") || strings.Contains(files["auth-service.json"], "argon2") {
+		t.Errorf("auth-service.json должен содержать email и не содержать хеш пароля: %s", files["auth-service.json"])
+	}
+	if files["user-service.json"] == "" || !strings.Contains(files["manifest.json"], "contact-service") {
+		t.Errorf("Ожидались данные user-service и результат contact-service в manifest: %v", files)
+	}
+	if _, ok := files["contact-service.json"]; ok {
+		t.Errorf("Недоступный сервис не должен попадать в архив")
+	}
+
+	if _, err := svc.Archive(u.ID, req.ID); err != nil {
+		t.Errorf("Archive: %v", err)
+	}
+	if _, err := svc.Archive(u.ID+1, req.ID); !errors.Is(err, ErrDataRequestNotFound) {
+		t.Errorf("Чужая выгрузка недоступна, получено: %v", err)
+	}
+
+	// по истечении срока архив недоступен и удаляется при следующей выгрузке
+	svc.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	if _, err := svc.Archive(u.ID, req.ID); !errors.Is(err, ErrDataExportExpired) {
+		t.Errorf("Ожидалась ErrDataExportExpired, получено: %v", err)
+	}
+	contacts.down = false
+	next, err := svc.Export(u, u.ID)
+	if err != nil || next.Status != models.DataRequestCompleted {
+		t.Fatalf("Повторная выгрузка: %+v, %v", next, err)
+	}
+	if _, err := os.Stat(req.File); !os.IsNotExist(err) {
+		t.Errorf("Истёкший архив должен быть удалён")
+	}
+}
+
+func TestPrivacyService_Erase(t *testing.T) {
+	svc, users, contacts := setupPrivacyService(t)
+	auth := NewAuthService()
+	u, _ := auth.Register("erase@example.com", "password123", "Erase")
+	export, _ := svc.Export(u, u.ID)
+
+	contacts.down = true
+	req, err := svc.Erase(u, u.ID)
+	if !errors.Is(err, ErrErasureIncomplete) || req.Status != models.DataRequestPartial {
+		t.Fatalf("Ожидалась ErrErasureIncomplete, получено: %+v, %v", req, err)
+	}
+	if _, err := auth.repo.FindByID(u.ID); err != nil {
+		t.Errorf("При неудаче учётная запись должна сохраниться: %v", err)
+	}
+
+	contacts.down = false
+	req, err = svc.Erase(u, u.ID)
+	if err != nil || req.Status != models.DataRequestCompleted {
+		t.Fatalf("Erase: %+v, %v", req, err)
+	}
+	if _, err := auth.repo.FindByIDUnscoped(u.ID); err == nil {
+		t.Errorf("Учётная запись должна быть удалена")
+	}
+	if len(users.anonymized) != 2 || users.anonymized[1] != "erase@example.com" {
+		t.Errorf("Сервисы должны получить email для обезличивания: %v", users.anonymized)
+	}
+	if _, err := os.Stat(export.File); !os.IsNotExist(err) {
+		t.Errorf("Архивы выгрузок должны удаляться вместе с учётной записью")
+	}
+	if items, _ := svc.List(u.ID); len(items) != 3 {
+		t.Errorf("Записи о запросах остаются после удаления, получено %d", len(items))
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"auth-service/models"
+	"auth-service/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrSystemRole        = errors.New("system role cannot be changed")
+	ErrInvalidRoleName   = errors.New("role name must be 2-32 chars: lowercase letters, digits and dashes")
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+var roleNameRe = regexp.MustCompile(`^[a-z][a-z0-9-]{1,31}$`)
+
+type RBACService struct {
+	roles *repositories.RoleRepo
+}
+
+func NewRBACService() *RBACService {
+	return &RBACService{roles: repositories.NewRoleRepo()}
+}
+
+// Permissions возвращает отсортированный список прав роли.
+// Для неизвестной роли возвращается пустой список: пользователь просто ничего не может.
+func (s *RBACService) Permissions(role string) ([]string, error) {
+	r, err := s.roles.FindByName(role)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := r.PermissionNames()
+	sort.Strings(names)
+	return names, nil
+}
+
+// ValidateRole проверяет, что роль существует и её можно назначить
+func (s *RBACService) ValidateRole(name string) error {
+	_, err := s.roles.FindByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRoleNotFound
+	}
+	return err
+}
+
+func (s *RBACService) ListRoles() ([]models.Role, error) {
+	return s.roles.List()
+}
+
+func (s *RBACService) ListPermissions() ([]models.Permission, error) {
+	return s.roles.ListPermissions()
+}
+
+func (s *RBACService) GetRole(name string) (*models.Role, error) {
+	r, err := s.roles.FindByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	return r, err
+}
+
+func (s *RBACService) CreateRole(name, description string, permissions []string) (*models.Role, error) {
+	if !roleNameRe.MatchString(name) {
+		return nil, ErrInvalidRoleName
+	}
+	if _, err := s.roles.FindByName(name); err == nil {
+		return nil, ErrRoleExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	perms, err := s.resolve(permissions)
+	if err != nil {
+		return nil, err
+	}
+	role := &models.Role{Name: name, Description: description, Permissions: perms}
+	if err := s.roles.Create(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// UpdateRole меняет описание и/или набор прав. nil означает "не менять".
+func (s *RBACService) UpdateRole(name string, description *string, permissions []string) (*models.Role, error) {
+	role, err := s.GetRole(name)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == models.RoleAdmin && permissions != nil {
+		return nil, ErrSystemRole
+	}
+	if description != nil {
+		role.Description = *description
+	}
+	if permissions != nil {
+		perms, err := s.resolve(permissions)
+		if err != nil {
+			return nil, err
+		}
+		role.Permissions = perms
+	}
+	if err := s.roles.Update(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *RBACService) DeleteRole(name string) error {
+	role, err := s.GetRole(name)
+	if err != nil {
+		return err
+	}
+	if role.System {
+		return ErrSystemRole
+	}
+	n, err := s.roles.CountUsers(name)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrRoleInUse
+	}
+	return s.roles.Delete(role)
+}
+
+func (s *RBACService) resolve(names []string) ([]models.Permission, error) {
+	perms, err := s.roles.FindPermissions(names)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		known[p.Name] = true
+	}
+	for _, n := range names {
+		if !known[n] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, n)
+		}
+	}
+	return perms, nil
+}
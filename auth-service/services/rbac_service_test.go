@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"auth-service/config"
+	"auth-service/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRBACService_BuiltinRoles(t *testing.T) {
+	setupTestDB()
+	rbac := NewRBACService()
+
+	admin, err := rbac.Permissions(models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("Permissions: %v", err)
+	}
+	if len(admin) != len(models.BuiltinPermissions) {
+		t.Errorf("admin должен иметь все права, получено %v", admin)
+	}
+
+	editor, _ := rbac.Permissions("content-editor")
+	if len(editor) != 1 || editor[0] != models.PermPortfolioManage {
+		t.Errorf("content-editor: ожидалось только %s, получено %v", models.PermPortfolioManage, editor)
+	}
+
+	user, _ := rbac.Permissions(models.RoleUser)
+	if len(user) != 0 {
+		t.Errorf("У роли user не должно быть прав, получено %v", user)
+	}
+
+	if _, err := rbac.UpdateRole(models.RoleAdmin, nil, []string{}); !errors.Is(err, ErrSystemRole) {
+		t.Errorf("Права admin не должны редактироваться, получено: %v", err)
+	}
+	if err := rbac.DeleteRole(models.RoleUser); !errors.Is(err, ErrSystemRole) {
+		t.Errorf("Системную роль нельзя удалить, получено: %v", err)
+	}
+}
+
+func TestRBACService_CustomRole(t *testing.T) {
+	setupTestDB()
+	rbac := NewRBACService()
+
+	if _, err := rbac.CreateRole("Bad Name", "", nil); !errors.Is(err, ErrInvalidRoleName) {
+		t.Errorf("Ожидалась ошибка имени роли, получено: %v", err)
+	}
+	if _, err := rbac.CreateRole("catalog", "", []string{"products.nuke"}); !errors.Is(err, ErrUnknownPermission) {
+		t.Errorf("Ожидалась ошибка неизвестного права, получено: %v", err)
+	}
+
+	role, err := rbac.CreateRole("catalog", "catalog team", []string{models.PermProductsManage})
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if _, err := rbac.CreateRole("catalog", "", nil); !errors.Is(err, ErrRoleExists) {
+		t.Errorf("Ожидалась ошибка дубликата, получено: %v", err)
+	}
+
+	if _, err := rbac.UpdateRole(role.Name, nil, []string{models.PermProductsManage, models.PermOrdersManage}); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	perms, _ := rbac.Permissions(role.Name)
+	if len(perms) != 2 || perms[0] != models.PermOrdersManage || perms[1] != models.PermProductsManage {
+		t.Errorf("Ожидались orders.manage и products.manage, получено %v", perms)
+	}
+
+	// Права роли попадают в токен
+	auth := NewAuthService()
+	user, err := auth.Register("catalog@example.com", "password123", "Catalog")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	user.Role = role.Name
+	if err := auth.repo.Update(user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	token, err := auth.GenerateJWT(user, "test-session")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return config.JWTSecret, nil }); err != nil {
+		t.Fatalf("Не удалось разобрать токен: %v", err)
+	}
+	if got, _ := claims["permissions"].([]interface{}); len(got) != 2 {
+		t.Errorf("В токене ожидалось 2 права, получено %v", claims["permissions"])
+	}
+
+	if err := rbac.DeleteRole(role.Name); !errors.Is(err, ErrRoleInUse) {
+		t.Errorf("Назначенную роль нельзя удалить, получено: %v", err)
+	}
+	user.Role = models.RoleUser
+	auth.repo.Update(user)
+	if err := rbac.DeleteRole(role.Name); err != nil {
+		t.Errorf("DeleteRole: %v", err)
+	}
+}
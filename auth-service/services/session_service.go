@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/repositories"
+	"auth-service/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionRevoked  = errors.New("session revoked or expired")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// last_seen_at обновляется не чаще раза в минуту, чтобы не писать в БД на каждый запрос
+const sessionTouchInterval = time.Minute
+
+type SessionService struct {
+	repo *repositories.SessionRepo
+	now  func() time.Time
+}
+
+func NewSessionService() *SessionService {
+	return &SessionService{repo: repositories.NewSessionRepo(), now: time.Now}
+}
+
+// Start создаёт сессию при входе; срок жизни совпадает со сроком JWT
+func (s *SessionService) Start(userID uint, ip, userAgent string) (*models.Session, error) {
+	return s.start(userID, nil, ip, userAgent, time.Duration(config.JWTTTLMin)*time.Minute)
+}
+
+// StartImpersonation создаёт короткую сессию пользователя userID для администратора actorID.
+// Она видна пользователю в списке сессий и отзывается так же, как обычная.
+func (s *SessionService) StartImpersonation(userID, actorID uint, ip, userAgent string) (*models.Session, error) {
+	return s.start(userID, &actorID, ip, userAgent, time.Duration(config.ImpersonationTTLMin)*time.Minute)
+}
+
+func (s *SessionService) start(userID uint, actorID *uint, ip, userAgent string, ttl time.Duration) (*models.Session, error) {
+	sid, _, err := utils.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	sess := &models.Session{
+		UserID:     userID,
+		ActorID:    actorID,
+		SID:        sid,
+		Device:     describeDevice(userAgent),
+		IP:         ip,
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if err := s.repo.Create(sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// Validate проверяет, что сессия из токена не отозвана, и отмечает активность
+func (s *SessionService) Validate(sid string, userID uint) (*models.Session, error) {
+	sess, err := s.repo.FindBySID(sid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if sess.UserID != userID || !sess.Active(now) {
+		return nil, ErrSessionRevoked
+	}
+	if now.Sub(sess.LastSeenAt) >= sessionTouchInterval {
+		if err := s.repo.Touch(sess.ID, now); err != nil {
+			return nil, err
+		}
+		sess.LastSeenAt = now
+	}
+	return sess, nil
+}
+
+func (s *SessionService) List(userID uint) ([]models.Session, error) {
+	return s.repo.ListActive(userID, s.now())
+}
+
+func (s *SessionService) Revoke(userID, id uint) error {
+	sess, err := s.repo.FindForUser(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if sess.RevokedAt != nil {
+		return nil
+	}
+	return s.repo.Revoke(sess, s.now())
+}
+
+// RevokeAll - выход на всех устройствах
+func (s *SessionService) RevokeAll(userID uint) (int64, error) {
+	return s.repo.RevokeAll(userID, s.now())
+}
+
+// describeDevice - короткое описание устройства для списка сессий, например "Chrome on Windows"
+func describeDevice(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+	browsers := []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+	}
+	systems := []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+	browser, system := "", ""
+	for _, b := range browsers {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, o := range systems {
+		if strings.Contains(ua, o.token) {
+			system = o.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown device"
+}
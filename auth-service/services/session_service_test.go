@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"auth-service/repositories"
+)
+
+func TestSessionService_Lifecycle(t *testing.T) {
+	setupTestDB()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := &SessionService{repo: repositories.NewSessionRepo(), now: func() time.Time { return now }}
+
+	phone, err := s.Start(1, "10.0.0.1", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Safari/604.1")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	laptop, _ := s.Start(1, "10.0.0.2", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0 Safari/537.36")
+	other, _ := s.Start(2, "10.0.0.3", "")
+
+	if phone.Device != "Safari on iOS" || laptop.Device != "Chrome on Windows" || other.Device != "Unknown device" {
+		t.Errorf("Неверное описание устройств: %q, %q, %q", phone.Device, laptop.Device, other.Device)
+	}
+
+	// Сессия чужого пользователя не принимается
+	if _, err := s.Validate(phone.SID, 2); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Ожидался отказ для чужой сессии, получено: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	got, err := s.Validate(laptop.SID, 1)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if !got.LastSeenAt.Equal(now) {
+		t.Errorf("last_seen_at должен обновиться, получено %v", got.LastSeenAt)
+	}
+
+	list, _ := s.List(1)
+	if len(list) != 2 || list[0].ID != laptop.ID {
+		t.Fatalf("Ожидались 2 сессии, последняя активная первой, получено %+v", list)
+	}
+
+	if err := s.Revoke(2, phone.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Нельзя отозвать чужую сессию, получено: %v", err)
+	}
+	if err := s.Revoke(1, phone.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := s.Validate(phone.SID, 1); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Отозванная сессия должна отклоняться, получено: %v", err)
+	}
+
+	n, err := s.RevokeAll(1)
+	if err != nil || n != 1 {
+		t.Errorf("RevokeAll: ожидалась 1 сессия, получено %d (%v)", n, err)
+	}
+	if _, err := s.Validate(other.SID, 2); err != nil {
+		t.Errorf("Сессии других пользователей не затрагиваются: %v", err)
+	}
+
+	// Истёкшая сессия недействительна
+	now = now.Add(2 * time.Hour)
+	if _, err := s.Validate(other.SID, 2); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Истёкшая сессия должна отклоняться, получено: %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/oidc"
+	"auth-service/repositories"
+	"auth-service/utils"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrSSOStateInvalid = errors.New("invalid or expired login state")
+	// ErrSSOFailed - провайдер отклонил обмен кода или вернул непроверяемый ID-токен
+	ErrSSOFailed = errors.New("external login failed")
+	// ErrProviderEmailNotVerified - без подтверждённого провайдером email учётные записи не связываются
+	ErrProviderEmailNotVerified = errors.New("email is not verified by the identity provider")
+	// ErrLocalEmailNotVerified - локальная учётная запись с тем же email не подтверждена:
+	// автоматическая привязка позволила бы захватить её тому, кто зарегистрировал чужой адрес
+	ErrLocalEmailNotVerified = errors.New("account with this email is not verified, sign in with password and link the provider")
+	ErrIdentityLinked        = errors.New("this external account is linked to another user")
+	ErrIdentityNotFound      = errors.New("identity not found")
+	// ErrLastLoginMethod - у пользователя без пароля нельзя отвязать последний провайдер
+	ErrLastLoginMethod = errors.New("cannot unlink the only sign-in method, set a password first")
+)
+
+// SSOResult - итог возврата от провайдера: вход или привязка к текущему пользователю
+type SSOResult struct {
+	User     *models.User
+	Identity *models.ExternalIdentity
+	Linked   bool // это был сценарий привязки, токен выдавать не нужно
+	Created  bool // учётная запись создана при первом входе
+}
+
+type SSOService struct {
+	repo      *repositories.IdentityRepo
+	users     *repositories.UserRepo
+	auth      *AuthService
+	providers map[string]*oidc.Provider
+	now       func() time.Time
+}
+
+func NewSSOService() *SSOService {
+	return &SSOService{
+		repo:      repositories.NewIdentityRepo(),
+		users:     repositories.NewUserRepo(),
+		auth:      NewAuthService(),
+		providers: oidc.Providers,
+		now:       time.Now,
+	}
+}
+
+// Providers - имена настроенных провайдеров
+func (s *SSOService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *SSOService) provider(name string) (*oidc.Provider, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Begin создаёт state и возвращает адрес входа у провайдера.
+// linkUserID != nil - привязка провайдера к уже вошедшему пользователю.
+func (s *SSOService) Begin(providerName string, linkUserID *uint) (string, error) {
+	p, err := s.provider(providerName)
+	if err != nil {
+		return "", err
+	}
+	now := s.now()
+	if err := s.repo.DeleteExpiredStates(now); err != nil {
+		return "", err
+	}
+	state, stateHash, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, _, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, _, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+	authURL, err := p.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSSOFailed, err)
+	}
+	err = s.repo.CreateState(&models.SSOState{
+		StateHash:    stateHash,
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    now.Add(time.Duration(config.SSOStateTTLMin) * time.Minute),
+	})
+	if err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+// Complete обрабатывает возврат от провайдера. Внешняя учётная запись ищется по (provider, sub);
+// новая привязывается к пользователю с тем же email, только если email подтверждён
+// и провайдером, и у нас. Если пользователя с таким email нет, он создаётся без пароля.
+//
+// userID - вошедший пользователь, завершающий привязку, nil для входа. Привязку нельзя
+// завершить без токена её инициатора: иначе по чужой ссылке жертва привязала бы
+// свою внешнюю учётную запись к аккаунту злоумышленника.
+func (s *SSOService) Complete(providerName, state, code string, userID *uint) (*SSOResult, error) {
+	p, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	st, err := s.repo.ConsumeState(utils.HashToken(state), s.now())
+	if errors.Is(err, repositories.ErrStateInvalid) {
+		return nil, ErrSSOStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if st.Provider != providerName || (st.LinkUserID == nil) != (userID == nil) ||
+		(userID != nil && *st.LinkUserID != *userID) {
+		return nil, ErrSSOStateInvalid
+	}
+
+	ext, err := p.Exchange(code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSOFailed, err)
+	}
+
+	if st.LinkUserID != nil {
+		return s.link(providerName, ext, *st.LinkUserID)
+	}
+	return s.login(providerName, ext)
+}
+
+func (s *SSOService) link(providerName string, ext *oidc.Identity, userID uint) (*SSOResult, error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := s.repo.FindBySubject(providerName, ext.Subject)
+	switch {
+	case err == nil && identity.UserID != userID:
+		return nil, ErrIdentityLinked
+	case err == nil:
+		return &SSOResult{User: u, Identity: identity, Linked: true}, nil
+	case !errors.Is(err, repositories.ErrIdentityNotFound):
+		return nil, err
+	}
+	identity = &models.ExternalIdentity{UserID: userID, Provider: providerName, Subject: ext.Subject, Email: ext.Email}
+	if err := s.repo.Create(identity); err != nil {
+		return nil, err
+	}
+	return &SSOResult{User: u, Identity: identity, Linked: true}, nil
+}
+
+func (s *SSOService) login(providerName string, ext *oidc.Identity) (*SSOResult, error) {
+	now := s.now()
+	res := &SSOResult{}
+
+	identity, err := s.repo.FindBySubject(providerName, ext.Subject)
+	switch {
+	case err == nil:
+		res.User, err = s.users.FindByID(identity.UserID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, fmt.Errorf("%w: user %d is deleted", ErrSSOFailed, identity.UserID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, repositories.ErrIdentityNotFound):
+		if ext.Email == "" || !ext.EmailVerified {
+			return nil, ErrProviderEmailNotVerified
+		}
+		u, err := s.users.FindByEmail(ext.Email)
+		switch {
+		case err == nil && !u.EmailVerified:
+			return nil, ErrLocalEmailNotVerified
+		case err == nil:
+			res.User = u
+		case errors.Is(err, repositories.ErrNotFound):
+			u = &models.User{Email: ext.Email, FullName: ext.Name, Role: models.RoleUser}
+			u.MarkEmailVerified(now)
+			if err := s.users.Create(u); err != nil {
+				return nil, err
+			}
+			res.User, res.Created = u, true
+		default:
+			return nil, err
+		}
+		identity = &models.ExternalIdentity{UserID: res.User.ID, Provider: providerName, Subject: ext.Subject}
+	default:
+		return nil, err
+	}
+
+	if err := s.auth.checkSuspension(res.User); err != nil {
+		return nil, err
+	}
+	identity.Email = ext.Email
+	identity.LastLoginAt = &now
+	if identity.ID == 0 {
+		err = s.repo.Create(identity)
+	} else {
+		err = s.repo.Update(identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.Identity = identity
+	return res, nil
+}
+
+func (s *SSOService) Identities(userID uint) ([]models.ExternalIdentity, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// Unlink отвязывает провайдер. Пользователь, созданный через провайдер, не имеет пароля,
+// поэтому последнюю привязку он может удалить только после установки пароля.
+func (s *SSOService) Unlink(userID, identityID uint) error {
+	identity, err := s.repo.FindForUser(identityID, userID)
+	if errors.Is(err, repositories.ErrIdentityNotFound) {
+		return ErrIdentityNotFound
+	}
+	if err != nil {
+		return err
+	}
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if u.Password == "" {
+		all, err := s.repo.ListByUser(userID)
+		if err != nil {
+			return err
+		}
+		if len(all) <= 1 {
+			return ErrLastLoginMethod
+		}
+	}
+	return s.repo.Delete(identity)
+}
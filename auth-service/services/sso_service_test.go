@@ -0,0 +1,234 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"auth-service/config"
+	"auth-service/oidc"
+	"auth-service/repositories"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer - минимальный внешний OIDC провайдер: discovery, JWKS и token endpoint
+type mockIssuer struct {
+	srv    *httptest.Server
+	signer *oidc.Signer
+	grants map[string]mockGrant
+}
+
+type mockGrant struct {
+	sub, email string
+	verified   bool
+	nonce      string
+	challenge  string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Не удалось создать ключ: %v", err)
+	}
+	m := &mockIssuer{signer: oidc.NewSigner(key), grants: map[string]mockGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 m.srv.URL,
+			"authorization_endpoint": m.srv.URL + "/authorize",
+			"token_endpoint":         m.srv.URL + "/token",
+			"jwks_uri":               m.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.signer.JWKS())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		g, ok := m.grants[r.PostFormValue("code")]
+		delete(m.grants, r.PostFormValue("code"))
+		if !ok || r.PostFormValue("client_id") != "test-client" ||
+			!oidc.VerifyPKCE(r.PostFormValue("code_verifier"), g.challenge, oidc.MethodS256) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		idToken, _ := m.signer.Sign(jwt.MapClaims{
+			"iss":            m.srv.URL,
+			"aud":            "test-client",
+			"sub":            g.sub,
+			"email":          g.email,
+			"email_verified": g.verified,
+			"nonce":          g.nonce,
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+		})
+		writeJSON(w, http.StatusOK, map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+	})
+	m.srv = httptest.NewServer(mux)
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// authorize имитирует вход пользователя у провайдера и возвращает state и code
+func (m *mockIssuer) authorize(t *testing.T, authURL, sub, email string, verified bool) (string, string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("Неверный адрес провайдера: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != oidc.MethodS256 || q.Get("nonce") == "" {
+		t.Fatalf("Ожидались PKCE и nonce: %s", authURL)
+	}
+	code := fmt.Sprintf("code-%d", len(m.grants)+1) + sub
+	m.grants[code] = mockGrant{sub: sub, email: email, verified: verified, nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	return q.Get("state"), code
+}
+
+func setupSSOService(t *testing.T) (*SSOService, *mockIssuer) {
+	setupTestDB()
+	config.SSOStateTTLMin = 10
+	m := newMockIssuer(t)
+	svc := NewSSOService()
+	svc.providers = map[string]*oidc.Provider{"mock": oidc.NewProvider(oidc.ProviderConfig{
+		Name:        "mock",
+		Issuer:      m.srv.URL,
+		ClientID:    "test-client",
+		Scopes:      []string{"openid", "email"},
+		RedirectURL: "http://localhost:8080/api/v1/sso/mock/callback",
+	}, m.srv.Client())}
+	return svc, m
+}
+
+func (m *mockIssuer) login(t *testing.T, svc *SSOService, sub, email string, verified bool) (*SSOResult, error) {
+	authURL, err := svc.Begin("mock", nil)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	state, code := m.authorize(t, authURL, sub, email, verified)
+	return svc.Complete("mock", state, code, nil)
+}
+
+func TestSSOService_Login(t *testing.T) {
+	svc, m := setupSSOService(t)
+	users := repositories.NewUserRepo()
+
+	res, err := m.login(t, svc, "ext-1", "new@example.com", true)
+	if err != nil {
+		t.Fatalf("Первый вход: %v", err)
+	}
+	if !res.Created || !res.User.EmailVerified || res.User.Password != "" {
+		t.Errorf("Ожидался новый пользователь без пароля с подтверждённым email: %+v", res.User)
+	}
+	again, err := m.login(t, svc, "ext-1", "new@example.com", true)
+	if err != nil || again.Created || again.User.ID != res.User.ID {
+		t.Errorf("Повторный вход должен найти того же пользователя: %+v, %v", again, err)
+	}
+
+	// существующая подтверждённая учётная запись привязывается по email
+	existing, _ := NewAuthService().Register("known@example.com", "password123", "Known")
+	existing.MarkEmailVerified(time.Now())
+	_ = users.Update(existing)
+	res, err = m.login(t, svc, "ext-2", "known@example.com", true)
+	if err != nil || res.Created || res.User.ID != existing.ID {
+		t.Errorf("Ожидалась привязка к существующему пользователю: %+v, %v", res, err)
+	}
+
+	if _, err := NewAuthService().Register("squatter@example.com", "password123", "Squatter"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := m.login(t, svc, "ext-3", "squatter@example.com", true); !errors.Is(err, ErrLocalEmailNotVerified) {
+		t.Errorf("Неподтверждённая учётная запись не должна привязываться, получено: %v", err)
+	}
+	if _, err := m.login(t, svc, "ext-4", "other@example.com", false); !errors.Is(err, ErrProviderEmailNotVerified) {
+		t.Errorf("Email без подтверждения провайдером должен отклоняться, получено: %v", err)
+	}
+}
+
+func TestSSOService_RejectsReplayedState(t *testing.T) {
+	svc, m := setupSSOService(t)
+
+	authURL, _ := svc.Begin("mock", nil)
+	state, code := m.authorize(t, authURL, "ext-1", "a@example.com", true)
+	if _, err := svc.Complete("mock", state, code, nil); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, err := svc.Complete("mock", state, code, nil); !errors.Is(err, ErrSSOStateInvalid) {
+		t.Errorf("Повторный state должен отклоняться, получено: %v", err)
+	}
+
+	// подмена nonce: ID-токен выдан для другой попытки входа
+	authURL, _ = svc.Begin("mock", nil)
+	state, code = m.authorize(t, authURL, "ext-1", "a@example.com", true)
+	g := m.grants[code]
+	g.nonce = "other"
+	m.grants[code] = g
+	if _, err := svc.Complete("mock", state, code, nil); !errors.Is(err, ErrSSOFailed) {
+		t.Errorf("Чужой nonce должен отклоняться, получено: %v", err)
+	}
+
+	if _, err := svc.Begin("unknown", nil); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Ожидалась ErrUnknownProvider, получено: %v", err)
+	}
+}
+
+func TestSSOService_LinkAndUnlink(t *testing.T) {
+	svc, m := setupSSOService(t)
+	alice, _ := NewAuthService().Register("alice@example.com", "password123", "Alice")
+	bob, _ := NewAuthService().Register("bob@example.com", "password123", "Bob")
+
+	// привязку нельзя завершить без токена инициатора
+	authURL, _ := svc.Begin("mock", &alice.ID)
+	state, code := m.authorize(t, authURL, "ext-alice", "alice@gmail.com", true)
+	if _, err := svc.Complete("mock", state, code, nil); !errors.Is(err, ErrSSOStateInvalid) {
+		t.Errorf("Привязка через публичный callback должна отклоняться, получено: %v", err)
+	}
+
+	authURL, _ = svc.Begin("mock", &alice.ID)
+	state, code = m.authorize(t, authURL, "ext-alice", "alice@gmail.com", true)
+	res, err := svc.Complete("mock", state, code, &alice.ID)
+	if err != nil || !res.Linked || res.Identity.UserID != alice.ID {
+		t.Fatalf("Привязка: %+v, %v", res, err)
+	}
+
+	authURL, _ = svc.Begin("mock", &bob.ID)
+	state, code = m.authorize(t, authURL, "ext-alice", "alice@gmail.com", true)
+	if _, err := svc.Complete("mock", state, code, &bob.ID); !errors.Is(err, ErrIdentityLinked) {
+		t.Errorf("Чужая внешняя учётная запись не должна привязываться, получено: %v", err)
+	}
+
+	// вход по привязанной учётной записи, email у провайдера отличается
+	login, err := m.login(t, svc, "ext-alice", "alice@gmail.com", true)
+	if err != nil || login.User.ID != alice.ID {
+		t.Errorf("Вход должен найти Alice: %+v, %v", login, err)
+	}
+
+	created, err := m.login(t, svc, "ext-carol", "carol@example.com", true)
+	if err != nil {
+		t.Fatalf("Вход: %v", err)
+	}
+	if err := svc.Unlink(created.User.ID, created.Identity.ID); !errors.Is(err, ErrLastLoginMethod) {
+		t.Errorf("Последний способ входа без пароля отвязывать нельзя, получено: %v", err)
+	}
+	if err := svc.Unlink(bob.ID, res.Identity.ID); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("Чужую привязку отвязывать нельзя, получено: %v", err)
+	}
+	if err := svc.Unlink(alice.ID, res.Identity.ID); err != nil {
+		t.Errorf("Unlink: %v", err)
+	}
+	if items, _ := svc.Identities(alice.ID); len(items) != 0 {
+		t.Errorf("Ожидалось 0 привязок, получено %d", len(items))
+	}
+}
@@ -0,0 +1,100 @@
+// Package totp реализует одноразовые пароли по времени (RFC 6238) с HMAC-SHA1,
+// совместимые с Google Authenticator и аналогами.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Period = 30 // секунд на один код
+	Digits = 6
+	// Skew - сколько соседних интервалов принимаем из-за расхождения часов
+	Skew = 1
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret возвращает случайный 160-битный секрет в base32
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// Step - номер 30-секундного интервала для момента t
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code вычисляет код для интервала step
+func Code(secret string, step int64) (string, error) {
+	return code(secret, step, Digits)
+}
+
+func code(secret string, step int64, digits int) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, bin%mod), nil
+}
+
+// Validate проверяет код с допуском Skew и возвращает интервал, которому он соответствует.
+// Интервалы не новее lastStep отклоняются, чтобы один код нельзя было использовать дважды.
+func Validate(secret, input string, now time.Time, lastStep int64) (int64, bool) {
+	input = strings.ReplaceAll(strings.TrimSpace(input), " ", "")
+	if len(input) != Digits {
+		return 0, false
+	}
+	current := Step(now)
+	for delta := int64(-Skew); delta <= Skew; delta++ {
+		step := current + delta
+		if step <= lastStep {
+			continue
+		}
+		want, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(input)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// ProvisioningURI - otpauth:// ссылка для QR-кода в приложении-аутентификаторе
+func ProvisioningURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(Period))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Тестовые векторы RFC 6238 (приложение B) для SHA1, ключ "12345678901234567890"
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		got, err := code(secret, Step(time.Unix(tt.unix, 0)), 8)
+		if err != nil {
+			t.Fatalf("code: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("T=%d: ожидалось %s, получено %s", tt.unix, tt.want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	current, _ := Code(secret, Step(now))
+	previous, _ := Code(secret, Step(now)-1)
+	old, _ := Code(secret, Step(now)-3)
+
+	if step, ok := Validate(secret, current, now, 0); !ok || step != Step(now) {
+		t.Errorf("Текущий код должен приниматься")
+	}
+	if _, ok := Validate(secret, previous, now, 0); !ok {
+		t.Errorf("Код предыдущего интервала должен приниматься из-за расхождения часов")
+	}
+	if _, ok := Validate(secret, old, now, 0); ok {
+		t.Errorf("Старый код не должен приниматься")
+	}
+	if _, ok := Validate(secret, current, now, Step(now)); ok {
+		t.Errorf("Повторное использование кода должно отклоняться")
+	}
+	if _, ok := Validate(secret, "12345", now, 0); ok {
+		t.Errorf("Код неверной длины не должен приниматься")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Shop", "admin@example.com", "JBSWY3DPEHPK3PXP")
+	for _, want := range []string{"otpauth://totp/Shop:admin@example.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=Shop", "digits=6"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("URI %q не содержит %q", uri, want)
+		}
+	}
+}
@@ -7,10 +7,11 @@ import (
 )
 
 type Config struct {
-	Port      string
-	DBPath    string
-	LogLevel  string
-	JWTSecret string
+	Port          string
+	DBPath        string
+	LogLevel      string
+	JWTSecret     string
+	InternalToken string
 }
 
 func LoadConfig() Config {
@@ -38,9 +39,10 @@ func LoadConfig() Config {
 	}
 
 	return Config{
-		Port:      port,
-		DBPath:    dbPath,
-		LogLevel:  logLevel,
-		JWTSecret: jwtSecret,
+		Port:          port,
+		DBPath:        dbPath,
+		LogLevel:      logLevel,
+		JWTSecret:     jwtSecret,
+		InternalToken: os.Getenv("INTERNAL_API_TOKEN"),
 	}
 }
@@ -11,7 +11,7 @@ var DB *gorm.DB
 
 func InitDB(path string, models ...interface{}) {
 	var err error
-	DB, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open(path), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ooolalex/contact-service/db"
+	"ooolalex/contact-service/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterInternalRoutes - межсервисные эндпоинты, защищённые X-Internal-Token
+func RegisterInternalRoutes(r *gin.Engine, internalMiddleware gin.HandlerFunc) {
+	internal := r.Group("/api/internal")
+	internal.Use(internalMiddleware)
+
+	// Выгрузка: заявки, оставленные с email пользователя (?email=), обработанные им заявки и журнал
+	internal.GET("/users/:id/export", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		requests := []models.ContactRequest{}
+		handled := []models.ContactRequest{}
+		logs := []models.Log{}
+		if email := c.Query("email"); email != "" {
+			if err := db.DB.Where("contact = ?", email).Order("id").Find(&requests).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+				return
+			}
+		}
+		if err := db.DB.Where("admin_id = ?", id).Order("id").Find(&handled).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+			return
+		}
+		if err := db.DB.Where("user_id = ?", id).Order("id").Find(&logs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"contact_requests": requests,
+			"handled_requests": handled,
+			"logs":             logs,
+		})
+	})
+
+	// Обезличивание: убираем ссылки на пользователя из заявок и журнала,
+	// а в заявках, оставленных с его email (?email=), стираем контакт
+	internal.POST("/users/:id/anonymize", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		email := c.Query("email")
+
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.ContactRequest{}).Where("admin_id = ?", id).Update("admin_id", nil).Error; err != nil {
+				return err
+			}
+			if email != "" {
+				if err := tx.Model(&models.ContactRequest{}).Where("contact = ?", email).Update("contact", "").Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&models.Log{}).Where("user_id = ?", id).Update("user_id", nil).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"anonymized": true})
+	})
+}
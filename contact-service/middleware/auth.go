@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"errors"
 	"net/http"
 	"ooolalex/contact-service/clients"
 	"ooolalex/contact-service/config"
@@ -11,10 +13,18 @@ import (
 )
 
 const ContextUserID = "userID"
-const ContextIsAdmin = "isAdmin"
+const ContextPermissions = "permissions"
+
+// PermContactsManage - обработка заявок (роль support)
+const PermContactsManage = "contacts.manage"
 
 func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		auth := c.GetHeader("Authorization")
 		if auth == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "no token"})
@@ -45,33 +55,118 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 
 		c.Set(ContextUserID, userID)
 
-		// Check role from auth-service
+		// Resolve permissions from auth-service
 		authClient := clients.NewAuthClient()
-		role, err := authClient.GetUserRole(userID)
+		sid, _ := claims["sid"].(string)
+		access, err := authClient.GetUserAccess(userID, sid)
+		if errors.Is(err, clients.ErrSessionRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			return
+		}
+		if errors.Is(err, clients.ErrUserSuspended) || errors.Is(err, clients.ErrUserNotFound) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is suspended or deleted"})
+			return
+		}
+		actorID := actorFromClaims(claims)
 		if err == nil {
-			c.Set(ContextIsAdmin, role == "admin")
+			c.Set(ContextPermissions, access.Permissions)
+		} else if actorID != 0 {
+			// токен имперсонации без проверки его сессии не принимается
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify impersonation session"})
+			return
 		} else {
 			// Fallback to token claim if auth-service is unavailable
-			if isAdmin, ok := claims["is_admin"].(bool); ok {
-				c.Set(ContextIsAdmin, isAdmin)
-			} else {
-				c.Set(ContextIsAdmin, false)
-			}
+			c.Set(ContextPermissions, permissionsFromClaims(claims))
 		}
 
+		if actorID != 0 {
+			impersonationGuard(c, actorID, userID, sid)
+			return
+		}
 		c.Next()
 	}
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// APIKeyHeader - альтернатива "Authorization: Bearer ak_..." для персональных API-ключей
+const APIKeyHeader = "X-API-Key"
+
+const apiKeyPrefix = "ak_"
+
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(token, apiKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey проверяет ключ в auth-service. Права ограничены scopes ключа,
+// поэтому без ответа auth-service запрос отклоняется, а не пропускается по claims.
+func authenticateAPIKey(c *gin.Context, key string) {
+	access, err := clients.NewAuthClient().VerifyAPIKey(key)
+	if errors.Is(err, clients.ErrInvalidAPIKey) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		return
+	}
+	if errors.Is(err, clients.ErrUserSuspended) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is suspended or deleted"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify api key"})
+		return
+	}
+	c.Set(ContextUserID, access.ID)
+	c.Set(ContextPermissions, access.Permissions)
+	c.Next()
+}
+
+// RequirePermission пропускает запрос, только если у пользователя есть право perm.
+// Ставится после AuthMiddleware.
+func RequirePermission(perm string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		isAdmin, exists := c.Get(ContextIsAdmin)
-		if !exists || !isAdmin.(bool) {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin only"})
+		if !HasPermission(c, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission required: " + perm})
 			return
 		}
 		c.Next()
 	}
 }
 
+func HasPermission(c *gin.Context, perm string) bool {
+	raw, _ := c.Get(ContextPermissions)
+	perms, _ := raw.([]string)
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func permissionsFromClaims(claims jwt.MapClaims) []string {
+	var perms []string
+	if list, ok := claims["permissions"].([]interface{}); ok {
+		for _, p := range list {
+			if s, ok := p.(string); ok {
+				perms = append(perms, s)
+			}
+		}
+	}
+	return perms
+}
 
+// InternalOnly пропускает только межсервисные запросы с корректным X-Internal-Token.
+// Если токен не задан, все запросы отклоняются.
+func InternalOnly(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader("X-Internal-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid internal token"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -10,8 +10,9 @@ import (
 
 func SetupRoutes(r *gin.Engine, cfg *config.Config) {
 	authMiddleware := middleware.AuthMiddleware(cfg)
-	adminMiddleware := middleware.AdminMiddleware()
+	adminMiddleware := middleware.RequirePermission(middleware.PermContactsManage)
 	
 	handlers.RegisterContactRoutes(r, cfg, authMiddleware, adminMiddleware)
 	handlers.RegisterLogRoutes(r)
+	handlers.RegisterInternalRoutes(r, middleware.InternalOnly(cfg.InternalToken))
 }
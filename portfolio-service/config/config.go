@@ -6,8 +6,9 @@ import (
 )
 
 type Config struct {
-	DBPath    string
-	JWTSecret string
+	DBPath        string
+	JWTSecret     string
+	InternalToken string
 }
 
 func LoadConfig() Config {
@@ -22,7 +23,8 @@ func LoadConfig() Config {
 	}
 
 	return Config{
-		DBPath:    dbPath,
-		JWTSecret: jwtSecret,
+		DBPath:        dbPath,
+		JWTSecret:     jwtSecret,
+		InternalToken: os.Getenv("INTERNAL_API_TOKEN"),
 	}
 }
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"portfolio-service/db"
+	"portfolio-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterInternalRoutes - межсервисные эндпоинты, защищённые X-Internal-Token
+func RegisterInternalRoutes(r *gin.Engine, internalMiddleware gin.HandlerFunc) {
+	internal := r.Group("/api/internal")
+	internal.Use(internalMiddleware)
+
+	// Выгрузка: в портфолио пользователь встречается только в журнале
+	internal.GET("/users/:id/export", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		logs := []models.Log{}
+		if err := db.DB.Where("user_id = ?", id).Order("id").Find(&logs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"logs": logs})
+	})
+
+	// Обезличивание: в портфолио пользователь встречается только в журнале
+	internal.POST("/users/:id/anonymize", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		if err := db.DB.Model(&models.Log{}).Where("user_id = ?", id).Update("user_id", nil).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"anonymized": true})
+	})
+}
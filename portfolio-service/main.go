@@ -20,7 +20,7 @@ func main() {
 	db.InitDB(cfg.DBPath)
 
 	r := gin.Default()
-	routes.SetupRoutes(r)
+	routes.SetupRoutes(r, cfg)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -1,12 +1,14 @@
 package routes
 
 import (
+	"portfolio-service/config"
 	"portfolio-service/handlers"
 	"portfolio-service/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine) {
-	handlers.RegisterPortfolioRoutes(r, middleware.AuthMiddleware(), middleware.AdminMiddleware())
+func SetupRoutes(r *gin.Engine, cfg config.Config) {
+	handlers.RegisterPortfolioRoutes(r, middleware.AuthMiddleware(), middleware.RequirePermission(middleware.PermPortfolioManage))
+	handlers.RegisterInternalRoutes(r, middleware.InternalOnly(cfg.InternalToken))
 }
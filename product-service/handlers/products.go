@@ -28,7 +28,7 @@ type updateProductRequest struct {
 
 func RegisterProductRoutes(r *gin.Engine) {
 	admin := r.Group("/api/products")
-	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware()) // JWT проверка через Auth Service
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(middleware.PermProductsManage)) // JWT проверка через Auth Service
 
 	admin.POST("", CreateProduct)
 	admin.GET("", ListProducts)
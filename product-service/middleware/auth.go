@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"ooolalex/product-service/clients"
 	"ooolalex/product-service/config"
@@ -10,8 +11,18 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const ContextPermissions = "permissions"
+
+// PermProductsManage - создание, изменение и удаление товаров
+const PermProductsManage = "products.manage"
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		cfg := config.LoadConfig()
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -48,31 +59,105 @@ func AuthMiddleware() gin.HandlerFunc {
 		userID := uint(userIDFloat)
 		c.Set("userID", userID)
 		
-		// Check role from auth-service
+		// Resolve permissions from auth-service
 		authClient := clients.NewAuthClient()
-		role, err := authClient.GetUserRole(userID)
+		sid, _ := claims["sid"].(string)
+		access, err := authClient.GetUserAccess(userID, sid)
+		if errors.Is(err, clients.ErrSessionRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			return
+		}
+		if errors.Is(err, clients.ErrUserSuspended) || errors.Is(err, clients.ErrUserNotFound) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is suspended or deleted"})
+			return
+		}
+		actorID := actorFromClaims(claims)
 		if err == nil {
-			c.Set("isAdmin", role == "admin")
+			c.Set(ContextPermissions, access.Permissions)
+		} else if actorID != 0 {
+			// токен имперсонации без проверки его сессии не принимается
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify impersonation session"})
+			return
 		} else {
 			// Fallback to token claim if auth-service is unavailable
-			if isAdmin, ok := claims["is_admin"].(bool); ok {
-				c.Set("isAdmin", isAdmin)
-			} else {
-				c.Set("isAdmin", false)
-			}
+			c.Set(ContextPermissions, permissionsFromClaims(claims))
+		}
+
+		if actorID != 0 {
+			impersonationGuard(c, actorID, userID, sid)
+			return
 		}
-		
 		c.Next()
 	}
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// APIKeyHeader - альтернатива "Authorization: Bearer ak_..." для персональных API-ключей
+const APIKeyHeader = "X-API-Key"
+
+const apiKeyPrefix = "ak_"
+
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(token, apiKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey проверяет ключ в auth-service. Права ограничены scopes ключа,
+// поэтому без ответа auth-service запрос отклоняется, а не пропускается по claims.
+func authenticateAPIKey(c *gin.Context, key string) {
+	access, err := clients.NewAuthClient().VerifyAPIKey(key)
+	if errors.Is(err, clients.ErrInvalidAPIKey) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		return
+	}
+	if errors.Is(err, clients.ErrUserSuspended) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is suspended or deleted"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify api key"})
+		return
+	}
+	c.Set("userID", access.ID)
+	c.Set(ContextPermissions, access.Permissions)
+	c.Next()
+}
+
+// RequirePermission пропускает запрос, только если у пользователя есть право perm.
+// Ставится после AuthMiddleware.
+func RequirePermission(perm string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		isAdmin, exists := c.Get("isAdmin")
-		if !exists || !isAdmin.(bool) {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin only"})
+		if !HasPermission(c, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission required: " + perm})
 			return
 		}
 		c.Next()
 	}
 }
+
+func HasPermission(c *gin.Context, perm string) bool {
+	raw, _ := c.Get(ContextPermissions)
+	perms, _ := raw.([]string)
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func permissionsFromClaims(claims jwt.MapClaims) []string {
+	var perms []string
+	if list, ok := claims["permissions"].([]interface{}); ok {
+		for _, p := range list {
+			if s, ok := p.(string); ok {
+				perms = append(perms, s)
+			}
+		}
+	}
+	return perms
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"ooolalex/product-service/clients"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ContextActorID - администратор, действующий от имени пользователя (claim "act" токена имперсонации)
+const ContextActorID = "actorID"
+
+const serviceName = "product-service"
+
+// actorFromClaims - id администратора из claim "act", 0 для обычного токена
+func actorFromClaims(claims jwt.MapClaims) uint {
+	act, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	sub, _ := act["sub"].(float64)
+	return uint(sub)
+}
+
+// impersonationGuard запрещает удаление во время имперсонации и отправляет каждый запрос,
+// в том числе отклонённый, в журнал auth-service. Если auth-service недоступен,
+// действие с обеими личностями остаётся в логе сервиса.
+func impersonationGuard(c *gin.Context, actorID, userID uint, sid string) {
+	c.Set(ContextActorID, actorID)
+	if c.Request.Method == http.MethodDelete {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+	} else {
+		c.Next()
+	}
+	action := clients.ImpersonatedAction{
+		SID:     sid,
+		ActorID: actorID,
+		UserID:  userID,
+		Service: serviceName,
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+		Status:  c.Writer.Status(),
+		IP:      c.ClientIP(),
+	}
+	go func() {
+		if err := clients.NewAuthClient().RecordImpersonatedAction(action); err != nil {
+			log.Printf("impersonation: actor %d as user %d %s %s -> %d: failed to record action: %v",
+				action.ActorID, action.UserID, action.Method, action.Path, action.Status, err)
+		}
+	}()
+}
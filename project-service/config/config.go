@@ -2,12 +2,44 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	DBPath string
+	// InternalToken - секрет для межсервисных вызовов (X-Internal-Token)
+	InternalToken string
+
+	// Сколько автор может править и удалять свой комментарий
+	CommentEditWindow   time.Duration
+	CommentDeleteWindow time.Duration
+
+	// Кому передавать проекты удалённого пользователя; 0 - проекты архивируются
+	ReassignProjectsTo uint
+
+	// Проверка сроков: как часто, за сколько до срока напоминать
+	// и какой горизонт по умолчанию у отчёта о проектах под угрозой
+	DeadlineCheckInterval time.Duration
+	DueSoonWindow         time.Duration
+	AtRiskWindow          time.Duration
+
+	// Notifier - log или webhook (NotifyWebhookURL)
+	Notifier         string
+	NotifyWebhookURL string
+
+	// Вложения: хранилище, ограничения размера и подписанные ссылки на скачивание
+	StorageBackend       string
+	AttachmentsDir       string
+	AttachmentMaxSize    int64 // байт на файл
+	AttachmentQuota      int64 // байт на проект
+	AttachmentLinkTTL    time.Duration
+	AttachmentSigningKey []byte // обязателен: без ключа сервис не запускается
+
+	// Валюта ставок и сумм в отчётах по времени
+	BillingCurrency string
 }
 
 func LoadConfig() Config {
@@ -15,6 +47,49 @@ func LoadConfig() Config {
 	_ = godotenv.Load(".env")
 
 	return Config{
-		DBPath: os.Getenv("DB_PATH"),
+		DBPath:              os.Getenv("DB_PATH"),
+		InternalToken:       os.Getenv("INTERNAL_API_TOKEN"),
+		CommentEditWindow:   minutesEnv("COMMENT_EDIT_WINDOW_MINUTES", 15),
+		CommentDeleteWindow: minutesEnv("COMMENT_DELETE_WINDOW_MINUTES", 15),
+		ReassignProjectsTo:  uintEnv("REASSIGN_PROJECTS_TO_USER_ID", 0),
+
+		DeadlineCheckInterval: minutesEnv("DEADLINE_CHECK_INTERVAL_MINUTES", 60),
+		DueSoonWindow:         time.Duration(uintEnv("DUE_SOON_DAYS", 2)) * 24 * time.Hour,
+		AtRiskWindow:          time.Duration(uintEnv("AT_RISK_DAYS", 7)) * 24 * time.Hour,
+
+		Notifier:         os.Getenv("NOTIFIER"),
+		NotifyWebhookURL: os.Getenv("NOTIFY_WEBHOOK_URL"),
+
+		StorageBackend:       os.Getenv("STORAGE_BACKEND"),
+		AttachmentsDir:       stringEnv("ATTACHMENTS_DIR", "attachments"),
+		AttachmentMaxSize:    int64(uintEnv("ATTACHMENT_MAX_FILE_MB", 25)) << 20,
+		AttachmentQuota:      int64(uintEnv("ATTACHMENT_QUOTA_MB", 200)) << 20,
+		AttachmentLinkTTL:    minutesEnv("ATTACHMENT_LINK_TTL_MINUTES", 15),
+		AttachmentSigningKey: []byte(os.Getenv("ATTACHMENT_SIGNING_KEY")),
+
+		BillingCurrency: stringEnv("BILLING_CURRENCY", "RUB"),
+	}
+}
+
+func minutesEnv(key string, def int) time.Duration {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n < 0 {
+		n = def
+	}
+	return time.Duration(n) * time.Minute
+}
+
+func uintEnv(key string, def uint) uint {
+	n, err := strconv.ParseUint(os.Getenv(key), 10, 64)
+	if err != nil {
+		return def
+	}
+	return uint(n)
+}
+
+func stringEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return def
 }
@@ -16,7 +16,84 @@ func InitDB(dbPath string) {
 	if err != nil {
 		log.Fatal("failed to connect database:", err)
 	}
-	if err := DB.AutoMigrate(&models.Project{}, &models.Log{}); err != nil {
+	if err := DB.AutoMigrate(models.All()...); err != nil {
 		log.Fatal("failed to migrate database:", err)
 	}
+	if err := normalizeStatuses(DB); err != nil {
+		log.Fatal("failed to normalize project statuses:", err)
+	}
+	if err := normalizeBoard(DB); err != nil {
+		log.Fatal("failed to prepare boards:", err)
+	}
+}
+
+// normalizeStatuses приводит статусы, сохранённые до появления правил переходов,
+// к известным значениям, согласованным с прогрессом
+func normalizeStatuses(db *gorm.DB) error {
+	var projects []models.Project
+	if err := db.Find(&projects).Error; err != nil {
+		return err
+	}
+	for _, p := range projects {
+		status := models.NormalizeStatus(p.Status, p.Progress)
+		if status == p.Status {
+			continue
+		}
+		if err := db.Model(&models.Project{}).Where("id = ?", p.ID).Update("status", status).Error; err != nil {
+			return err
+		}
+		log.Printf("project %d: status %q changed to %q", p.ID, p.Status, status)
+	}
+	return nil
+}
+
+// normalizeBoard согласует статусы задач с отметкой done и расставляет позиции на досках
+// записям, созданным до их появления: карточки без позиции встают в конец колонки по id
+func normalizeBoard(db *gorm.DB) error {
+	if err := db.Model(&models.Task{}).Where("done = ? AND status <> ?", true, models.TaskDone).
+		UpdateColumn("status", models.TaskDone).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.Task{}).Where("done = ? AND status = ?", false, models.TaskDone).
+		UpdateColumn("status", models.TaskInProgress).Error; err != nil {
+		return err
+	}
+
+	var columns []struct {
+		ProjectID uint
+		Status    string
+	}
+	if err := db.Model(&models.Project{}).Distinct("status").Where("rank = ''").Scan(&columns).Error; err != nil {
+		return err
+	}
+	for _, col := range columns {
+		if err := rerankColumn(db.Model(&models.Project{}).Where("status = ?", col.Status)); err != nil {
+			return err
+		}
+	}
+	columns = nil
+	if err := db.Model(&models.Task{}).Distinct("project_id", "status").Where("rank = ''").Scan(&columns).Error; err != nil {
+		return err
+	}
+	for _, col := range columns {
+		if err := rerankColumn(db.Model(&models.Task{}).Where("project_id = ? AND status = ?", col.ProjectID, col.Status)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rerankColumn заново расставляет колонку равномерными позициями, сохраняя текущий порядок
+func rerankColumn(column *gorm.DB) error {
+	var ids []uint
+	if err := column.Session(&gorm.Session{}).Order("rank = '', rank, id").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	ranks := models.RankSequence(len(ids))
+	for i, id := range ids {
+		if err := column.Session(&gorm.Session{}).Where("id = ?", id).UpdateColumn("rank", ranks[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
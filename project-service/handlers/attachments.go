@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"ooolalex/project-service/config"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+	"ooolalex/project-service/storage"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// attachmentHandler - файлы проектов и задач. Права те же, что у комментариев:
+// администраторы видят все файлы, владелец проекта - только видимые клиенту.
+// Скачивание - по подписанной ссылке с ограниченным сроком действия; ссылка, выданная
+// владельцу, перестаёт работать, если файл стал внутренним.
+type attachmentHandler struct {
+	store   storage.Storage
+	maxSize int64
+	quota   int64
+	linkTTL time.Duration
+	signKey []byte
+}
+
+var (
+	errInvalidSignature = errors.New("invalid signature")
+	errLinkExpired      = errors.New("link expired")
+	errQuotaExceeded    = errors.New("project attachment quota exceeded")
+)
+
+// files задаётся в RegisterProjectRoutes; нужен также карточке и удалению проекта
+var files *attachmentHandler
+
+type updateAttachmentRequest struct {
+	Visibility models.CommentVisibility `json:"visibility" binding:"required"`
+}
+
+// attachmentView - вложение со ссылкой на скачивание
+type attachmentView struct {
+	models.Attachment
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func registerAttachmentRoutes(r *gin.Engine, admin, me *gin.RouterGroup, cfg config.Config, store storage.Storage) {
+	files = &attachmentHandler{
+		store:   store,
+		maxSize: cfg.AttachmentMaxSize,
+		quota:   cfg.AttachmentQuota,
+		linkTTL: cfg.AttachmentLinkTTL,
+		signKey: cfg.AttachmentSigningKey,
+	}
+	h := files
+
+	admin.GET(":id/attachments", adminScope(h.list))
+	admin.POST(":id/attachments", adminScope(h.upload))
+	admin.GET(":id/tasks/:taskId/attachments", adminScope(h.list))
+	admin.POST(":id/tasks/:taskId/attachments", adminScope(h.upload))
+	admin.PATCH(":id/attachments/:attachmentId", adminScope(h.update))
+	admin.DELETE(":id/attachments/:attachmentId", adminScope(h.delete))
+
+	me.GET("/:id/attachments", ownerScope(h.list))
+	me.POST("/:id/attachments", ownerScope(h.upload))
+	me.GET("/:id/tasks/:taskId/attachments", ownerScope(h.list))
+	me.POST("/:id/tasks/:taskId/attachments", ownerScope(h.upload))
+	me.DELETE("/:id/attachments/:attachmentId", ownerScope(h.delete))
+
+	// ссылка сама по себе даёт доступ, поэтому токен не нужен
+	r.GET("/api/attachments/:attachmentId/download", h.download)
+}
+
+func (h *attachmentHandler) list(c *gin.Context, project *models.Project, admin bool) {
+	q := db.DB.Where("project_id = ?", project.ID)
+	if taskID, ok := taskParam(c, project); !ok {
+		return
+	} else if taskID != nil {
+		q = q.Where("task_id = ?", *taskID)
+	}
+	if !admin {
+		q = q.Where("visibility = ?", models.CommentClient)
+	}
+	var attachments []models.Attachment
+	if err := q.Order("id").Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch attachments"})
+		return
+	}
+	used, err := h.usage(db.DB, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch attachments"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":       h.views(attachments, admin),
+		"used_bytes":  used,
+		"quota_bytes": h.quota,
+	})
+}
+
+// upload принимает multipart/form-data: file, необязательные visibility и task_id
+func (h *attachmentHandler) upload(c *gin.Context, project *models.Project, admin bool) {
+	if project.Status == models.ProjectCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "project is cancelled"})
+		return
+	}
+	// запас на заголовки multipart
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+1<<20)
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && header.Size > h.maxSize) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file is too large", "max_bytes": h.maxSize})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	taskID, ok := taskParam(c, project)
+	if !ok {
+		return
+	}
+	if v := c.PostForm("task_id"); taskID == nil && v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task_id"})
+			return
+		}
+		formTask := uint(id)
+		if !taskInProject(c, project, formTask) {
+			return
+		}
+		taskID = &formTask
+	}
+	visibility, ok := visibilityParam(c, models.CommentVisibility(c.PostForm("visibility")), admin, "only admins can upload internal files")
+	if !ok {
+		return
+	}
+
+	// предварительная проверка, чтобы не сохранять файл сверх квоты; окончательная - при записи
+	used, err := h.usage(db.DB, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot upload attachment"})
+		return
+	}
+	if used+header.Size > h.quota {
+		h.quotaExceeded(c, used)
+		return
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read file"})
+		return
+	}
+	defer src.Close()
+
+	key, err := storageKey(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot upload attachment"})
+		return
+	}
+	size, err := h.store.Put(key, src)
+	if err != nil {
+		log.Printf("attachment upload for project %d: %v", project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot upload attachment"})
+		return
+	}
+
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	attachment := models.Attachment{
+		ProjectID:   project.ID,
+		TaskID:      taskID,
+		UploadedBy:  c.MustGet(middleware.ContextUserID).(uint),
+		FileName:    fileName(header.Filename),
+		ContentType: contentType,
+		Size:        size,
+		Key:         key,
+		Visibility:  visibility,
+	}
+	// квоту проверяем в одной транзакции с записью, чтобы параллельные загрузки её не превысили
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if used, err = h.usage(tx, project.ID); err != nil {
+			return err
+		}
+		if used+size > h.quota {
+			return errQuotaExceeded
+		}
+		return tx.Create(&attachment).Error
+	})
+	if err != nil {
+		h.removeObjects([]string{key})
+		if errors.Is(err, errQuotaExceeded) {
+			h.quotaExceeded(c, used)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot upload attachment"})
+		return
+	}
+	c.JSON(http.StatusCreated, h.view(attachment, admin))
+}
+
+// update меняет видимость файла (только администраторы)
+func (h *attachmentHandler) update(c *gin.Context, project *models.Project, admin bool) {
+	attachment, ok := findAttachment(c, project, admin)
+	if !ok {
+		return
+	}
+	var req updateAttachmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	visibility, ok := visibilityParam(c, req.Visibility, admin, "only admins can upload internal files")
+	if !ok {
+		return
+	}
+	attachment.Visibility = visibility
+	if err := db.DB.Save(attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update attachment"})
+		return
+	}
+	c.JSON(http.StatusOK, h.view(*attachment, admin))
+}
+
+// delete - владелец проекта удаляет только загруженные им файлы, администратор - любые
+func (h *attachmentHandler) delete(c *gin.Context, project *models.Project, admin bool) {
+	attachment, ok := findAttachment(c, project, admin)
+	if !ok {
+		return
+	}
+	if !admin && attachment.UploadedBy != c.MustGet(middleware.ContextUserID).(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the uploader can delete a file"})
+		return
+	}
+	if err := db.DB.Delete(attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete attachment"})
+		return
+	}
+	h.removeObjects([]string{attachment.Key})
+	c.JSON(http.StatusOK, gin.H{"message": "attachment deleted"})
+}
+
+// download отдаёт файл по подписанной ссылке из attachmentView.DownloadURL
+func (h *attachmentHandler) download(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("attachmentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": errInvalidSignature.Error()})
+		return
+	}
+	scope := models.CommentVisibility(c.Query("scope"))
+	err = h.verify(uint(id), expires, scope, c.Query("sig"), time.Now())
+	if errors.Is(err, errLinkExpired) {
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// видимость проверяем заново: файл могли сделать внутренним после выдачи ссылки владельцу
+	q := db.DB.Where("id = ?", id)
+	if scope != models.CommentInternal {
+		q = q.Where("visibility = ?", models.CommentClient)
+	}
+	var attachment models.Attachment
+	if err := q.First(&attachment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+	rc, err := h.store.Open(attachment.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot read attachment"})
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, rc, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// usage - сколько байт занимают файлы проекта
+func (h *attachmentHandler) usage(tx *gorm.DB, projectID uint) (int64, error) {
+	var used int64
+	err := tx.Model(&models.Attachment{}).Where("project_id = ?", projectID).
+		Select("COALESCE(SUM(size), 0)").Scan(&used).Error
+	return used, err
+}
+
+func (h *attachmentHandler) quotaExceeded(c *gin.Context, used int64) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":       errQuotaExceeded.Error(),
+		"used_bytes":  used,
+		"quota_bytes": h.quota,
+	})
+}
+
+// sign подписывает ссылку; scope - какие файлы доступны получателю ссылки:
+// internal - администратору любые, client - владельцу только видимые клиенту
+func (h *attachmentHandler) sign(id uint, expires int64, scope models.CommentVisibility) string {
+	mac := hmac.New(sha256.New, h.signKey)
+	fmt.Fprintf(mac, "%d:%d:%s", id, expires, scope)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify проверяет подпись и срок ссылки
+func (h *attachmentHandler) verify(id uint, expires int64, scope models.CommentVisibility, sig string, now time.Time) error {
+	if !hmac.Equal([]byte(sig), []byte(h.sign(id, expires, scope))) {
+		return errInvalidSignature
+	}
+	if now.Unix() > expires {
+		return errLinkExpired
+	}
+	return nil
+}
+
+func (h *attachmentHandler) view(a models.Attachment, admin bool) attachmentView {
+	scope := models.CommentClient
+	if admin {
+		scope = models.CommentInternal
+	}
+	expires := time.Now().Add(h.linkTTL).Truncate(time.Second)
+	return attachmentView{
+		Attachment: a,
+		DownloadURL: fmt.Sprintf("/api/attachments/%d/download?expires=%d&scope=%s&sig=%s",
+			a.ID, expires.Unix(), scope, h.sign(a.ID, expires.Unix(), scope)),
+		ExpiresAt: expires,
+	}
+}
+
+func (h *attachmentHandler) views(attachments []models.Attachment, admin bool) []attachmentView {
+	out := make([]attachmentView, len(attachments))
+	for i, a := range attachments {
+		out[i] = h.view(a, admin)
+	}
+	return out
+}
+
+// removeObjects удаляет содержимое файлов из хранилища после удаления записей;
+// ошибки только пишутся в лог, записей о файлах уже нет
+func (h *attachmentHandler) removeObjects(keys []string) {
+	for _, key := range keys {
+		if err := h.store.Delete(key); err != nil {
+			log.Printf("cannot delete stored attachment %s: %v", key, err)
+		}
+	}
+}
+
+// loadAttachments - файлы проекта; без admin только видимые клиенту
+func loadAttachments(projectID uint, admin bool) ([]models.Attachment, error) {
+	q := db.DB.Where("project_id = ?", projectID)
+	if !admin {
+		q = q.Where("visibility = ?", models.CommentClient)
+	}
+	var attachments []models.Attachment
+	err := q.Order("id").Find(&attachments).Error
+	return attachments, err
+}
+
+// findAttachment ищет файл проекта; владельцу внутренние файлы не видны
+func findAttachment(c *gin.Context, project *models.Project, admin bool) (*models.Attachment, bool) {
+	q := db.DB.Where("id = ? AND project_id = ?", c.Param("attachmentId"), project.ID)
+	if !admin {
+		q = q.Where("visibility = ?", models.CommentClient)
+	}
+	var attachment models.Attachment
+	if err := q.First(&attachment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return nil, false
+	}
+	return &attachment, true
+}
+
+// storageKey - случайный ключ файла в хранилище; имя файла в ключ не попадает
+func storageKey(projectID uint) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("projects/%d/%s", projectID, hex.EncodeToString(b)), nil
+}
+
+func fileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if r := []rune(name); len(r) > 255 {
+		name = string(r[len(r)-255:])
+	}
+	return name
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+	"ooolalex/project-service/storage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAttachmentSignature(t *testing.T) {
+	h := &attachmentHandler{signKey: []byte("test-key")}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(15 * time.Minute).Unix()
+	sig := h.sign(7, expires, models.CommentClient)
+
+	tests := []struct {
+		name    string
+		h       *attachmentHandler
+		id      uint
+		expires int64
+		scope   models.CommentVisibility
+		sig     string
+		now     time.Time
+		wantErr error
+	}{
+		{"Действующая ссылка", h, 7, expires, models.CommentClient, sig, now, nil},
+		{"В последнюю секунду", h, 7, expires, models.CommentClient, sig, time.Unix(expires, 0), nil},
+		{"Истёкшая ссылка", h, 7, expires, models.CommentClient, sig, time.Unix(expires+1, 0), errLinkExpired},
+		{"Изменена подпись", h, 7, expires, models.CommentClient, strings.Repeat("0", len(sig)), now, errInvalidSignature},
+		{"Пустая подпись", h, 7, expires, models.CommentClient, "", now, errInvalidSignature},
+		{"Другой файл", h, 8, expires, models.CommentClient, sig, now, errInvalidSignature},
+		{"Продлённый срок", h, 7, expires + 3600, models.CommentClient, sig, now, errInvalidSignature},
+		{"Расширенный доступ", h, 7, expires, models.CommentInternal, sig, now, errInvalidSignature},
+		{"Другой ключ", &attachmentHandler{signKey: []byte("other-key")}, 7, expires, models.CommentClient, sig, now, errInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.h.verify(tt.id, tt.expires, tt.scope, tt.sig, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Ожидалась ошибка %v, получено %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAttachmentDownloadRechecksVisibility(t *testing.T) {
+	setupTestDB(t)
+	store := &storage.LocalStorage{Dir: t.TempDir()}
+	h := &attachmentHandler{store: store, linkTTL: time.Minute, signKey: []byte("test-key")}
+
+	if _, err := store.Put("projects/1/brief", strings.NewReader("brief")); err != nil {
+		t.Fatal(err)
+	}
+	attachment := models.Attachment{ProjectID: 1, UploadedBy: 1, FileName: "brief.txt", ContentType: "text/plain", Size: 5, Key: "projects/1/brief", Visibility: models.CommentClient}
+	if err := db.DB.Create(&attachment).Error; err != nil {
+		t.Fatal(err)
+	}
+	ownerURL := h.view(attachment, false).DownloadURL
+	adminURL := h.view(attachment, true).DownloadURL
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/attachments/:attachmentId/download", h.download)
+	get := func(url string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w.Code
+	}
+
+	if code := get(ownerURL); code != http.StatusOK {
+		t.Fatalf("Ссылка владельца: ожидался статус %d, получен %d", http.StatusOK, code)
+	}
+	if code := get(strings.Replace(ownerURL, "scope=client", "scope=internal", 1)); code != http.StatusForbidden {
+		t.Errorf("Подмена scope: ожидался статус %d, получен %d", http.StatusForbidden, code)
+	}
+
+	if err := db.DB.Model(&attachment).Update("visibility", models.CommentInternal).Error; err != nil {
+		t.Fatal(err)
+	}
+	if code := get(ownerURL); code != http.StatusNotFound {
+		t.Errorf("Файл стал внутренним: ожидался статус %d, получен %d", http.StatusNotFound, code)
+	}
+	if code := get(adminURL); code != http.StatusOK {
+		t.Errorf("Ссылка администратора: ожидался статус %d, получен %d", http.StatusOK, code)
+	}
+}
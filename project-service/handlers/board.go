@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/logs"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errVersionConflict - карточку уже изменил кто-то другой
+var errVersionConflict = errors.New("changed by someone else, reload the board")
+
+// moveRequest - новая колонка и место карточки между соседями в ней.
+// after_id - карточка выше, before_id - ниже; без соседей карточка встаёт в конец колонки.
+// version - версия карточки, которую видел клиент.
+type moveRequest struct {
+	Status   string `json:"status"`
+	AfterID  *uint  `json:"after_id"`
+	BeforeID *uint  `json:"before_id"`
+	Version  *int   `json:"version" binding:"required"`
+}
+
+type projectColumn struct {
+	Status models.ProjectStatus `json:"status"`
+	Total  int64                `json:"total"`
+	Items  []projectWithOwner   `json:"items"`
+}
+
+type taskColumn struct {
+	Status models.TaskStatus `json:"status"`
+	Total  int64             `json:"total"`
+	Items  []models.Task     `json:"items"`
+}
+
+func registerBoardRoutes(admin *gin.RouterGroup) {
+	admin.GET("board", ProjectBoard)
+	admin.POST(":id/move", MoveProject)
+	admin.GET(":id/board", TaskBoard)
+	admin.POST(":id/tasks/:taskId/move", MoveTask)
+}
+
+// ProjectBoard - проекты по колонкам статусов в порядке жизненного цикла, внутри колонки -
+// в порядке, расставленном на доске. Фильтры как у списка проектов; ?size ограничивает
+// число карточек в колонке (по умолчанию 50), total - сколько их всего.
+func ProjectBoard(c *gin.Context) {
+	f, err := parseProjectFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_, size := pageParams(c)
+	statuses := f.Statuses
+	if len(statuses) == 0 {
+		statuses = models.ProjectStatuses
+	}
+
+	counts := make([]int64, len(statuses))
+	var projects []models.Project
+	for i, status := range statuses {
+		q := f.where(db.DB.Model(&models.Project{})).Where("status = ?", status)
+		if err := q.Count(&counts[i]).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch board"})
+			return
+		}
+		var items []models.Project
+		if err := q.Order("rank, id").Limit(size).Find(&items).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch board"})
+			return
+		}
+		projects = append(projects, items...)
+	}
+
+	columns := make([]projectColumn, len(statuses))
+	index := map[models.ProjectStatus]int{}
+	for i, status := range statuses {
+		columns[i] = projectColumn{Status: status, Total: counts[i], Items: []projectWithOwner{}}
+		index[status] = i
+	}
+	for _, p := range withOwners(projects) {
+		col := &columns[index[p.Status]]
+		col.Items = append(col.Items, p)
+	}
+	c.JSON(http.StatusOK, gin.H{"columns": columns})
+}
+
+// TaskBoard - задачи проекта по колонкам todo, in_progress, review, done.
+// Фильтры: ?milestone_id и ?assignee_id (0 - задачи без этапа или исполнителя).
+func TaskBoard(c *gin.Context) {
+	var project models.Project
+	if err := db.DB.First(&project, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+	base := db.DB.Model(&models.Task{}).Where("project_id = ?", project.ID)
+	for _, param := range []string{"milestone_id", "assignee_id"} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+			return
+		}
+		if id == 0 {
+			base = base.Where(param + " IS NULL")
+		} else {
+			base = base.Where(param+" = ?", id)
+		}
+	}
+
+	columns := make([]taskColumn, len(models.TaskStatuses))
+	for i, status := range models.TaskStatuses {
+		columns[i] = taskColumn{Status: status, Items: []models.Task{}}
+		q := base.Session(&gorm.Session{}).Where("status = ?", status)
+		if err := q.Count(&columns[i].Total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch board"})
+			return
+		}
+		if err := q.Order("rank, id").Find(&columns[i].Items).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch board"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"project_id": project.ID, "columns": columns})
+}
+
+// MoveProject переносит проект в другую колонку и/или на другое место в ней.
+// Смена статуса проходит по правилам переходов и записывается в историю.
+func MoveProject(c *gin.Context) {
+	var project models.Project
+	if err := db.DB.First(&project, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+	var req moveRequest
+	if !bindMove(c, &req, project.ID) {
+		return
+	}
+	if *req.Version != project.Version {
+		c.JSON(http.StatusConflict, gin.H{"error": "project " + errVersionConflict.Error(), "current": project})
+		return
+	}
+
+	fromStatus, fromProgress := project.Status, project.Progress
+	status := project.Status
+	if req.Status != "" {
+		status = models.ProjectStatus(req.Status)
+	}
+	changed, err := project.ChangeState(status, project.Progress)
+	if errors.Is(err, models.ErrStatusChange) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": fromStatus, "allowed": fromStatus.Next()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.MustGet(middleware.ContextUserID).(uint)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		column := tx.Model(&models.Project{}).Where("status = ?", project.Status)
+		rank, err := models.PlaceRank(column, project.ID, req.AfterID, req.BeforeID)
+		if err != nil {
+			return err
+		}
+		res := tx.Model(&models.Project{}).Where("id = ? AND version = ?", project.ID, project.Version).Updates(map[string]interface{}{
+			"status":     project.Status,
+			"rank":       rank,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		if changed {
+			if err := recordHistory(tx, &project, fromStatus, fromProgress, userID, "moved on board"); err != nil {
+				return err
+			}
+		}
+		return tx.First(&project, project.ID).Error
+	})
+	if !moveSaved(c, err, &models.Project{}, project.ID, "project") {
+		return
+	}
+
+	if changed {
+		logs.SendLog(project.UserID, "Project status changed: "+project.Title)
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// MoveTask переносит задачу в другую колонку и/или на другое место в ней. Колонка done
+// отмечает задачу выполненной, уход из неё снова открывает задачу; прогресс проекта
+// пересчитывается в той же транзакции.
+func MoveTask(c *gin.Context) {
+	project, ok := openProject(c)
+	if !ok {
+		return
+	}
+	var t models.Task
+	if err := db.DB.Where("id = ? AND project_id = ?", c.Param("taskId"), project.ID).First(&t).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	var req moveRequest
+	if !bindMove(c, &req, t.ID) {
+		return
+	}
+	if req.Status != "" && !models.TaskStatus(req.Status).Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be todo, in_progress, review or done"})
+		return
+	}
+	if *req.Version != t.Version {
+		c.JSON(http.StatusConflict, gin.H{"error": "task " + errVersionConflict.Error(), "current": t})
+		return
+	}
+	if req.Status != "" {
+		t.SetStatus(models.TaskStatus(req.Status), time.Now())
+	}
+
+	userID := c.MustGet(middleware.ContextUserID).(uint)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		column := tx.Model(&models.Task{}).Where("project_id = ? AND status = ?", t.ProjectID, t.Status)
+		rank, err := models.PlaceRank(column, t.ID, req.AfterID, req.BeforeID)
+		if err != nil {
+			return err
+		}
+		res := tx.Model(&models.Task{}).Where("id = ? AND version = ?", t.ID, t.Version).Updates(map[string]interface{}{
+			"status":     t.Status,
+			"done":       t.Done,
+			"done_at":    t.DoneAt,
+			"rank":       rank,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		if err := syncProgress(tx, t.ProjectID, userID); err != nil {
+			return err
+		}
+		return tx.First(&t, t.ID).Error
+	})
+	if moveSaved(c, err, &models.Task{}, t.ID, "task") {
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// bindMove разбирает запрос перемещения; карточка не может быть соседом самой себя
+func bindMove(c *gin.Context, req *moveRequest, selfID uint) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if (req.AfterID != nil && *req.AfterID == selfID) || (req.BeforeID != nil && *req.BeforeID == selfID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "after_id and before_id must refer to other cards"})
+		return false
+	}
+	return true
+}
+
+// moveSaved отвечает на ошибку перемещения; при конфликте версий отдаёт текущее состояние карточки
+func moveSaved(c *gin.Context, err error, current interface{}, id uint, name string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errVersionConflict):
+		db.DB.First(current, id)
+		c.JSON(http.StatusConflict, gin.H{"error": name + " " + err.Error(), "current": current})
+	case errors.Is(err, models.ErrRankConflict), errors.Is(err, models.ErrStatusChange):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot move " + name})
+	}
+	return false
+}
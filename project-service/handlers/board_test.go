@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupBoardRouter - маршруты доски от имени администратора 1, без проверки токена
+func setupBoardRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	admin := r.Group("/api/projects", func(c *gin.Context) { c.Set(middleware.ContextUserID, uint(1)) })
+	registerBoardRoutes(admin)
+	return r
+}
+
+func TestMoveVersionConflict(t *testing.T) {
+	setupTestDB(t)
+	r := setupBoardRouter()
+
+	project := models.Project{UserID: 2, Title: "Лендинг"}
+	if err := db.DB.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	other := models.Project{UserID: 2, Title: "Магазин"}
+	if err := db.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	task := models.Task{ProjectID: project.ID, Title: "Макет"}
+	if err := db.DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	projectPath := fmt.Sprintf("/api/projects/%d/move", project.ID)
+	taskPath := fmt.Sprintf("/api/projects/%d/tasks/%d/move", project.ID, task.ID)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    gin.H
+		want    int
+		version int // ожидаемая версия карточки в ответе
+	}{
+		{"Проект: устаревшая версия", projectPath, gin.H{"version": project.Version - 1, "before_id": other.ID}, http.StatusConflict, project.Version},
+		{"Проект: будущая версия", projectPath, gin.H{"version": project.Version + 1, "before_id": other.ID}, http.StatusConflict, project.Version},
+		{"Проект: текущая версия", projectPath, gin.H{"version": project.Version, "before_id": other.ID}, http.StatusOK, project.Version + 1},
+		{"Проект: повтор того же запроса", projectPath, gin.H{"version": project.Version, "before_id": other.ID}, http.StatusConflict, project.Version + 1},
+		{"Задача: устаревшая версия", taskPath, gin.H{"version": task.Version - 1, "status": "in_progress"}, http.StatusConflict, task.Version},
+		{"Задача: текущая версия", taskPath, gin.H{"version": task.Version, "status": "in_progress"}, http.StatusOK, task.Version + 1},
+		{"Задача: повтор того же запроса", taskPath, gin.H{"version": task.Version, "status": "review"}, http.StatusConflict, task.Version + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodPost, tt.path, tt.body)
+			if w.Code != tt.want {
+				t.Fatalf("Ожидался статус %d, получен %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			var body struct {
+				Error   string `json:"error"`
+				Version int    `json:"version"`
+				Current *struct {
+					Version int `json:"version"`
+				} `json:"current"`
+			}
+			decode(t, w, &body)
+			version := body.Version
+			if tt.want == http.StatusConflict {
+				if body.Error == "" || body.Current == nil {
+					t.Fatalf("Конфликт должен возвращать ошибку и текущее состояние: %s", w.Body.String())
+				}
+				version = body.Current.Version
+			}
+			if version != tt.version {
+				t.Errorf("Версия: ожидалось %d, получено %d", tt.version, version)
+			}
+		})
+	}
+
+	// конфликт ничего не меняет: задача осталась в in_progress
+	var stored models.Task
+	if err := db.DB.First(&stored, task.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.TaskInProgress {
+		t.Errorf("Статус задачи: ожидалось %s, получено %s", models.TaskInProgress, stored.Status)
+	}
+}
+
+func TestMoveRequiresVersion(t *testing.T) {
+	setupTestDB(t)
+	r := setupBoardRouter()
+	project := models.Project{UserID: 2, Title: "Лендинг"}
+	if err := db.DB.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	w := doJSON(t, r, http.MethodPost, fmt.Sprintf("/api/projects/%d/move", project.ID), gin.H{"status": "in_progress"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Без version: ожидался статус %d, получен %d", http.StatusBadRequest, w.Code)
+	}
+}
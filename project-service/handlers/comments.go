@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"net/http"
+	"ooolalex/project-service/config"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// commentHandler - обсуждение проекта и задач. Администраторы (projects.manage) видят
+// все комментарии, владелец проекта - только видимые клиенту и пишет только такие.
+type commentHandler struct {
+	editWindow   time.Duration
+	deleteWindow time.Duration
+}
+
+type createCommentRequest struct {
+	Body       string                   `json:"body" binding:"required"`
+	Visibility models.CommentVisibility `json:"visibility"`
+	TaskID     *uint                    `json:"task_id"`
+}
+
+type updateCommentRequest struct {
+	Body       *string                   `json:"body"`
+	Visibility *models.CommentVisibility `json:"visibility"`
+}
+
+func registerCommentRoutes(admin, me *gin.RouterGroup, mentions *gin.RouterGroup, cfg config.Config) {
+	h := &commentHandler{editWindow: cfg.CommentEditWindow, deleteWindow: cfg.CommentDeleteWindow}
+
+	admin.GET(":id/comments", adminScope(h.list))
+	admin.POST(":id/comments", adminScope(h.create))
+	admin.GET(":id/tasks/:taskId/comments", adminScope(h.list))
+	admin.POST(":id/tasks/:taskId/comments", adminScope(h.create))
+	admin.PATCH(":id/comments/:commentId", adminScope(h.update))
+	admin.DELETE(":id/comments/:commentId", adminScope(h.delete))
+
+	me.GET("/:id/comments", ownerScope(h.list))
+	me.POST("/:id/comments", ownerScope(h.create))
+	me.GET("/:id/tasks/:taskId/comments", ownerScope(h.list))
+	me.POST("/:id/tasks/:taskId/comments", ownerScope(h.create))
+	me.PATCH("/:id/comments/:commentId", ownerScope(h.update))
+	me.DELETE("/:id/comments/:commentId", ownerScope(h.delete))
+
+	mentions.GET("", h.myMentions)
+}
+
+func (h *commentHandler) list(c *gin.Context, project *models.Project, admin bool) {
+	q := db.DB.Where("project_id = ?", project.ID)
+	if taskID, ok := taskParam(c, project); !ok {
+		return
+	} else if taskID != nil {
+		q = q.Where("task_id = ?", *taskID)
+	}
+	if !admin {
+		q = q.Where("visibility = ?", models.CommentClient)
+	}
+	comments := []models.Comment{}
+	if err := q.Order("id").Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch comments"})
+		return
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+func (h *commentHandler) create(c *gin.Context, project *models.Project, admin bool) {
+	var req createCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	taskID, ok := taskParam(c, project)
+	if !ok {
+		return
+	}
+	if taskID == nil && req.TaskID != nil {
+		if !taskInProject(c, project, *req.TaskID) {
+			return
+		}
+		taskID = req.TaskID
+	}
+	visibility, ok := visibilityParam(c, req.Visibility, admin, "only admins can post internal comments")
+	if !ok {
+		return
+	}
+	body, ok := commentBody(c, req.Body)
+	if !ok {
+		return
+	}
+
+	comment := models.Comment{
+		ProjectID:  project.ID,
+		TaskID:     taskID,
+		AuthorID:   c.MustGet(middleware.ContextUserID).(uint),
+		Body:       body,
+		Visibility: visibility,
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		return saveMentions(tx, &comment)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create comment"})
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
+
+// update - правка своего комментария в течение editWindow
+func (h *commentHandler) update(c *gin.Context, project *models.Project, admin bool) {
+	comment, ok := findComment(c, project, admin)
+	if !ok {
+		return
+	}
+	if comment.AuthorID != c.MustGet(middleware.ContextUserID).(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the author can edit a comment"})
+		return
+	}
+	if time.Since(comment.CreatedAt) > h.editWindow {
+		c.JSON(http.StatusForbidden, gin.H{"error": "edit window has expired"})
+		return
+	}
+	var req updateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Body != nil {
+		body, ok := commentBody(c, *req.Body)
+		if !ok {
+			return
+		}
+		comment.Body = body
+	}
+	if req.Visibility != nil {
+		visibility, ok := visibilityParam(c, *req.Visibility, admin, "only admins can post internal comments")
+		if !ok {
+			return
+		}
+		comment.Visibility = visibility
+	}
+	now := time.Now()
+	comment.EditedAt = &now
+	comment.Mentions = models.ParseMentions(comment.Body)
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(comment).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		return saveMentions(tx, comment)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update comment"})
+		return
+	}
+	c.JSON(http.StatusOK, comment)
+}
+
+// delete - автор удаляет свой комментарий в течение deleteWindow, администратор - любой и в любое время
+func (h *commentHandler) delete(c *gin.Context, project *models.Project, admin bool) {
+	comment, ok := findComment(c, project, admin)
+	if !ok {
+		return
+	}
+	if !admin {
+		if comment.AuthorID != c.MustGet(middleware.ContextUserID).(uint) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the author can delete a comment"})
+			return
+		}
+		if time.Since(comment.CreatedAt) > h.deleteWindow {
+			c.JSON(http.StatusForbidden, gin.H{"error": "delete window has expired"})
+			return
+		}
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(comment).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete comment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "comment deleted"})
+}
+
+// myMentions - комментарии, в которых упомянут пользователь. Без projects.manage
+// видны только комментарии для клиента в собственных проектах.
+func (h *commentHandler) myMentions(c *gin.Context) {
+	userID := c.MustGet(middleware.ContextUserID).(uint)
+
+	q := db.DB.Model(&models.Comment{}).
+		Joins("JOIN comment_mentions ON comment_mentions.comment_id = comments.id").
+		Where("comment_mentions.user_id = ?", userID)
+	if !middleware.HasPermission(c, middleware.PermProjectsManage) {
+		q = q.Joins("JOIN projects ON projects.id = comments.project_id").
+			Where("projects.user_id = ? AND comments.visibility = ?", userID, models.CommentClient)
+	}
+	comments := []models.Comment{}
+	if err := q.Order("comments.id desc").Limit(200).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch mentions"})
+		return
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+// taskParam разбирает :taskId, если он есть в маршруте, и проверяет, что задача из этого проекта
+func taskParam(c *gin.Context, project *models.Project) (*uint, bool) {
+	raw := c.Param("taskId")
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return nil, false
+	}
+	taskID := uint(id)
+	if !taskInProject(c, project, taskID) {
+		return nil, false
+	}
+	return &taskID, true
+}
+
+func taskInProject(c *gin.Context, project *models.Project, taskID uint) bool {
+	var count int64
+	db.DB.Model(&models.Task{}).Where("id = ? AND project_id = ?", taskID, project.ID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return false
+	}
+	return true
+}
+
+// findComment ищет комментарий проекта; владельцу внутренние комментарии не видны
+func findComment(c *gin.Context, project *models.Project, admin bool) (*models.Comment, bool) {
+	q := db.DB.Where("id = ? AND project_id = ?", c.Param("commentId"), project.ID)
+	if !admin {
+		q = q.Where("visibility = ?", models.CommentClient)
+	}
+	var comment models.Comment
+	if err := q.First(&comment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		return nil, false
+	}
+	return &comment, true
+}
+
+// visibilityParam - видимость комментария или файла: по умолчанию администратор создаёт
+// внутренние, владелец проекта - только видимые клиенту. denied - ответ владельцу на internal.
+func visibilityParam(c *gin.Context, v models.CommentVisibility, admin bool, denied string) (models.CommentVisibility, bool) {
+	switch {
+	case v == "" && admin:
+		return models.CommentInternal, true
+	case v == "" || v == models.CommentClient:
+		return models.CommentClient, true
+	case v == models.CommentInternal && admin:
+		return v, true
+	case v == models.CommentInternal:
+		c.JSON(http.StatusForbidden, gin.H{"error": denied})
+		return "", false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be client or internal"})
+		return "", false
+	}
+}
+
+func commentBody(c *gin.Context, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body is required"})
+		return "", false
+	}
+	if utf8.RuneCountInString(body) > models.MaxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body is too long"})
+		return "", false
+	}
+	return body, true
+}
+
+func saveMentions(tx *gorm.DB, comment *models.Comment) error {
+	comment.Mentions = models.ParseMentions(comment.Body)
+	for _, userID := range comment.Mentions {
+		if err := tx.Create(&models.CommentMention{CommentID: comment.ID, UserID: userID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Причины, по которым проект попадает в отчёт о проектах под угрозой
+const (
+	riskOverdue        = "overdue"         // срок проекта прошёл
+	riskDueSoon        = "due_soon"        // срок наступит в пределах горизонта отчёта
+	riskBehindSchedule = "behind_schedule" // прогресс отстаёт от ожидаемого по срокам
+	riskOverdueTasks   = "overdue_tasks"   // есть просроченные задачи
+)
+
+var closedStatuses = []models.ProjectStatus{models.ProjectDone, models.ProjectCancelled}
+
+type atRiskProject struct {
+	projectWithOwner
+	Risks            []string `json:"risks"`
+	DaysLeft         *int     `json:"days_left"`
+	ExpectedProgress *int     `json:"expected_progress"`
+	OverdueTasks     int64    `json:"overdue_tasks"`
+}
+
+// overdueProjects ограничивает q незавершёнными проектами с прошедшим сроком
+func overdueProjects(q *gorm.DB, now time.Time) *gorm.DB {
+	return q.Where("due_date < ? AND archived_at IS NULL AND status NOT IN ?", now, closedStatuses)
+}
+
+// overdueTasksQuery - невыполненные задачи с прошедшим сроком в незавершённых проектах
+func overdueTasksQuery(now time.Time) *gorm.DB {
+	return db.DB.Model(&models.Task{}).
+		Joins("JOIN projects ON projects.id = tasks.project_id").
+		Where("tasks.due_date < ? AND tasks.done = ? AND projects.archived_at IS NULL AND projects.status NOT IN ?",
+			now, false, closedStatuses)
+}
+
+// atRiskReport - незавершённые проекты, которые просрочены, скоро должны быть сданы,
+// отстают от графика или содержат просроченные задачи. ?days меняет горизонт «скоро».
+func atRiskReport(defaultWindow time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		window := defaultWindow
+		if v := c.Query("days"); v != "" {
+			days, err := strconv.Atoi(v)
+			if err != nil || days < 0 || days > 365 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 0 and 365"})
+				return
+			}
+			window = time.Duration(days) * 24 * time.Hour
+		}
+		now := time.Now()
+
+		var counts []struct {
+			ProjectID uint
+			Count     int64
+		}
+		if err := overdueTasksQuery(now).Select("tasks.project_id, count(*) as count").
+			Group("tasks.project_id").Scan(&counts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot build report"})
+			return
+		}
+		overdueTasks := map[uint]int64{}
+		ids := []uint{}
+		for _, r := range counts {
+			overdueTasks[r.ProjectID] = r.Count
+			ids = append(ids, r.ProjectID)
+		}
+
+		var projects []models.Project
+		if err := db.DB.Where("archived_at IS NULL AND status NOT IN ?", closedStatuses).
+			Where(db.DB.Where("due_date IS NOT NULL").Or("id IN ?", ids)).
+			Find(&projects).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot build report"})
+			return
+		}
+
+		risky := []models.Project{}
+		risks := map[uint][]string{}
+		for i := range projects {
+			p := &projects[i]
+			var reasons []string
+			switch {
+			case p.Overdue(now):
+				reasons = append(reasons, riskOverdue)
+			case p.DueSoon(now, window):
+				reasons = append(reasons, riskDueSoon)
+			}
+			if expected, ok := p.ExpectedProgress(now); ok && !p.Overdue(now) && p.Progress+models.ScheduleTolerance < expected {
+				reasons = append(reasons, riskBehindSchedule)
+			}
+			if overdueTasks[p.ID] > 0 {
+				reasons = append(reasons, riskOverdueTasks)
+			}
+			if len(reasons) > 0 {
+				risky = append(risky, *p)
+				risks[p.ID] = reasons
+			}
+		}
+
+		items := make([]atRiskProject, 0, len(risky))
+		for _, p := range withOwners(risky) {
+			item := atRiskProject{projectWithOwner: p, Risks: risks[p.ID], OverdueTasks: overdueTasks[p.ID]}
+			if p.DueDate != nil {
+				days := int(p.DueDate.Sub(now).Hours() / 24) // целые сутки до срока, после срока - отрицательные
+				item.DaysLeft = &days
+			}
+			if expected, ok := p.ExpectedProgress(now); ok {
+				item.ExpectedProgress = &expected
+			}
+			items = append(items, item)
+		}
+		// сначала ближайшие сроки, проекты без срока - в конце
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i].DueDate, items[j].DueDate
+			if a == nil || b == nil {
+				return a != nil
+			}
+			return a.Before(*b)
+		})
+		c.JSON(http.StatusOK, items)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAtRiskReport(t *testing.T) {
+	setupTestDB(t)
+	// владельцев нет в auth-service: отчёт всё равно строится, owner остаётся null
+	stubAuthService(t, nil)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/projects/at-risk", atRiskReport(7*24*time.Hour))
+
+	now := time.Now()
+	day := func(n int) *time.Time { v := now.Add(time.Duration(n) * 24 * time.Hour); return &v }
+	projects := []models.Project{
+		{UserID: 2, Title: "Просрочен", Status: models.ProjectInProgress, Progress: 90, StartDate: day(-10), DueDate: day(-2)},
+		{UserID: 2, Title: "Просрочен с задачами", Status: models.ProjectReview, Progress: 100, StartDate: day(-10), DueDate: day(-1)},
+		{UserID: 2, Title: "Скоро срок", Status: models.ProjectInProgress, Progress: 80, StartDate: day(-10), DueDate: day(3)},
+		{UserID: 2, Title: "Отстаёт", Status: models.ProjectInProgress, Progress: 0, StartDate: day(-10), DueDate: day(20)},
+		{UserID: 2, Title: "По графику", Status: models.ProjectInProgress, Progress: 30, StartDate: day(-10), DueDate: day(20)},
+		{UserID: 2, Title: "Задачи просрочены", Status: models.ProjectInProgress, Progress: 10},
+		{UserID: 2, Title: "Без срока", Status: models.ProjectPending},
+		{UserID: 2, Title: "Выполнен", Status: models.ProjectDone, Progress: 100, DueDate: day(-1)},
+		{UserID: 2, Title: "В архиве", Status: models.ProjectOnHold, DueDate: day(-1), ArchivedAt: day(-1)},
+	}
+	ids := map[string]uint{}
+	for i := range projects {
+		if err := db.DB.Create(&projects[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[projects[i].Title] = projects[i].ID
+	}
+	tasks := []models.Task{
+		{ProjectID: ids["Просрочен с задачами"], Title: "Правки", DueDate: day(-3)},
+		{ProjectID: ids["Задачи просрочены"], Title: "Макет", DueDate: day(-1)},
+		{ProjectID: ids["Задачи просрочены"], Title: "Вёрстка", DueDate: day(-2)},
+		{ProjectID: ids["Задачи просрочены"], Title: "Выполненная", DueDate: day(-2), Done: true},
+		{ProjectID: ids["По графику"], Title: "Будущая", DueDate: day(5)},
+		{ProjectID: ids["Выполнен"], Title: "Забытая", DueDate: day(-5)},
+	}
+	for i := range tasks {
+		if err := db.DB.Create(&tasks[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Некорректный горизонт", func(t *testing.T) {
+		for _, days := range []string{"abc", "-1", "366"} {
+			if w := doJSON(t, r, http.MethodGet, "/api/projects/at-risk?days="+days, nil); w.Code != http.StatusBadRequest {
+				t.Errorf("days=%s: ожидался статус %d, получен %d", days, http.StatusBadRequest, w.Code)
+			}
+		}
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		// want - проекты отчёта по порядку с причинами
+		want []string
+	}{
+		{"Горизонт по умолчанию", "", []string{
+			"Просрочен [overdue]",
+			"Просрочен с задачами [overdue overdue_tasks]",
+			"Скоро срок [due_soon]",
+			"Отстаёт [behind_schedule]",
+			"Задачи просрочены [overdue_tasks]",
+		}},
+		{"Короткий горизонт", "?days=1", []string{
+			"Просрочен [overdue]",
+			"Просрочен с задачами [overdue overdue_tasks]",
+			"Отстаёт [behind_schedule]",
+			"Задачи просрочены [overdue_tasks]",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodGet, "/api/projects/at-risk"+tt.query, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var items []struct {
+				Title            string   `json:"title"`
+				Risks            []string `json:"risks"`
+				DaysLeft         *int     `json:"days_left"`
+				ExpectedProgress *int     `json:"expected_progress"`
+				OverdueTasks     int64    `json:"overdue_tasks"`
+			}
+			decode(t, w, &items)
+			got := []string{}
+			for _, item := range items {
+				got = append(got, fmt.Sprintf("%s %v", item.Title, item.Risks))
+				switch item.Title {
+				case "Задачи просрочены":
+					if item.OverdueTasks != 2 || item.DaysLeft != nil || item.ExpectedProgress != nil {
+						t.Errorf("%s: ожидалось 2 просроченные задачи без срока, получено %d, days_left %v", item.Title, item.OverdueTasks, item.DaysLeft)
+					}
+				case "Отстаёт":
+					if item.ExpectedProgress == nil || *item.ExpectedProgress != 33 {
+						t.Errorf("%s: ожидаемый прогресс 33, получено %v", item.Title, item.ExpectedProgress)
+					}
+				case "Просрочен":
+					if item.DaysLeft == nil || *item.DaysLeft != -2 {
+						t.Errorf("%s: ожидалось -2 дня, получено %v", item.Title, item.DaysLeft)
+					}
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Ожидалось %v, получено %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"ooolalex/project-service/config"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterInternalRoutes - межсервисные эндпоинты, защищённые X-Internal-Token
+func RegisterInternalRoutes(r *gin.Engine, cfg config.Config) {
+	h := &ownerHandler{reassignTo: cfg.ReassignProjectsTo}
+
+	internal := r.Group("/api/internal")
+	internal.Use(middleware.InternalOnly(cfg.InternalToken))
+	{
+		internal.GET("/users/:id/export", ExportUser)
+		internal.POST("/users/:id/deleted", h.userDeleted)
+		internal.POST("/users/:id/anonymize", h.anonymizeUser)
+	}
+}
+
+// ownerHandler - что происходит с проектами, когда их владелец удалён в auth-service
+type ownerHandler struct {
+	reassignTo uint // 0 - проекты архивируются
+}
+
+// userDeleted передаёт проекты удалённого пользователя REASSIGN_PROJECTS_TO_USER_ID
+// или архивирует их
+func (h *ownerHandler) userDeleted(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var result gin.H
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = h.releaseProjects(tx, uint(id))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot release projects"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// releaseProjects передаёт или архивирует действующие проекты пользователя и пишет это в историю
+func (h *ownerHandler) releaseProjects(tx *gorm.DB, userID uint) (gin.H, error) {
+	var projects []models.Project
+	if err := tx.Where("user_id = ? AND archived_at IS NULL", userID).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	reassignTo := h.reassignTo
+	if reassignTo == userID {
+		reassignTo = 0
+	}
+	now := time.Now()
+	for i := range projects {
+		p := &projects[i]
+		fromStatus := p.Status
+		note := fmt.Sprintf("owner %d deleted, project archived", userID)
+		if reassignTo != 0 {
+			p.UserID = reassignTo
+			note = fmt.Sprintf("owner %d deleted, project reassigned to %d", userID, reassignTo)
+		} else {
+			p.Archive(now)
+		}
+		p.UpdatedAt = now
+		if err := tx.Save(p).Error; err != nil {
+			return nil, err
+		}
+		if err := recordHistory(tx, p, fromStatus, p.Progress, 0, note); err != nil {
+			return nil, err
+		}
+	}
+	if reassignTo != 0 {
+		return gin.H{"reassigned": len(projects), "to": reassignTo}, nil
+	}
+	return gin.H{"archived": len(projects)}, nil
+}
+
+// ExportUser отдаёт проекты, комментарии, сведения о загруженных файлах, учтённое время и журнал
+// пользователя для выгрузки персональных данных
+func ExportUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	projects := []models.Project{}
+	comments := []models.Comment{}
+	attachments := []models.Attachment{}
+	timeEntries := []models.TimeEntry{}
+	logs := []models.Log{}
+	if err := db.DB.Where("user_id = ?", id).Order("id").Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot export user"})
+		return
+	}
+	if err := db.DB.Where("author_id = ?", id).Order("id").Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot export user"})
+		return
+	}
+	if err := db.DB.Where("uploaded_by = ?", id).Order("id").Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot export user"})
+		return
+	}
+	if err := db.DB.Where("user_id = ?", id).Order("id").Find(&timeEntries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot export user"})
+		return
+	}
+	if err := db.DB.Where("user_id = ?", id).Order("id").Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot export user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"projects":     projects,
+		"comments":     comments,
+		"attachments":  attachments,
+		"time_entries": timeEntries,
+		"logs":         logs,
+	})
+}
+
+// anonymizeUser отвязывает проекты, историю изменений, назначения задач, комментарии,
+// загруженные файлы, учтённое время и журнал от удаляемого пользователя; его таймер
+// останавливается, а персональные ставки удаляются.
+// Сами проекты остаются, так как это рабочие данные компании: их получает
+// REASSIGN_PROJECTS_TO_USER_ID, а без него они архивируются без владельца.
+func (h *ownerHandler) anonymizeUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := h.releaseProjects(tx, uint(id)); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Project{}).Where("user_id = ?", id).Update("user_id", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ProjectHistory{}).Where("changed_by = ?", id).Update("changed_by", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Comment{}).Where("author_id = ?", id).Update("author_id", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Attachment{}).Where("uploaded_by = ?", id).Update("uploaded_by", 0).Error; err != nil {
+			return err
+		}
+		if _, err := stopRunning(tx, uint(id), time.Now()); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Model(&models.TimeEntry{}).Where("user_id = ?", id).Update("user_id", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? OR client_id = ?", id, id).Delete(&models.RateCard{}).Error; err != nil {
+			return err
+		}
+		for _, m := range []interface{}{&models.Milestone{}, &models.Task{}} {
+			if err := tx.Model(m).Where("assignee_id = ?", id).Update("assignee_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Log{}).Where("user_id = ?", id).Update("user_id", nil).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot anonymize user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"anonymized": true})
+}
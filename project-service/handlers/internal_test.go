@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubAuthService подменяет auth-service: на GET /api/v1/internal/users/:id отвечает
+// статусом из statuses (по умолчанию 404), для 200 - учётной записью с этим id
+func stubAuthService(t *testing.T, statuses map[string]int) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/internal/users/")
+		status, ok := statuses[id]
+		if !ok {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			fmt.Fprintf(w, `{"data":{"id":%s,"email":"user%s@example.com"}}`, id, id)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("AUTH_SERVICE_URL", srv.URL)
+}
+
+func TestOwnerExists(t *testing.T) {
+	setupTestDB(t)
+	stubAuthService(t, map[string]int{
+		"2": http.StatusOK,
+		"7": http.StatusInternalServerError,
+		"8": http.StatusUnauthorized,
+	})
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	admin := r.Group("/api/projects", func(c *gin.Context) { c.Set(middleware.ContextUserID, uint(1)) })
+	admin.POST("", CreateProject)
+	admin.PATCH(":id/owner", ChangeProjectOwner)
+
+	project := models.Project{UserID: 2, Title: "Лендинг"}
+	if err := db.DB.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	ownerPath := fmt.Sprintf("/api/projects/%d/owner", project.ID)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		userID uint
+		want   int
+	}{
+		{"Создание: владелец есть", http.MethodPost, "/api/projects", 2, http.StatusCreated},
+		{"Создание: владельца нет", http.MethodPost, "/api/projects", 5, http.StatusBadRequest},
+		{"Создание: ошибка auth-service", http.MethodPost, "/api/projects", 7, http.StatusBadGateway},
+		{"Создание: токен не принят", http.MethodPost, "/api/projects", 8, http.StatusBadGateway},
+		{"Смена владельца: владельца нет", http.MethodPatch, ownerPath, 5, http.StatusBadRequest},
+		{"Смена владельца: ошибка auth-service", http.MethodPatch, ownerPath, 7, http.StatusBadGateway},
+		{"Смена владельца: владелец есть", http.MethodPatch, ownerPath, 2, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before int64
+			db.DB.Model(&models.Project{}).Count(&before)
+
+			w := doJSON(t, r, tt.method, tt.path, gin.H{"user_id": tt.userID, "title": "Магазин"})
+			if w.Code != tt.want {
+				t.Fatalf("Ожидался статус %d, получен %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.want != http.StatusBadRequest && tt.want != http.StatusBadGateway {
+				return
+			}
+			var after int64
+			db.DB.Model(&models.Project{}).Count(&after)
+			var current models.Project
+			db.DB.First(&current, project.ID)
+			if after != before || current.UserID != project.UserID {
+				t.Errorf("Отклонённый запрос не должен ничего менять: проектов %d -> %d, владелец %d", before, after, current.UserID)
+			}
+		})
+	}
+}
+
+func TestUserDeletedReleasesProjects(t *testing.T) {
+	archivedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// проекты удаляемого пользователя 3 и один чужой
+	seed := []models.Project{
+		{UserID: 3, Title: "В работе", Status: models.ProjectInProgress, Progress: 40},
+		{UserID: 3, Title: "Новый", Status: models.ProjectPending},
+		{UserID: 3, Title: "На проверке", Status: models.ProjectReview, Progress: 100},
+		{UserID: 3, Title: "Выполнен", Status: models.ProjectDone, Progress: 100},
+		{UserID: 3, Title: "Уже в архиве", Status: models.ProjectOnHold, Progress: 10, ArchivedAt: &archivedAt},
+		{UserID: 4, Title: "Чужой", Status: models.ProjectInProgress, Progress: 20},
+	}
+	archived := map[string]models.ProjectStatus{
+		"В работе":    models.ProjectOnHold,
+		"Новый":       models.ProjectOnHold,
+		"На проверке": models.ProjectOnHold,
+		"Выполнен":    models.ProjectDone,
+	}
+
+	tests := []struct {
+		name       string
+		reassignTo uint
+		response   map[string]uint
+	}{
+		{"Архивация без REASSIGN_PROJECTS_TO_USER_ID", 0, map[string]uint{"archived": 4}},
+		{"Передача другому пользователю", 5, map[string]uint{"reassigned": 4, "to": 5}},
+		{"Передача самому удаляемому - архивация", 3, map[string]uint{"archived": 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			projects := append([]models.Project(nil), seed...)
+			for i := range projects {
+				if err := db.DB.Create(&projects[i]).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			h := &ownerHandler{reassignTo: tt.reassignTo}
+			r.POST("/api/internal/users/:id/deleted", h.userDeleted)
+
+			w := doJSON(t, r, http.MethodPost, "/api/internal/users/3/deleted", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var response map[string]uint
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(response) != fmt.Sprint(tt.response) {
+				t.Errorf("Ответ: ожидалось %v, получено %v", tt.response, response)
+			}
+
+			for _, before := range projects {
+				var p models.Project
+				if err := db.DB.First(&p, before.ID).Error; err != nil {
+					t.Fatal(err)
+				}
+				var history []models.ProjectHistory
+				db.DB.Where("project_id = ?", p.ID).Find(&history)
+
+				wantStatus, released := archived[before.Title]
+				switch {
+				case !released:
+					// чужой и уже архивный проекты не трогаются
+					if p.UserID != before.UserID || p.Status != before.Status || fmt.Sprint(p.ArchivedAt) != fmt.Sprint(before.ArchivedAt) || len(history) != 0 {
+						t.Errorf("%s: проект не должен меняться: %+v, история %d", before.Title, p, len(history))
+					}
+				case tt.response["to"] != 0:
+					if p.UserID != tt.reassignTo || p.ArchivedAt != nil || p.Status != before.Status {
+						t.Errorf("%s: ожидалась передача %d без архивации, получено владелец %d, статус %s, архив %v",
+							before.Title, tt.reassignTo, p.UserID, p.Status, p.ArchivedAt)
+					}
+				default:
+					if p.UserID != before.UserID || p.ArchivedAt == nil || p.Status != wantStatus {
+						t.Errorf("%s: ожидалась архивация в статусе %s, получено владелец %d, статус %s, архив %v",
+							before.Title, wantStatus, p.UserID, p.Status, p.ArchivedAt)
+					}
+				}
+				if !released {
+					continue
+				}
+				if len(history) != 1 {
+					t.Fatalf("%s: ожидалась одна запись истории, получено %d", before.Title, len(history))
+				}
+				if h := history[0]; h.FromStatus != before.Status || h.ToStatus != p.Status || h.ChangedBy != 0 {
+					t.Errorf("%s: запись истории %s -> %s (changed_by %d), ожидалось %s -> %s",
+						before.Title, h.FromStatus, h.ToStatus, h.ChangedBy, before.Status, p.Status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"ooolalex/project-service/clients"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// projectFilter - параметры списка проектов; пустые поля не ограничивают выборку
+type projectFilter struct {
+	Statuses    []models.ProjectStatus
+	Search      string // подстрока title или description
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Archived    string // false (по умолчанию), true или all
+	SortBy      string
+	SortDesc    bool
+}
+
+var projectSortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
+	"progress":   "progress",
+}
+
+// parseProjectFilter разбирает status (через запятую), q, created_from, created_to, archived,
+// sort (created_at|updated_at|title|progress) и order (asc|desc)
+func parseProjectFilter(c *gin.Context) (projectFilter, error) {
+	f := projectFilter{
+		Search:   strings.TrimSpace(c.Query("q")),
+		Archived: c.DefaultQuery("archived", "false"),
+		SortBy:   c.DefaultQuery("sort", "created_at"),
+		SortDesc: true,
+	}
+	switch f.Archived {
+	case "false", "true", "all":
+	default:
+		return f, errors.New("invalid archived, expected false, true or all")
+	}
+	if v := c.Query("status"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			status := models.ProjectStatus(strings.TrimSpace(s))
+			if !status.Valid() {
+				return f, errors.New("invalid status " + string(status))
+			}
+			f.Statuses = append(f.Statuses, status)
+		}
+	}
+	if _, ok := projectSortColumns[f.SortBy]; !ok {
+		return f, errors.New("invalid sort, expected created_at, updated_at, title or progress")
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		f.SortDesc = false
+	case "desc":
+	default:
+		return f, errors.New("invalid order, expected asc or desc")
+	}
+	if v := c.Query("created_from"); v != "" {
+		t, err := parseFilterTime(v, false)
+		if err != nil {
+			return f, errors.New("invalid created_from")
+		}
+		f.CreatedFrom = &t
+	}
+	if v := c.Query("created_to"); v != "" {
+		t, err := parseFilterTime(v, true)
+		if err != nil {
+			return f, errors.New("invalid created_to")
+		}
+		f.CreatedTo = &t
+	}
+	return f, nil
+}
+
+func (f projectFilter) apply(q *gorm.DB) *gorm.DB {
+	order := projectSortColumns[f.SortBy]
+	if f.SortDesc {
+		order += " desc"
+	}
+	return f.where(q).Order(order + ", id desc")
+}
+
+// where - условия фильтра без сортировки
+func (f projectFilter) where(q *gorm.DB) *gorm.DB {
+	if len(f.Statuses) > 0 {
+		q = q.Where("status IN ?", f.Statuses)
+	}
+	switch f.Archived {
+	case "false":
+		q = q.Where("archived_at IS NULL")
+	case "true":
+		q = q.Where("archived_at IS NOT NULL")
+	}
+	if f.Search != "" {
+		p := likePattern(f.Search)
+		q = q.Where(`(LOWER(title) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\')`, p, p)
+	}
+	if f.CreatedFrom != nil {
+		q = q.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q = q.Where("created_at <= ?", *f.CreatedTo)
+	}
+	return q
+}
+
+// projectPage - страница списка проектов
+type projectPage struct {
+	Items []models.Project `json:"items"`
+	Total int64            `json:"total"`
+	Page  int              `json:"page"`
+	Size  int              `json:"size"`
+}
+
+// listProjects загружает страницу проектов из base с фильтрами из запроса;
+// size по умолчанию 50, не больше 200
+func listProjects(c *gin.Context, base *gorm.DB) (*projectPage, bool) {
+	f, err := parseProjectFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	page := projectPage{Items: []models.Project{}}
+	page.Page, page.Size = pageParams(c)
+
+	q := f.apply(base.Model(&models.Project{}))
+	if err := q.Count(&page.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch projects"})
+		return nil, false
+	}
+	if err := q.Offset((page.Page - 1) * page.Size).Limit(page.Size).Find(&page.Items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch projects"})
+		return nil, false
+	}
+	return &page, true
+}
+
+// projectOwner - владелец проекта из auth-service
+type projectOwner struct {
+	ID       uint   `json:"id"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+	Deleted  bool   `json:"deleted,omitempty"`
+}
+
+type projectWithOwner struct {
+	models.Project
+	Owner *projectOwner `json:"owner"`
+}
+
+// withOwners дополняет проекты владельцами одним пакетным запросом к auth-service.
+// Если auth-service недоступен, owner остаётся null, а список всё равно отдаётся.
+func withOwners(projects []models.Project) []projectWithOwner {
+	ids := []uint{}
+	seen := map[uint]bool{}
+	for _, p := range projects {
+		if p.UserID != 0 && !seen[p.UserID] {
+			seen[p.UserID] = true
+			ids = append(ids, p.UserID)
+		}
+	}
+	users, err := clients.NewAuthClient().FindUsers(ids)
+	if err != nil {
+		log.Printf("cannot fetch project owners: %v", err)
+	}
+
+	out := make([]projectWithOwner, len(projects))
+	for i, p := range projects {
+		out[i].Project = p
+		if u, ok := users[p.UserID]; ok {
+			out[i].Owner = &projectOwner{ID: u.ID, Email: u.Email, FullName: u.FullName, Deleted: u.DeletedAt != nil}
+		}
+	}
+	return out
+}
+
+func pageParams(c *gin.Context) (int, int) {
+	page := 1
+	size := 50
+	if p := c.Query("page"); p != "" {
+		if pv, err := strconv.Atoi(p); err == nil && pv > 0 {
+			page = pv
+		}
+	}
+	if s := c.Query("size"); s != "" {
+		if sv, err := strconv.Atoi(s); err == nil && sv > 0 && sv <= 200 {
+			size = sv
+		}
+	}
+	return page, size
+}
+
+// parseFilterTime принимает RFC3339 или дату YYYY-MM-DD (для верхней границы - конец дня)
+func parseFilterTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+func likePattern(s string) string {
+	s = strings.ToLower(s)
+	s = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+// Типы событий ленты проекта
+const (
+	eventHistory    = "history"    // изменение статуса или прогресса
+	eventComment    = "comment"    // комментарий
+	eventTaskDone   = "task_done"  // выполненная задача
+	eventAttachment = "attachment" // загруженный файл
+)
+
+type timelineEvent struct {
+	Type    string      `json:"type"`
+	At      time.Time   `json:"at"`
+	ActorID uint        `json:"actor_id,omitempty"`
+	Data    interface{} `json:"data"`
+}
+
+// loadTimeline - лента проекта в хронологическом порядке: история, комментарии,
+// выполненные задачи и файлы. Без admin внутренние комментарии и файлы не попадают в ленту.
+func loadTimeline(projectID uint, admin bool) ([]timelineEvent, error) {
+	var history []models.ProjectHistory
+	if err := db.DB.Where("project_id = ?", projectID).Find(&history).Error; err != nil {
+		return nil, err
+	}
+	q := db.DB.Where("project_id = ?", projectID)
+	if !admin {
+		q = q.Where("visibility = ?", models.CommentClient)
+	}
+	var comments []models.Comment
+	if err := q.Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	if err := db.DB.Where("project_id = ? AND done = ?", projectID, true).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	attachments, err := loadAttachments(projectID, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]timelineEvent, 0, len(history)+len(comments)+len(tasks)+len(attachments))
+	for _, h := range history {
+		events = append(events, timelineEvent{Type: eventHistory, At: h.CreatedAt, ActorID: h.ChangedBy, Data: h})
+	}
+	for _, cm := range comments {
+		events = append(events, timelineEvent{Type: eventComment, At: cm.CreatedAt, ActorID: cm.AuthorID, Data: cm})
+	}
+	for _, t := range tasks {
+		if t.DoneAt != nil {
+			events = append(events, timelineEvent{Type: eventTaskDone, At: *t.DoneAt, Data: t})
+		}
+	}
+	for _, a := range attachments {
+		events = append(events, timelineEvent{Type: eventAttachment, At: a.CreatedAt, ActorID: a.UploadedBy, Data: a})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return events, nil
+}
+
+// projectView - карточка проекта: этапы, задачи, файлы и лента событий.
+// Владелец проекта не видит внутренние комментарии и файлы.
+func projectView(c *gin.Context, project *models.Project, admin bool) {
+	breakdown, err := loadBreakdown(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch project"})
+		return
+	}
+	attachments, err := loadAttachments(project.ID, admin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch project"})
+		return
+	}
+	timeline, err := loadTimeline(project.ID, admin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch project"})
+		return
+	}
+	c.JSON(http.StatusOK, projectDetails{
+		Project:          *project,
+		projectBreakdown: *breakdown,
+		Attachments:      files.views(attachments, admin),
+		Timeline:         timeline,
+	})
+}
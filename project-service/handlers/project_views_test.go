@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupViewsRouter - карточки и списки проектов от имени владельца 2 (/api/me/projects)
+// и администратора 1 (/api/projects), без проверки токена
+func setupViewsRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	files = &attachmentHandler{linkTTL: time.Minute, signKey: []byte("test-key")}
+	r := gin.New()
+	me := r.Group("/api/me/projects", func(c *gin.Context) { c.Set(middleware.ContextUserID, uint(2)) })
+	me.GET("", ListMyProjects)
+	me.GET("/:id", ownerScope(projectView))
+	me.GET("/:id/attachments", ownerScope(files.list))
+	admin := r.Group("/api/projects", func(c *gin.Context) { c.Set(middleware.ContextUserID, uint(1)) })
+	admin.GET(":id", adminScope(projectView))
+	return r
+}
+
+// visibleItems - видимость комментариев и файлов в карточке проекта
+type visibleItems struct {
+	Attachments []models.Attachment `json:"attachments"`
+	Timeline    []struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	} `json:"timeline"`
+}
+
+// visibilities возвращает видимость файлов карточки и комментариев и файлов ленты
+func (v visibleItems) visibilities(t *testing.T) (attachments, comments, timelineFiles []models.CommentVisibility) {
+	t.Helper()
+	for _, a := range v.Attachments {
+		attachments = append(attachments, a.Visibility)
+	}
+	for _, e := range v.Timeline {
+		var item struct {
+			Visibility models.CommentVisibility `json:"visibility"`
+		}
+		if err := json.Unmarshal(e.Data, &item); err != nil {
+			t.Fatal(err)
+		}
+		switch e.Type {
+		case eventComment:
+			comments = append(comments, item.Visibility)
+		case eventAttachment:
+			timelineFiles = append(timelineFiles, item.Visibility)
+		}
+	}
+	return
+}
+
+func TestOwnerProjectView(t *testing.T) {
+	setupTestDB(t)
+	r := setupViewsRouter()
+
+	mine := models.Project{UserID: 2, Title: "Лендинг"}
+	if err := db.DB.Create(&mine).Error; err != nil {
+		t.Fatal(err)
+	}
+	other := models.Project{UserID: 3, Title: "Магазин"}
+	if err := db.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []models.CommentVisibility{models.CommentClient, models.CommentInternal} {
+		if err := db.DB.Create(&models.Comment{ProjectID: mine.ID, AuthorID: 1, Body: string(v), Visibility: v}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.DB.Create(&models.Attachment{ProjectID: mine.ID, UploadedBy: 1, FileName: string(v) + ".txt", Key: string(v), Size: 1, Visibility: v}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Чужой и несуществующий проект", func(t *testing.T) {
+		for _, path := range []string{
+			fmt.Sprintf("/api/me/projects/%d", other.ID),
+			fmt.Sprintf("/api/me/projects/%d/attachments", other.ID),
+			"/api/me/projects/999",
+		} {
+			if w := doJSON(t, r, http.MethodGet, path, nil); w.Code != http.StatusNotFound {
+				t.Errorf("%s: ожидался статус %d, получен %d", path, http.StatusNotFound, w.Code)
+			}
+		}
+	})
+
+	client := []models.CommentVisibility{models.CommentClient}
+	all := []models.CommentVisibility{models.CommentClient, models.CommentInternal}
+	tests := []struct {
+		name string
+		path string
+		want []models.CommentVisibility
+	}{
+		{"Владелец не видит внутреннее", fmt.Sprintf("/api/me/projects/%d", mine.ID), client},
+		{"Администратор видит всё", fmt.Sprintf("/api/projects/%d", mine.ID), all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodGet, tt.path, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var view visibleItems
+			decode(t, w, &view)
+			attachments, comments, timelineFiles := view.visibilities(t)
+			for name, got := range map[string][]models.CommentVisibility{
+				"Файлы карточки":      attachments,
+				"Комментарии в ленте": comments,
+				"Файлы в ленте":       timelineFiles,
+			} {
+				if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("%s: ожидалось %v, получено %v", name, tt.want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("Список файлов владельца", func(t *testing.T) {
+		w := doJSON(t, r, http.MethodGet, fmt.Sprintf("/api/me/projects/%d/attachments", mine.ID), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var list struct {
+			Items []models.Attachment `json:"items"`
+		}
+		decode(t, w, &list)
+		if len(list.Items) != 1 || list.Items[0].Visibility != models.CommentClient {
+			t.Errorf("Владельцу должны быть видны только файлы для клиента: %+v", list.Items)
+		}
+	})
+}
+
+func TestListMyProjects(t *testing.T) {
+	setupTestDB(t)
+	r := setupViewsRouter()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := base.AddDate(0, 1, 0)
+	projects := []models.Project{
+		{UserID: 2, Title: "Лендинг", Status: models.ProjectPending, CreatedAt: base},
+		{UserID: 2, Title: "Магазин", Status: models.ProjectInProgress, Progress: 40, CreatedAt: base.AddDate(0, 0, 1)},
+		{UserID: 2, Title: "Блог", Status: models.ProjectInProgress, Progress: 10, CreatedAt: base.AddDate(0, 0, 2)},
+		{UserID: 2, Title: "Визитка", Status: models.ProjectDone, Progress: 100, CreatedAt: base.AddDate(0, 0, 3)},
+		{UserID: 2, Title: "Каталог", Status: models.ProjectReview, Progress: 100, CreatedAt: base.AddDate(0, 0, 4)},
+		{UserID: 2, Title: "Архив", Status: models.ProjectOnHold, Progress: 30, CreatedAt: base.AddDate(0, 0, 5), ArchivedAt: &now},
+		{UserID: 3, Title: "Чужой", Status: models.ProjectInProgress, Progress: 50, CreatedAt: base.AddDate(0, 0, 6)},
+	}
+	for i := range projects {
+		if err := db.DB.Create(&projects[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Некорректные параметры", func(t *testing.T) {
+		for _, query := range []string{
+			"status=unknown",
+			"status=pending,paused",
+			"sort=owner",
+			"order=up",
+			"archived=yes",
+			"created_from=01.03.2025",
+			"created_to=tomorrow",
+		} {
+			if w := doJSON(t, r, http.MethodGet, "/api/me/projects?"+query, nil); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: ожидался статус %d, получен %d", query, http.StatusBadRequest, w.Code)
+			}
+		}
+	})
+
+	tests := []struct {
+		name   string
+		query  string
+		total  int64
+		page   int
+		size   int
+		titles []string
+	}{
+		{"По умолчанию - новые первыми, без архива", "", 5, 1, 50, []string{"Каталог", "Визитка", "Блог", "Магазин", "Лендинг"}},
+		{"Первая страница", "size=2", 5, 1, 2, []string{"Каталог", "Визитка"}},
+		{"Вторая страница", "size=2&page=2", 5, 2, 2, []string{"Блог", "Магазин"}},
+		{"Последняя неполная страница", "size=2&page=3", 5, 3, 2, []string{"Лендинг"}},
+		{"За последней страницей", "size=2&page=4", 5, 4, 2, []string{}},
+		{"Размер больше предела - по умолчанию", "size=500", 5, 1, 50, []string{"Каталог", "Визитка", "Блог", "Магазин", "Лендинг"}},
+		{"Несколько статусов", "status=in_progress,review&sort=progress&order=asc", 3, 1, 50, []string{"Блог", "Магазин", "Каталог"}},
+		{"Только архив", "archived=true", 1, 1, 50, []string{"Архив"}},
+		{"С архивом", "archived=all&size=1", 6, 1, 1, []string{"Архив"}},
+		{"По дате создания", "created_from=2025-03-02&created_to=2025-03-03&sort=title&order=asc", 2, 1, 50, []string{"Блог", "Магазин"}},
+		{"Поиск по названию", "q=газ", 1, 1, 50, []string{"Магазин"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodGet, "/api/me/projects?"+tt.query, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var page projectPage
+			decode(t, w, &page)
+			titles := []string{}
+			for _, p := range page.Items {
+				titles = append(titles, p.Title)
+			}
+			if page.Total != tt.total || page.Page != tt.page || page.Size != tt.size {
+				t.Errorf("Ожидалось total=%d page=%d size=%d, получено total=%d page=%d size=%d",
+					tt.total, tt.page, tt.size, page.Total, page.Page, page.Size)
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(tt.titles) {
+				t.Errorf("Ожидалось %v, получено %v", tt.titles, titles)
+			}
+		})
+	}
+}
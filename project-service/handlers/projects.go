@@ -1,40 +1,72 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"ooolalex/project-service/clients"
+	"ooolalex/project-service/config"
 	"ooolalex/project-service/db"
 	"ooolalex/project-service/logs"
 	"ooolalex/project-service/middleware"
 	"ooolalex/project-service/models"
+	"ooolalex/project-service/storage"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type createProjectRequest struct {
-	UserID      uint   `json:"user_id" binding:"required"`
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
+	UserID         uint       `json:"user_id" binding:"required"`
+	Title          string     `json:"title" binding:"required"`
+	Description    string     `json:"description"`
+	StartDate      *time.Time `json:"start_date"`
+	DueDate        *time.Time `json:"due_date"`
+	EstimatedHours *float64   `json:"estimated_hours"`
+}
+
+type changeOwnerRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
 }
 
 type updateProjectRequest struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
+	Title          *string    `json:"title"`
+	Description    *string    `json:"description"`
+	StartDate      *time.Time `json:"start_date"`
+	DueDate        *time.Time `json:"due_date"`
+	EstimatedHours *float64   `json:"estimated_hours"`
 }
 
+// updateProjectProgressRequest - новый статус и/или прогресс; не переданное поле не меняется.
+// Переданный progress включает ручной режим, manual=false возвращает расчёт по задачам.
 type updateProjectProgressRequest struct {
-	Status   string `json:"status" binding:"required"`
-	Progress int    `json:"progress" binding:"required"`
+	Status   *models.ProjectStatus `json:"status"`
+	Progress *int                  `json:"progress"`
+	Manual   *bool                 `json:"manual"`
+	Note     string                `json:"note"`
+}
+
+// projectDetails - проект с этапами, задачами, файлами и лентой событий
+type projectDetails struct {
+	models.Project
+	projectBreakdown
+	Attachments []attachmentView `json:"attachments"`
+	Timeline    []timelineEvent  `json:"timeline"`
 }
 
-func RegisterProjectRoutes(r *gin.Engine) {
+func RegisterProjectRoutes(r *gin.Engine, cfg config.Config, store storage.Storage) {
 	admin := r.Group("/api/projects")
-	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(middleware.PermProjectsManage))
 	{
 		admin.POST("", CreateProject)
 		admin.GET("", ListProjects)
+		admin.GET("at-risk", atRiskReport(cfg.AtRiskWindow))
+		admin.GET(":id", adminScope(projectView))
 		admin.PATCH(":id", UpdateProject)
 		admin.PATCH(":id/progress", UpdateProjectProgress)
+		admin.PATCH(":id/owner", ChangeProjectOwner)
+		admin.GET(":id/history", ProjectHistory)
 		admin.DELETE(":id", DeleteProject)
 	}
 
@@ -43,7 +75,19 @@ func RegisterProjectRoutes(r *gin.Engine) {
 	{
 		me.GET("", ListMyProjects)
 		me.GET("/summary", ProjectSummary)
+		me.GET("/:id", ownerScope(projectView))
+		me.GET("/:id/history", MyProjectHistory)
 	}
+
+	mentions := r.Group("/api/me/mentions")
+	mentions.Use(middleware.AuthMiddleware())
+
+	registerTaskRoutes(admin)
+	registerBoardRoutes(admin)
+	registerTimeRoutes(admin, cfg)
+	registerTemplateRoutes(admin)
+	registerCommentRoutes(admin, me, mentions, cfg)
+	registerAttachmentRoutes(r, admin, me, cfg, store)
 }
 
 func CreateProject(c *gin.Context) {
@@ -52,18 +96,30 @@ func CreateProject(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !ownerExists(c, req.UserID) {
+		return
+	}
 
 	project := models.Project{
 		UserID:      req.UserID,
 		Title:       req.Title,
 		Description: req.Description,
-		Status:      "pending",
+		Status:      models.ProjectPending,
 		Progress:    0,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	if !applySchedule(c, &project, req.StartDate, req.DueDate, req.EstimatedHours) {
+		return
+	}
 
-	if err := db.DB.Create(&project).Error; err != nil {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+		return recordHistory(tx, &project, "", 0, c.MustGet(middleware.ContextUserID).(uint), "")
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create project"})
 		return
 	}
@@ -72,13 +128,18 @@ func CreateProject(c *gin.Context) {
 	c.JSON(http.StatusCreated, project)
 }
 
+// ListProjects - все проекты с владельцами, фильтрами и постраничной выдачей (см. parseProjectFilter)
 func ListProjects(c *gin.Context) {
-	var projects []models.Project
-	if err := db.DB.Order("created_at desc").Find(&projects).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch projects"})
+	page, ok := listProjects(c, db.DB)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, projects)
+	c.JSON(http.StatusOK, gin.H{
+		"items": withOwners(page.Items),
+		"total": page.Total,
+		"page":  page.Page,
+		"size":  page.Size,
+	})
 }
 
 func UpdateProject(c *gin.Context) {
@@ -102,6 +163,9 @@ func UpdateProject(c *gin.Context) {
 	if req.Description != nil {
 		project.Description = *req.Description
 	}
+	if !applySchedule(c, &project, req.StartDate, req.DueDate, req.EstimatedHours) {
+		return
+	}
 	project.UpdatedAt = time.Now()
 
 	if err := db.DB.Save(&project).Error; err != nil {
@@ -112,6 +176,75 @@ func UpdateProject(c *gin.Context) {
 	c.JSON(http.StatusOK, project)
 }
 
+// applySchedule переносит даты и оценку трудозатрат из запроса; не переданное поле не меняется
+func applySchedule(c *gin.Context, project *models.Project, start, due *time.Time, hours *float64) bool {
+	if hours != nil && *hours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "estimated_hours must not be negative"})
+		return false
+	}
+	if err := project.SetSchedule(start, due); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if hours != nil {
+		project.EstimatedHours = *hours
+	}
+	return true
+}
+
+// ChangeProjectOwner передаёт проект другому пользователю и возвращает его из архива
+func ChangeProjectOwner(c *gin.Context) {
+	var req changeOwnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project models.Project
+	if err := db.DB.First(&project, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+	if !ownerExists(c, req.UserID) {
+		return
+	}
+
+	fromOwner := project.UserID
+	project.UserID = req.UserID
+	project.ArchivedAt = nil
+	project.UpdatedAt = time.Now()
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&project).Error; err != nil {
+			return err
+		}
+		note := fmt.Sprintf("owner changed from %d to %d", fromOwner, req.UserID)
+		return recordHistory(tx, &project, project.Status, project.Progress, c.MustGet(middleware.ContextUserID).(uint), note)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot change owner"})
+		return
+	}
+
+	logs.SendLog(req.UserID, "Admin assigned project: "+project.Title)
+	c.JSON(http.StatusOK, project)
+}
+
+// ownerExists проверяет в auth-service, что пользователь существует и не удалён
+func ownerExists(c *gin.Context, userID uint) bool {
+	_, err := clients.NewAuthClient().GetUser(userID)
+	if errors.Is(err, clients.ErrUserNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "cannot verify owner"})
+		return false
+	}
+	return true
+}
+
+// UpdateProjectProgress меняет статус и прогресс по правилам переходов (models.ProjectStatus)
+// и записывает изменение в историю
 func UpdateProjectProgress(c *gin.Context) {
 	id := c.Param("id")
 
@@ -121,9 +254,12 @@ func UpdateProjectProgress(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	if req.Progress < 0 || req.Progress > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "progress must be between 0 and 100"})
+	if req.Status == nil && req.Progress == nil && req.Manual == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status, progress or manual is required"})
+		return
+	}
+	if req.Progress != nil && req.Manual != nil && !*req.Manual {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "progress cannot be set together with manual=false"})
 		return
 	}
 
@@ -133,11 +269,58 @@ func UpdateProjectProgress(c *gin.Context) {
 		return
 	}
 
-	project.Progress = req.Progress
-	project.Status = req.Status
+	fromStatus, fromProgress, fromManual := project.Status, project.Progress, project.ProgressManual
+	if req.Manual != nil {
+		project.ProgressManual = *req.Manual
+	}
+	if req.Progress != nil {
+		project.ProgressManual = true
+	}
+	status, progress := project.Status, project.Progress
+	if req.Status != nil {
+		status = *req.Status
+	}
+	if req.Progress != nil {
+		progress = *req.Progress
+	}
+	changed, err := project.ChangeState(status, progress)
+	if errors.Is(err, models.ErrStatusChange) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": fromStatus, "allowed": fromStatus.Next()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !changed && project.ProgressManual == fromManual {
+		c.JSON(http.StatusOK, project)
+		return
+	}
 	project.UpdatedAt = time.Now()
 
-	if err := db.DB.Save(&project).Error; err != nil {
+	userID := c.MustGet(middleware.ContextUserID).(uint)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&project).Error; err != nil {
+			return err
+		}
+		if changed {
+			if err := recordHistory(tx, &project, fromStatus, fromProgress, userID, req.Note); err != nil {
+				return err
+			}
+		}
+		if project.ProgressManual {
+			return nil
+		}
+		if err := syncProgress(tx, project.ID, userID); err != nil {
+			return err
+		}
+		return tx.First(&project, project.ID).Error
+	})
+	if errors.Is(err, models.ErrStatusChange) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": fromStatus, "allowed": fromStatus.Next()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update progress"})
 		return
 	}
@@ -146,6 +329,84 @@ func UpdateProjectProgress(c *gin.Context) {
 	c.JSON(http.StatusOK, project)
 }
 
+// ProjectHistory - изменения статуса и прогресса проекта
+func ProjectHistory(c *gin.Context) {
+	var project models.Project
+	if err := db.DB.First(&project, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+	respondHistory(c, project.ID)
+}
+
+// MyProjectHistory - история своего проекта
+func MyProjectHistory(c *gin.Context) {
+	project, ok := ownProject(c)
+	if !ok {
+		return
+	}
+	respondHistory(c, project.ID)
+}
+
+// projectAction получает проект, к которому у запроса есть доступ, и признак администратора
+type projectAction func(c *gin.Context, project *models.Project, admin bool)
+
+// adminScope - любой проект из :id (маршруты под projects.manage)
+func adminScope(next projectAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var project models.Project
+		if err := db.DB.First(&project, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		next(c, &project, true)
+	}
+}
+
+// ownerScope - только собственный проект из :id
+func ownerScope(next projectAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		project, ok := ownProject(c)
+		if !ok {
+			return
+		}
+		next(c, project, false)
+	}
+}
+
+// ownProject загружает проект из :id, если он принадлежит текущему пользователю
+func ownProject(c *gin.Context) (*models.Project, bool) {
+	userID := c.MustGet(middleware.ContextUserID).(uint)
+
+	var project models.Project
+	if err := db.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return nil, false
+	}
+	return &project, true
+}
+
+func respondHistory(c *gin.Context, projectID uint) {
+	history := []models.ProjectHistory{}
+	if err := db.DB.Where("project_id = ?", projectID).Order("id").Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch history"})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+func recordHistory(tx *gorm.DB, p *models.Project, fromStatus models.ProjectStatus, fromProgress int, changedBy uint, note string) error {
+	return tx.Create(&models.ProjectHistory{
+		ProjectID:    p.ID,
+		ChangedBy:    changedBy,
+		FromStatus:   fromStatus,
+		ToStatus:     p.Status,
+		FromProgress: fromProgress,
+		ToProgress:   p.Progress,
+		Note:         note,
+	}).Error
+}
+
 func DeleteProject(c *gin.Context) {
 	id := c.Param("id")
 	var project models.Project
@@ -155,41 +416,95 @@ func DeleteProject(c *gin.Context) {
 		return
 	}
 
-	if err := db.DB.Delete(&project).Error; err != nil {
+	var keys []string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Attachment{}).Where("project_id = ?", project.ID).Pluck("key", &keys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id IN (?)", tx.Model(&models.Comment{}).Unscoped().Select("id").Where("project_id = ?", project.ID)).
+			Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("project_id = ?", project.ID).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		for _, m := range []interface{}{&models.ProjectHistory{}, &models.Task{}, &models.Milestone{}, &models.Attachment{}, &models.TimeEntry{}, &models.RateCard{}} {
+			if err := tx.Where("project_id = ?", project.ID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&project).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete project"})
 		return
 	}
+	files.removeObjects(keys)
 
 	logs.SendLog(project.UserID, "Admin deleted project: "+project.Title)
 	c.JSON(http.StatusOK, gin.H{"message": "project deleted"})
 }
 
-// ✅ Получение всех проектов конкретного пользователя
+// ✅ Получение проектов текущего пользователя с фильтрами и постраничной выдачей
 func ListMyProjects(c *gin.Context) {
 	userID := c.MustGet(middleware.ContextUserID).(uint)
-
-	var projects []models.Project
-	if err := db.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&projects).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch projects"})
+	page, ok := listProjects(c, db.DB.Where("user_id = ?", userID))
+	if !ok {
 		return
 	}
-
-	c.JSON(http.StatusOK, projects)
+	c.JSON(http.StatusOK, page)
 }
 
 func ProjectSummary(c *gin.Context) {
 	userID := c.MustGet(middleware.ContextUserID).(uint)
 
+	var rows []struct {
+		Status models.ProjectStatus
+		Count  int64
+	}
+	if err := db.DB.Model(&models.Project{}).Select("status, count(*) as count").
+		Where("user_id = ?", userID).Group("status").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch summary"})
+		return
+	}
+
+	byStatus := gin.H{}
+	for _, st := range models.ProjectStatuses {
+		byStatus[string(st)] = int64(0)
+	}
 	var total, completed, inProgress int64
+	for _, r := range rows {
+		byStatus[string(r.Status)] = r.Count
+		total += r.Count
+		switch r.Status {
+		case models.ProjectDone:
+			completed += r.Count
+		case models.ProjectCancelled:
+		default:
+			inProgress += r.Count
+		}
+	}
 
-	db.DB.Model(&models.Project{}).Where("user_id = ?", userID).Count(&total)
-	db.DB.Model(&models.Project{}).Where("user_id = ? AND progress = 100", userID).Count(&completed)
-	db.DB.Model(&models.Project{}).Where("user_id = ? AND progress < 100", userID).Count(&inProgress)
+	now := time.Now()
+	var overdue, overdueTasks int64
+	if err := overdueProjects(db.DB.Model(&models.Project{}), now).
+		Where("user_id = ?", userID).Count(&overdue).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch summary"})
+		return
+	}
+	if err := overdueTasksQuery(now).
+		Where("projects.user_id = ?", userID).Count(&overdueTasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch summary"})
+		return
+	}
 
 	summary := gin.H{
-		"total":       total,
-		"completed":   completed,
-		"in_progress": inProgress,
+		"total":         total,
+		"completed":     completed,
+		"in_progress":   inProgress,
+		"by_status":     byStatus,
+		"overdue":       overdue,
+		"overdue_tasks": overdueTasks,
 	}
 
 	c.JSON(http.StatusOK, summary)
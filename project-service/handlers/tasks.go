@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type milestoneRequest struct {
+	Title      *string    `json:"title"`
+	AssigneeID *uint      `json:"assignee_id"`
+	DueDate    *time.Time `json:"due_date"`
+	Position   *int       `json:"position"`
+}
+
+type taskRequest struct {
+	Title       *string    `json:"title"`
+	MilestoneID *uint      `json:"milestone_id"`
+	AssigneeID  *uint      `json:"assignee_id"`
+	DueDate     *time.Time `json:"due_date"`
+	Weight      *int       `json:"weight"`
+	Done        *bool      `json:"done"`
+}
+
+type milestoneView struct {
+	models.Milestone
+	Progress int           `json:"progress"`
+	Done     bool          `json:"done"`
+	Tasks    []models.Task `json:"tasks"`
+}
+
+// projectBreakdown - этапы с задачами и задачи вне этапов
+type projectBreakdown struct {
+	Milestones []milestoneView `json:"milestones"`
+	Tasks      []models.Task   `json:"tasks"`
+}
+
+func registerTaskRoutes(admin *gin.RouterGroup) {
+	admin.GET(":id/tasks", ProjectTasks)
+	admin.POST(":id/milestones", CreateMilestone)
+	admin.PATCH(":id/milestones/:milestoneId", UpdateMilestone)
+	admin.DELETE(":id/milestones/:milestoneId", DeleteMilestone)
+	admin.POST(":id/tasks", CreateTask)
+	admin.PATCH(":id/tasks/:taskId", UpdateTask)
+	admin.DELETE(":id/tasks/:taskId", DeleteTask)
+}
+
+// ProjectTasks - этапы и задачи проекта
+func ProjectTasks(c *gin.Context) {
+	var project models.Project
+	if err := db.DB.First(&project, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+	breakdown, err := loadBreakdown(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch tasks"})
+		return
+	}
+	c.JSON(http.StatusOK, breakdown)
+}
+
+func CreateMilestone(c *gin.Context) {
+	project, ok := openProject(c)
+	if !ok {
+		return
+	}
+	var req milestoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Title == nil || *req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+	m := models.Milestone{ProjectID: project.ID}
+	applyMilestone(&m, &req)
+	if err := db.DB.Create(&m).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create milestone"})
+		return
+	}
+	c.JSON(http.StatusCreated, m)
+}
+
+func UpdateMilestone(c *gin.Context) {
+	project, ok := openProject(c)
+	if !ok {
+		return
+	}
+	var m models.Milestone
+	if err := db.DB.Where("id = ? AND project_id = ?", c.Param("milestoneId"), project.ID).First(&m).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "milestone not found"})
+		return
+	}
+	var req milestoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Title != nil && *req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title cannot be empty"})
+		return
+	}
+	applyMilestone(&m, &req)
+	if err := db.DB.Save(&m).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update milestone"})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+// DeleteMilestone удаляет этап; его задачи остаются в проекте вне этапов
+func DeleteMilestone(c *gin.Context) {
+	project, ok := openProject(c)
+	if !ok {
+		return
+	}
+	var m models.Milestone
+	if err := db.DB.Where("id = ? AND project_id = ?", c.Param("milestoneId"), project.ID).First(&m).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "milestone not found"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("milestone_id = ?", m.ID).Update("milestone_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&m).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete milestone"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "milestone deleted"})
+}
+
+func CreateTask(c *gin.Context) {
+	project, ok := openProject(c)
+	if !ok {
+		return
+	}
+	var req taskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Title == nil || *req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+	t := models.Task{ProjectID: project.ID, Weight: 1}
+	if !applyTask(c, &t, &req) {
+		return
+	}
+	if saveTask(c, t.ProjectID, func(tx *gorm.DB) error { return tx.Create(&t).Error }) {
+		c.JSON(http.StatusCreated, t)
+	}
+}
+
+func UpdateTask(c *gin.Context) {
+	project, ok := openProject(c)
+	if !ok {
+		return
+	}
+	var t models.Task
+	if err := db.DB.Where("id = ? AND project_id = ?", c.Param("taskId"), project.ID).First(&t).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	var req taskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Title != nil && *req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title cannot be empty"})
+		return
+	}
+	if !applyTask(c, &t, &req) {
+		return
+	}
+	if saveTask(c, t.ProjectID, func(tx *gorm.DB) error { return tx.Save(&t).Error }) {
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+func DeleteTask(c *gin.Context) {
+	project, ok := openProject(c)
+	if !ok {
+		return
+	}
+	var t models.Task
+	if err := db.DB.Where("id = ? AND project_id = ?", c.Param("taskId"), project.ID).First(&t).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	deleteTask := func(tx *gorm.DB) error {
+		if err := tx.Where("comment_id IN (?)", tx.Model(&models.Comment{}).Unscoped().Select("id").Where("task_id = ?", t.ID)).
+			Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("task_id = ?", t.ID).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		// файлы и учтённое время задачи остаются в проекте
+		for _, m := range []interface{}{&models.Attachment{}, &models.TimeEntry{}} {
+			if err := tx.Model(m).Where("task_id = ?", t.ID).Update("task_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&t).Error
+	}
+	if saveTask(c, t.ProjectID, deleteTask) {
+		c.JSON(http.StatusOK, gin.H{"message": "task deleted"})
+	}
+}
+
+// openProject загружает проект из :id; отменённый проект менять нельзя
+func openProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+	if err := db.DB.First(&project, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return nil, false
+	}
+	if project.Status == models.ProjectCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "project is cancelled"})
+		return nil, false
+	}
+	return &project, true
+}
+
+func applyMilestone(m *models.Milestone, req *milestoneRequest) {
+	if req.Title != nil {
+		m.Title = *req.Title
+	}
+	if req.AssigneeID != nil {
+		m.AssigneeID = nullableID(*req.AssigneeID)
+	}
+	if req.DueDate != nil {
+		m.DueDate = req.DueDate
+	}
+	if req.Position != nil {
+		m.Position = *req.Position
+	}
+}
+
+// applyTask переносит поля запроса в задачу; milestone_id и assignee_id = 0 снимают привязку
+func applyTask(c *gin.Context, t *models.Task, req *taskRequest) bool {
+	if req.Weight != nil && (*req.Weight < 1 || *req.Weight > models.MaxTaskWeight) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "weight must be between 1 and 100"})
+		return false
+	}
+	if req.MilestoneID != nil && *req.MilestoneID != 0 {
+		var count int64
+		db.DB.Model(&models.Milestone{}).Where("id = ? AND project_id = ?", *req.MilestoneID, t.ProjectID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "milestone not found in this project"})
+			return false
+		}
+	}
+	if req.Title != nil {
+		t.Title = *req.Title
+	}
+	if req.MilestoneID != nil {
+		t.MilestoneID = nullableID(*req.MilestoneID)
+	}
+	if req.AssigneeID != nil {
+		t.AssigneeID = nullableID(*req.AssigneeID)
+	}
+	if req.DueDate != nil {
+		t.SetDueDate(*req.DueDate)
+	}
+	if req.Weight != nil {
+		t.Weight = *req.Weight
+	}
+	if req.Done != nil {
+		t.SetDone(*req.Done, time.Now())
+	}
+	return true
+}
+
+// saveTask сохраняет изменение задачи и пересчитывает прогресс проекта в одной транзакции
+func saveTask(c *gin.Context, projectID uint, write func(tx *gorm.DB) error) bool {
+	userID := c.MustGet(middleware.ContextUserID).(uint)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		return syncProgress(tx, projectID, userID)
+	})
+	if errors.Is(err, models.ErrStatusChange) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save task"})
+		return false
+	}
+	return true
+}
+
+// syncProgress пересчитывает прогресс проекта по весу выполненных задач,
+// если прогресс не задан вручную. Проект без задач не меняется.
+func syncProgress(tx *gorm.DB, projectID, changedBy uint) error {
+	var project models.Project
+	if err := tx.First(&project, projectID).Error; err != nil {
+		return err
+	}
+	if project.ProgressManual {
+		return nil
+	}
+	var tasks []models.Task
+	if err := tx.Where("project_id = ?", projectID).Find(&tasks).Error; err != nil {
+		return err
+	}
+	progress, ok := models.ComputeProgress(tasks)
+	if !ok {
+		return nil
+	}
+	fromStatus, fromProgress := project.Status, project.Progress
+	changed, via, err := project.ApplyProgress(progress)
+	if err != nil || !changed {
+		return err
+	}
+	project.UpdatedAt = time.Now()
+	if err := tx.Save(&project).Error; err != nil {
+		return err
+	}
+	const note = "progress computed from tasks"
+	if via != "" {
+		// промежуточный переход: статус уже сменился, прогресс ещё прежний
+		step := project
+		step.Status, step.Progress = via, fromProgress
+		if err := recordHistory(tx, &step, fromStatus, fromProgress, changedBy, note); err != nil {
+			return err
+		}
+		fromStatus = via
+	}
+	return recordHistory(tx, &project, fromStatus, fromProgress, changedBy, note)
+}
+
+func loadBreakdown(projectID uint) (*projectBreakdown, error) {
+	var milestones []models.Milestone
+	if err := db.DB.Where("project_id = ?", projectID).Order("position, id").Find(&milestones).Error; err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	if err := db.DB.Where("project_id = ?", projectID).Order("id").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	out := &projectBreakdown{Milestones: make([]milestoneView, len(milestones)), Tasks: []models.Task{}}
+	index := map[uint]int{}
+	for i, m := range milestones {
+		out.Milestones[i] = milestoneView{Milestone: m, Tasks: []models.Task{}}
+		index[m.ID] = i
+	}
+	for _, t := range tasks {
+		if t.MilestoneID != nil {
+			if i, ok := index[*t.MilestoneID]; ok {
+				out.Milestones[i].Tasks = append(out.Milestones[i].Tasks, t)
+				continue
+			}
+		}
+		out.Tasks = append(out.Tasks, t)
+	}
+	for i := range out.Milestones {
+		m := &out.Milestones[i]
+		m.Progress, _ = models.ComputeProgress(m.Tasks)
+		m.Done = len(m.Tasks) > 0 && m.Progress == 100
+	}
+	return out, nil
+}
+
+func nullableID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"testing"
+
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+)
+
+func TestSyncProgressFromPendingToReview(t *testing.T) {
+	setupTestDB(t)
+	project := models.Project{UserID: 2, Title: "Лендинг"}
+	if err := db.DB.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"Макет", "Вёрстка"} {
+		if err := db.DB.Create(&models.Task{ProjectID: project.ID, Title: title, Weight: 1, Done: true, Status: models.TaskDone}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := syncProgress(db.DB, project.ID, 1); err != nil {
+		t.Fatalf("syncProgress: %v", err)
+	}
+	if err := db.DB.First(&project, project.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if project.Status != models.ProjectReview || project.Progress != 100 {
+		t.Errorf("Ожидалось review 100, получено %s %d", project.Status, project.Progress)
+	}
+
+	var history []models.ProjectHistory
+	if err := db.DB.Where("project_id = ?", project.ID).Order("id").Find(&history).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]models.ProjectStatus{
+		{models.ProjectPending, models.ProjectInProgress},
+		{models.ProjectInProgress, models.ProjectReview},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("Ожидалось %d записей истории, получено %d: %+v", len(want), len(history), history)
+	}
+	for i, h := range history {
+		if h.FromStatus != want[i][0] || h.ToStatus != want[i][1] {
+			t.Errorf("Запись %d: ожидалось %s -> %s, получено %s -> %s", i, want[i][0], want[i][1], h.FromStatus, h.ToStatus)
+		}
+		if !h.FromStatus.CanTransition(h.ToStatus) {
+			t.Errorf("Запись %d: переход %s -> %s не разрешён", i, h.FromStatus, h.ToStatus)
+		}
+	}
+	if history[0].ToProgress != 0 || history[1].FromProgress != 0 || history[1].ToProgress != 100 {
+		t.Errorf("Прогресс в истории: %+v", history)
+	}
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/logs"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errTemplateName - шаблон с таким названием уже есть
+var errTemplateName = errors.New("template name already exists")
+
+// templateRequest - шаблон целиком: PUT заменяет этапы и задачи полностью
+type templateRequest struct {
+	Name           string                     `json:"name" binding:"required"`
+	Description    string                     `json:"description"`
+	DurationDays   *int                       `json:"duration_days"`
+	EstimatedHours float64                    `json:"estimated_hours"`
+	Milestones     []models.TemplateMilestone `json:"milestones"`
+	Tasks          []models.TemplateTask      `json:"tasks"`
+}
+
+// fromTemplateRequest - проект по шаблону; title и description по умолчанию берутся из шаблона,
+// start_date - сегодня (UTC)
+type fromTemplateRequest struct {
+	UserID      uint       `json:"user_id" binding:"required"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	StartDate   *time.Time `json:"start_date"`
+}
+
+func registerTemplateRoutes(admin *gin.RouterGroup) {
+	admin.GET("templates", ListTemplates)
+	admin.POST("templates", CreateTemplate)
+	admin.GET("templates/:templateId", GetTemplate)
+	admin.PUT("templates/:templateId", ReplaceTemplate)
+	admin.DELETE("templates/:templateId", DeleteTemplate)
+	admin.POST("from-template/:templateId", CreateProjectFromTemplate)
+}
+
+// ListTemplates - все шаблоны со структурой; шаблонов немного, поэтому без постраничной выдачи
+func ListTemplates(c *gin.Context) {
+	templates := []models.ProjectTemplate{}
+	if err := db.DB.Order("name").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch templates"})
+		return
+	}
+	if err := loadTemplateStructure(templates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch templates"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func GetTemplate(c *gin.Context) {
+	tpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+func CreateTemplate(c *gin.Context) {
+	var tpl models.ProjectTemplate
+	if !bindTemplate(c, &tpl) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tpl).Error; err != nil {
+			return err
+		}
+		return saveTemplateStructure(tx, &tpl)
+	})
+	if !templateSaved(c, err) {
+		return
+	}
+	c.JSON(http.StatusCreated, tpl)
+}
+
+// ReplaceTemplate заменяет шаблон целиком; проекты, уже созданные по нему, не меняются
+func ReplaceTemplate(c *gin.Context) {
+	tpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+	if !bindTemplate(c, tpl) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(tpl).Error; err != nil {
+			return err
+		}
+		if err := deleteTemplateStructure(tx, tpl.ID); err != nil {
+			return err
+		}
+		return saveTemplateStructure(tx, tpl)
+	})
+	if !templateSaved(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+func DeleteTemplate(c *gin.Context) {
+	var tpl models.ProjectTemplate
+	if err := db.DB.First(&tpl, c.Param("templateId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTemplateStructure(tx, tpl.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Project{}).Where("template_id = ?", tpl.ID).Update("template_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tpl).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete template"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "template deleted"})
+}
+
+// CreateProjectFromTemplate создаёт проект для user_id с этапами и задачами шаблона.
+// Сроки шаблона отсчитываются от start_date.
+func CreateProjectFromTemplate(c *gin.Context) {
+	tpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+	var req fromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !ownerExists(c, req.UserID) {
+		return
+	}
+
+	now := time.Now()
+	start := now.UTC().Truncate(24 * time.Hour)
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	project := models.Project{
+		UserID:         req.UserID,
+		Title:          req.Title,
+		Description:    tpl.Description,
+		Status:         models.ProjectPending,
+		StartDate:      &start,
+		DueDate:        models.DueIn(start, tpl.DurationDays),
+		EstimatedHours: tpl.EstimatedHours,
+		TemplateID:     &tpl.ID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if project.Title == "" {
+		project.Title = tpl.Name
+	}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+
+	userID := c.MustGet(middleware.ContextUserID).(uint)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+		if err := recordHistory(tx, &project, "", 0, userID, "created from template "+tpl.Name); err != nil {
+			return err
+		}
+		for i, tm := range tpl.Milestones {
+			m := models.Milestone{ProjectID: project.ID, Title: tm.Title, DueDate: models.DueIn(start, tm.DueInDays), Position: i}
+			if err := tx.Create(&m).Error; err != nil {
+				return err
+			}
+			if err := createTemplateTasks(tx, &project, &m.ID, start, tm.Tasks); err != nil {
+				return err
+			}
+		}
+		return createTemplateTasks(tx, &project, nil, start, tpl.Tasks)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create project"})
+		return
+	}
+	breakdown, err := loadBreakdown(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch tasks"})
+		return
+	}
+
+	logs.SendLog(req.UserID, "Admin created project from template "+tpl.Name+": "+project.Title)
+	c.JSON(http.StatusCreated, struct {
+		models.Project
+		projectBreakdown
+	}{project, *breakdown})
+}
+
+func createTemplateTasks(tx *gorm.DB, project *models.Project, milestoneID *uint, start time.Time, tasks []models.TemplateTask) error {
+	for _, tt := range tasks {
+		t := models.Task{
+			ProjectID:   project.ID,
+			MilestoneID: milestoneID,
+			Title:       tt.Title,
+			Weight:      tt.Weight,
+			DueDate:     models.DueIn(start, tt.DueInDays),
+		}
+		if err := tx.Create(&t).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findTemplate загружает шаблон из :templateId вместе с этапами и задачами
+func findTemplate(c *gin.Context) (*models.ProjectTemplate, bool) {
+	var tpl models.ProjectTemplate
+	if err := db.DB.First(&tpl, c.Param("templateId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return nil, false
+	}
+	templates := []models.ProjectTemplate{tpl}
+	if err := loadTemplateStructure(templates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch template"})
+		return nil, false
+	}
+	return &templates[0], true
+}
+
+// loadTemplateStructure заполняет этапы и задачи шаблонов двумя запросами
+func loadTemplateStructure(templates []models.ProjectTemplate) error {
+	ids := make([]uint, len(templates))
+	for i, t := range templates {
+		ids[i] = t.ID
+	}
+	var milestones []models.TemplateMilestone
+	var tasks []models.TemplateTask
+	if err := db.DB.Where("template_id IN ?", ids).Order("position, id").Find(&milestones).Error; err != nil {
+		return err
+	}
+	if err := db.DB.Where("template_id IN ?", ids).Order("position, id").Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	byMilestone := map[uint][]models.TemplateTask{}
+	looseTasks := map[uint][]models.TemplateTask{}
+	for _, t := range tasks {
+		if t.MilestoneID == nil {
+			looseTasks[t.TemplateID] = append(looseTasks[t.TemplateID], t)
+		} else {
+			byMilestone[*t.MilestoneID] = append(byMilestone[*t.MilestoneID], t)
+		}
+	}
+	byTemplate := map[uint][]models.TemplateMilestone{}
+	for _, m := range milestones {
+		m.Tasks = byMilestone[m.ID]
+		if m.Tasks == nil {
+			m.Tasks = []models.TemplateTask{}
+		}
+		byTemplate[m.TemplateID] = append(byTemplate[m.TemplateID], m)
+	}
+	for i := range templates {
+		t := &templates[i]
+		t.Milestones = byTemplate[t.ID]
+		t.Tasks = looseTasks[t.ID]
+		if t.Milestones == nil {
+			t.Milestones = []models.TemplateMilestone{}
+		}
+		if t.Tasks == nil {
+			t.Tasks = []models.TemplateTask{}
+		}
+	}
+	return nil
+}
+
+// bindTemplate переносит запрос в шаблон и проверяет его; название шаблона уникально
+func bindTemplate(c *gin.Context, tpl *models.ProjectTemplate) bool {
+	var req templateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	tpl.Name = req.Name
+	tpl.Description = req.Description
+	tpl.DurationDays = req.DurationDays
+	tpl.EstimatedHours = req.EstimatedHours
+	tpl.Milestones = req.Milestones
+	tpl.Tasks = req.Tasks
+	if tpl.Milestones == nil {
+		tpl.Milestones = []models.TemplateMilestone{}
+	}
+	if tpl.Tasks == nil {
+		tpl.Tasks = []models.TemplateTask{}
+	}
+	if err := tpl.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	var count int64
+	if err := db.DB.Model(&models.ProjectTemplate{}).Where("name = ? AND id <> ?", tpl.Name, tpl.ID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save template"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errTemplateName.Error()})
+		return false
+	}
+	return true
+}
+
+// templateSaved отвечает на ошибку сохранения шаблона. Одновременное создание шаблонов
+// с одним названием проходит проверку в bindTemplate, но упирается в уникальный индекс - 409.
+func templateSaved(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case duplicateKey(err):
+		c.JSON(http.StatusConflict, gin.H{"error": errTemplateName.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save template"})
+	}
+	return false
+}
+
+// duplicateKey - ошибка нарушения уникального индекса
+func duplicateKey(err error) bool {
+	if t, ok := db.DB.Dialector.(gorm.ErrorTranslator); ok {
+		err = t.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// saveTemplateStructure записывает этапы и задачи шаблона с их порядком
+func saveTemplateStructure(tx *gorm.DB, tpl *models.ProjectTemplate) error {
+	for i := range tpl.Milestones {
+		m := &tpl.Milestones[i]
+		m.ID = 0
+		m.TemplateID = tpl.ID
+		m.Position = i
+		if err := tx.Create(m).Error; err != nil {
+			return err
+		}
+		if m.Tasks == nil {
+			m.Tasks = []models.TemplateTask{}
+		}
+		if err := saveTemplateTasks(tx, tpl.ID, &m.ID, m.Tasks); err != nil {
+			return err
+		}
+	}
+	return saveTemplateTasks(tx, tpl.ID, nil, tpl.Tasks)
+}
+
+func saveTemplateTasks(tx *gorm.DB, templateID uint, milestoneID *uint, tasks []models.TemplateTask) error {
+	for i := range tasks {
+		t := &tasks[i]
+		t.ID = 0
+		t.TemplateID = templateID
+		t.MilestoneID = milestoneID
+		t.Position = i
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteTemplateStructure(tx *gorm.DB, templateID uint) error {
+	for _, m := range []interface{}{&models.TemplateTask{}, &models.TemplateMilestone{}} {
+		if err := tx.Where("template_id = ?", templateID).Delete(m).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
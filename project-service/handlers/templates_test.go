@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTemplateNameConflict(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/projects/templates", CreateTemplate)
+	r.PUT("/api/projects/templates/:templateId", ReplaceTemplate)
+
+	landing := doJSON(t, r, http.MethodPost, "/api/projects/templates", gin.H{"name": "Лендинг"})
+	if landing.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusCreated, landing.Code, landing.Body.String())
+	}
+	shop := doJSON(t, r, http.MethodPost, "/api/projects/templates", gin.H{"name": "Интернет-магазин"})
+	if shop.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusCreated, shop.Code, shop.Body.String())
+	}
+	var created models.ProjectTemplate
+	decode(t, shop, &created)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"Повторное название при создании", http.MethodPost, "/api/projects/templates", http.StatusConflict},
+		{"Чужое название при замене", http.MethodPut, fmt.Sprintf("/api/projects/templates/%d", created.ID), http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, r, tt.method, tt.path, gin.H{"name": "Лендинг"})
+			if w.Code != tt.want {
+				t.Errorf("Ожидался статус %d, получен %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			var body map[string]string
+			decode(t, w, &body)
+			if body["error"] != errTemplateName.Error() {
+				t.Errorf("Неожиданная ошибка: %v", body)
+			}
+		})
+	}
+
+	// одновременное создание проходит проверку названия и упирается в уникальный индекс
+	err := db.DB.Create(&models.ProjectTemplate{Name: "Лендинг"}).Error
+	if err == nil {
+		t.Fatal("Ожидалось нарушение уникального индекса")
+	}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	if templateSaved(c, err) || w.Code != http.StatusConflict {
+		t.Errorf("Гонка при создании: ожидался статус %d, получен %d", http.StatusConflict, w.Code)
+	}
+}
@@ -0,0 +1,477 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"ooolalex/project-service/config"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/middleware"
+	"ooolalex/project-service/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// timeEntryRequest - ручная запись времени; task_id = 0 при правке снимает задачу,
+// user_id = 0 отдаёт запись текущему пользователю
+type timeEntryRequest struct {
+	TaskID    *uint      `json:"task_id"`
+	UserID    *uint      `json:"user_id"`
+	StartedAt *time.Time `json:"started_at"`
+	Minutes   *int       `json:"minutes"`
+	Note      *string    `json:"note"`
+	Billable  *bool      `json:"billable"`
+}
+
+type timerRequest struct {
+	TaskID   *uint  `json:"task_id"`
+	Note     string `json:"note"`
+	Billable *bool  `json:"billable"`
+}
+
+// rateCardRequest - project_id, client_id и user_id = 0 при правке снимают условие
+type rateCardRequest struct {
+	Name       *string  `json:"name"`
+	ProjectID  *uint    `json:"project_id"`
+	ClientID   *uint    `json:"client_id"`
+	UserID     *uint    `json:"user_id"`
+	HourlyRate *float64 `json:"hourly_rate"`
+}
+
+type timeEntriesList struct {
+	Items           []models.TimeEntry `json:"items"`
+	Minutes         int                `json:"minutes"`
+	BillableMinutes int                `json:"billable_minutes"`
+	Amount          float64            `json:"amount"`
+	Currency        string             `json:"currency"`
+}
+
+// timeHandler - учёт времени администраторов по проектам, таймеры и ставки
+type timeHandler struct {
+	currency string
+}
+
+func registerTimeRoutes(admin *gin.RouterGroup, cfg config.Config) {
+	h := &timeHandler{currency: cfg.BillingCurrency}
+
+	admin.GET(":id/time-entries", adminScope(h.list))
+	admin.POST(":id/time-entries", adminScope(h.create))
+	admin.GET(":id/tasks/:taskId/time-entries", adminScope(h.list))
+	admin.POST(":id/tasks/:taskId/time-entries", adminScope(h.create))
+	admin.PATCH(":id/time-entries/:entryId", adminScope(h.update))
+	admin.DELETE(":id/time-entries/:entryId", adminScope(h.delete))
+
+	// у каждого администратора свой таймер
+	admin.GET("timer", h.currentTimer)
+	admin.POST("timer/stop", h.stopTimer)
+	admin.POST(":id/timer", adminScope(h.startTimer))
+	admin.POST(":id/tasks/:taskId/timer", adminScope(h.startTimer))
+
+	admin.GET("rates", h.listRates)
+	admin.POST("rates", h.createRate)
+	admin.PATCH("rates/:rateId", h.updateRate)
+	admin.DELETE("rates/:rateId", h.deleteRate)
+
+	admin.GET("time-report", h.report)
+}
+
+// list - записи проекта или задачи; ?user_id, ?from, ?to (по started_at), ?billable
+func (h *timeHandler) list(c *gin.Context, project *models.Project, admin bool) {
+	q := db.DB.Where("project_id = ?", project.ID)
+	if taskID, ok := taskParam(c, project); !ok {
+		return
+	} else if taskID != nil {
+		q = q.Where("task_id = ?", *taskID)
+	}
+	if v := c.Query("user_id"); v != "" {
+		q = q.Where("user_id = ?", v)
+	}
+	q, ok := timeRange(c, q, "started_at")
+	if !ok {
+		return
+	}
+	if v := c.Query("billable"); v != "" {
+		billable, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid billable"})
+			return
+		}
+		q = q.Where("billable = ?", billable)
+	}
+
+	out := timeEntriesList{Items: []models.TimeEntry{}, Currency: h.currency}
+	if err := q.Order("started_at, id").Find(&out.Items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch time entries"})
+		return
+	}
+	for _, e := range out.Items {
+		out.Minutes += e.Minutes
+		if e.Billable {
+			out.BillableMinutes += e.Minutes
+		}
+		out.Amount += e.Amount
+	}
+	out.Amount = roundMoney(out.Amount)
+	c.JSON(http.StatusOK, out)
+}
+
+// create - запись уже затраченного времени: minutes обязательны, started_at по умолчанию - minutes назад
+func (h *timeHandler) create(c *gin.Context, project *models.Project, admin bool) {
+	if project.Status == models.ProjectCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "project is cancelled"})
+		return
+	}
+	var req timeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Minutes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minutes is required"})
+		return
+	}
+	taskID, ok := taskParam(c, project)
+	if !ok {
+		return
+	}
+	if taskID == nil && req.TaskID != nil && *req.TaskID != 0 {
+		if !taskInProject(c, project, *req.TaskID) {
+			return
+		}
+		taskID = req.TaskID
+	}
+
+	entry := models.TimeEntry{
+		ProjectID: project.ID,
+		TaskID:    taskID,
+		UserID:    c.MustGet(middleware.ContextUserID).(uint),
+		Billable:  true,
+	}
+	if req.UserID != nil && *req.UserID != 0 {
+		entry.UserID = *req.UserID
+	}
+	entry.SetSpan(time.Now().Add(-time.Duration(*req.Minutes)*time.Minute).Truncate(time.Second), *req.Minutes)
+	if !applyTimeEntry(c, &entry, &req) {
+		return
+	}
+	rate, err := projectRate(project, entry.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create time entry"})
+		return
+	}
+	entry.HourlyRate = rate
+	if err := db.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create time entry"})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// update правит запись; у запущенного таймера можно менять всё, кроме длительности.
+// Смена сотрудника заново подбирает ставку.
+func (h *timeHandler) update(c *gin.Context, project *models.Project, admin bool) {
+	var entry models.TimeEntry
+	if err := db.DB.Where("id = ? AND project_id = ?", c.Param("entryId"), project.ID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "time entry not found"})
+		return
+	}
+	var req timeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if entry.Running() && req.Minutes != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "timer is running"})
+		return
+	}
+	if req.TaskID != nil {
+		if *req.TaskID != 0 && !taskInProject(c, project, *req.TaskID) {
+			return
+		}
+		entry.TaskID = nullableID(*req.TaskID)
+	}
+	if req.UserID != nil {
+		userID := *req.UserID
+		if userID == 0 {
+			userID = c.MustGet(middleware.ContextUserID).(uint)
+		}
+		if userID != entry.UserID {
+			rate, err := projectRate(project, userID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update time entry"})
+				return
+			}
+			entry.UserID = userID
+			entry.HourlyRate = rate
+		}
+	}
+	if !applyTimeEntry(c, &entry, &req) {
+		return
+	}
+	if err := db.DB.Save(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update time entry"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *timeHandler) delete(c *gin.Context, project *models.Project, admin bool) {
+	res := db.DB.Where("id = ? AND project_id = ?", c.Param("entryId"), project.ID).Delete(&models.TimeEntry{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete time entry"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "time entry not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "time entry deleted"})
+}
+
+// startTimer запускает таймер текущего администратора; уже идущий таймер останавливается
+func (h *timeHandler) startTimer(c *gin.Context, project *models.Project, admin bool) {
+	if project.Status == models.ProjectCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "project is cancelled"})
+		return
+	}
+	var req timerRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	taskID, ok := taskParam(c, project)
+	if !ok {
+		return
+	}
+	if taskID == nil && req.TaskID != nil && *req.TaskID != 0 {
+		if !taskInProject(c, project, *req.TaskID) {
+			return
+		}
+		taskID = req.TaskID
+	}
+	userID := c.MustGet(middleware.ContextUserID).(uint)
+	rate, err := projectRate(project, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot start timer"})
+		return
+	}
+
+	now := time.Now()
+	entry := models.TimeEntry{
+		ProjectID:  project.ID,
+		TaskID:     taskID,
+		UserID:     userID,
+		StartedAt:  now,
+		Note:       req.Note,
+		Billable:   req.Billable == nil || *req.Billable,
+		HourlyRate: rate,
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := stopRunning(tx, userID, now); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot start timer"})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *timeHandler) stopTimer(c *gin.Context) {
+	entry, err := stopRunning(db.DB, c.MustGet(middleware.ContextUserID).(uint), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no running timer"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot stop timer"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *timeHandler) currentTimer(c *gin.Context) {
+	var entry models.TimeEntry
+	if err := db.DB.Where("user_id = ? AND ended_at IS NULL", c.MustGet(middleware.ContextUserID).(uint)).
+		First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no running timer"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *timeHandler) listRates(c *gin.Context) {
+	rates := []models.RateCard{}
+	if err := db.DB.Order("id").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch rates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": rates, "currency": h.currency})
+}
+
+func (h *timeHandler) createRate(c *gin.Context) {
+	var req rateCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.HourlyRate == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hourly_rate is required"})
+		return
+	}
+	var rate models.RateCard
+	if !applyRateCard(c, &rate, &req) {
+		return
+	}
+	if err := db.DB.Create(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create rate"})
+		return
+	}
+	c.JSON(http.StatusCreated, rate)
+}
+
+// updateRate меняет карту; уже сделанные записи времени сохраняют свою ставку
+func (h *timeHandler) updateRate(c *gin.Context) {
+	var rate models.RateCard
+	if err := db.DB.First(&rate, c.Param("rateId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rate not found"})
+		return
+	}
+	var req rateCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !applyRateCard(c, &rate, &req) {
+		return
+	}
+	if err := db.DB.Save(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update rate"})
+		return
+	}
+	c.JSON(http.StatusOK, rate)
+}
+
+func (h *timeHandler) deleteRate(c *gin.Context) {
+	res := db.DB.Delete(&models.RateCard{}, c.Param("rateId"))
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete rate"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rate not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "rate deleted"})
+}
+
+// applyTimeEntry переносит время, заметку и признак оплаты; у запущенного таймера меняется только начало
+func applyTimeEntry(c *gin.Context, e *models.TimeEntry, req *timeEntryRequest) bool {
+	if req.Minutes != nil && (*req.Minutes < 1 || *req.Minutes > models.MaxEntryMinutes) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minutes must be between 1 and 1440"})
+		return false
+	}
+	start, minutes := e.StartedAt, e.Minutes
+	if req.StartedAt != nil {
+		if req.StartedAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "started_at cannot be in the future"})
+			return false
+		}
+		start = *req.StartedAt
+	}
+	if req.Minutes != nil {
+		minutes = *req.Minutes
+	}
+	if e.Running() {
+		e.StartedAt = start
+	} else {
+		e.SetSpan(start, minutes)
+	}
+	if req.Note != nil {
+		e.Note = *req.Note
+	}
+	if req.Billable != nil {
+		e.Billable = *req.Billable
+	}
+	return true
+}
+
+func applyRateCard(c *gin.Context, r *models.RateCard, req *rateCardRequest) bool {
+	if req.HourlyRate != nil && *req.HourlyRate < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hourly_rate cannot be negative"})
+		return false
+	}
+	if req.ProjectID != nil && *req.ProjectID != 0 {
+		var count int64
+		db.DB.Model(&models.Project{}).Where("id = ?", *req.ProjectID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "project not found"})
+			return false
+		}
+	}
+	if req.Name != nil {
+		r.Name = *req.Name
+	}
+	if req.ProjectID != nil {
+		r.ProjectID = nullableID(*req.ProjectID)
+	}
+	if req.ClientID != nil {
+		r.ClientID = nullableID(*req.ClientID)
+	}
+	if req.UserID != nil {
+		r.UserID = nullableID(*req.UserID)
+	}
+	if req.HourlyRate != nil {
+		r.HourlyRate = *req.HourlyRate
+	}
+	return true
+}
+
+// projectRate - ставка сотрудника userID в проекте по картам ставок
+func projectRate(project *models.Project, userID uint) (float64, error) {
+	var cards []models.RateCard
+	if err := db.DB.Where("project_id IS NULL OR project_id = ?", project.ID).Find(&cards).Error; err != nil {
+		return 0, err
+	}
+	return models.PickRate(cards, project.ID, project.UserID, userID), nil
+}
+
+// stopRunning останавливает запущенный таймер пользователя; gorm.ErrRecordNotFound, если его нет
+func stopRunning(tx *gorm.DB, userID uint, now time.Time) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	if err := tx.Where("user_id = ? AND ended_at IS NULL", userID).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	entry.Stop(now)
+	if err := tx.Save(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// timeRange ограничивает column параметрами ?from и ?to
+func timeRange(c *gin.Context, q *gorm.DB, column string) (*gorm.DB, bool) {
+	if v := c.Query("from"); v != "" {
+		from, err := parseFilterTime(v, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return nil, false
+		}
+		q = q.Where(column+" >= ?", from)
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseFilterTime(v, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return nil, false
+		}
+		q = q.Where(column+" <= ?", to)
+	}
+	return q, true
+}
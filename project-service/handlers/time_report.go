@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"ooolalex/project-service/clients"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Разрезы отчёта по времени
+var reportGroups = map[string]bool{"project": true, "client": true, "user": true, "task": true}
+
+// Периоды отчёта; границы считаются в UTC, неделя - ISO
+var reportPeriods = map[string]string{"day": time.DateOnly, "week": "", "month": "2006-01"}
+
+// reportEntry - завершённая запись времени вместе с клиентом проекта
+type reportEntry struct {
+	ProjectID uint
+	TaskID    *uint
+	UserID    uint
+	ClientID  uint
+	StartedAt time.Time
+	Minutes   int
+	Billable  bool
+	Amount    float64
+}
+
+type timeReportRow struct {
+	Period          string  `json:"period,omitempty"`
+	ID              uint    `json:"id"`
+	Name            string  `json:"name"`
+	Minutes         int     `json:"minutes"`
+	Hours           float64 `json:"hours"`
+	BillableMinutes int     `json:"billable_minutes"`
+	BillableHours   float64 `json:"billable_hours"`
+	Amount          float64 `json:"amount"`
+}
+
+type timeReport struct {
+	GroupBy  string          `json:"group_by"`
+	Period   string          `json:"period,omitempty"`
+	Currency string          `json:"currency"`
+	Items    []timeReportRow `json:"items"`
+	Total    timeReportRow   `json:"total"`
+}
+
+// report - часы и суммы по проектам, клиентам, сотрудникам или задачам, при ?period - ещё и по
+// дням, неделям или месяцам. Фильтры: ?from, ?to, ?project_id, ?client_id, ?user_id, ?billable.
+// ?format=csv отдаёт отчёт файлом. Запущенные таймеры в отчёт не входят.
+func (h *timeHandler) report(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", "project")
+	if !reportGroups[groupBy] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be project, client, user or task"})
+		return
+	}
+	period := c.Query("period")
+	if _, ok := reportPeriods[period]; period != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week or month"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	q := db.DB.Table("time_entries").
+		Select("time_entries.project_id, time_entries.task_id, time_entries.user_id, projects.user_id AS client_id, " +
+			"time_entries.started_at, time_entries.minutes, time_entries.billable, time_entries.amount").
+		Joins("JOIN projects ON projects.id = time_entries.project_id").
+		Where("time_entries.ended_at IS NOT NULL")
+	for param, column := range map[string]string{"project_id": "time_entries.project_id", "client_id": "projects.user_id", "user_id": "time_entries.user_id"} {
+		if v := c.Query(param); v != "" {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+				return
+			}
+			q = q.Where(column+" = ?", id)
+		}
+	}
+	if v := c.Query("billable"); v != "" {
+		billable, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid billable"})
+			return
+		}
+		q = q.Where("time_entries.billable = ?", billable)
+	}
+	q, ok := timeRange(c, q, "time_entries.started_at")
+	if !ok {
+		return
+	}
+	var entries []reportEntry
+	if err := q.Scan(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot build report"})
+		return
+	}
+
+	report := buildTimeReport(entries, groupBy, period)
+	report.Currency = h.currency
+	if err := nameReportRows(report.Items, groupBy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot build report"})
+		return
+	}
+	if format == "csv" {
+		writeTimeReportCSV(c, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func buildTimeReport(entries []reportEntry, groupBy, period string) *timeReport {
+	type key struct {
+		period string
+		id     uint
+	}
+	rows := map[key]*timeReportRow{}
+	report := &timeReport{GroupBy: groupBy, Period: period, Items: []timeReportRow{}}
+	for _, e := range entries {
+		k := key{id: reportGroupID(e, groupBy)}
+		if period != "" {
+			k.period = periodKey(e.StartedAt, period)
+		}
+		row, ok := rows[k]
+		if !ok {
+			row = &timeReportRow{Period: k.period, ID: k.id}
+			rows[k] = row
+		}
+		for _, r := range []*timeReportRow{row, &report.Total} {
+			r.Minutes += e.Minutes
+			if e.Billable {
+				r.BillableMinutes += e.Minutes
+			}
+			r.Amount += e.Amount
+		}
+	}
+	for _, row := range rows {
+		report.Items = append(report.Items, *row)
+	}
+	sort.Slice(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		return a.ID < b.ID
+	})
+	for i := range report.Items {
+		report.Items[i].round()
+	}
+	report.Total.round()
+	return report
+}
+
+func (r *timeReportRow) round() {
+	r.Hours = math.Round(float64(r.Minutes)/60*100) / 100
+	r.BillableHours = math.Round(float64(r.BillableMinutes)/60*100) / 100
+	r.Amount = roundMoney(r.Amount)
+}
+
+func reportGroupID(e reportEntry, groupBy string) uint {
+	switch groupBy {
+	case "client":
+		return e.ClientID
+	case "user":
+		return e.UserID
+	case "task":
+		if e.TaskID != nil {
+			return *e.TaskID
+		}
+		return 0
+	default:
+		return e.ProjectID
+	}
+}
+
+func periodKey(t time.Time, period string) string {
+	t = t.UTC()
+	if period == "week" {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format(reportPeriods[period])
+}
+
+// nameReportRows подписывает строки: проекты и задачи - названиями, клиентов и сотрудников -
+// именем из auth-service. Если auth-service недоступен, name остаётся пустым.
+func nameReportRows(rows []timeReportRow, groupBy string) error {
+	ids := []uint{}
+	for _, r := range rows {
+		if r.ID != 0 {
+			ids = append(ids, r.ID)
+		}
+	}
+	names := map[uint]string{}
+	switch groupBy {
+	case "project", "task":
+		var titles []struct {
+			ID    uint
+			Title string
+		}
+		var model interface{} = &models.Project{}
+		if groupBy == "task" {
+			model = &models.Task{}
+		}
+		if err := db.DB.Model(model).Select("id, title").Where("id IN ?", ids).Scan(&titles).Error; err != nil {
+			return err
+		}
+		for _, t := range titles {
+			names[t.ID] = t.Title
+		}
+	default:
+		users, err := clients.NewAuthClient().FindUsers(ids)
+		if err != nil {
+			log.Printf("cannot fetch report users: %v", err)
+		}
+		for id, u := range users {
+			names[id] = u.FullName
+			if names[id] == "" {
+				names[id] = u.Email
+			}
+		}
+	}
+	for i := range rows {
+		rows[i].Name = names[rows[i].ID]
+	}
+	return nil
+}
+
+func writeTimeReportCSV(c *gin.Context, report *timeReport) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="time-report.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	header := []string{report.GroupBy + "_id", "name", "hours", "billable_hours", "amount", "currency"}
+	if report.Period != "" {
+		header = append([]string{"period"}, header...)
+	}
+	w.Write(header)
+	write := func(r timeReportRow, id string) {
+		record := []string{id, csvText(r.Name),
+			strconv.FormatFloat(r.Hours, 'f', 2, 64),
+			strconv.FormatFloat(r.BillableHours, 'f', 2, 64),
+			strconv.FormatFloat(r.Amount, 'f', 2, 64),
+			report.Currency}
+		if report.Period != "" {
+			record = append([]string{r.Period}, record...)
+		}
+		w.Write(record)
+	}
+	for _, r := range report.Items {
+		write(r, strconv.FormatUint(uint64(r.ID), 10))
+	}
+	write(report.Total, "total")
+	w.Flush()
+}
+
+// csvText не даёт табличным редакторам принять имя за формулу
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,192 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"ooolalex/project-service/config"
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+	"ooolalex/project-service/notify"
+	"time"
+)
+
+// DeadlineChecker периодически отмечает просроченные проекты и задачи и рассылает напоминания
+// владельцу проекта и назначенным администраторам. Каждое напоминание уходит один раз
+// на срок: повторно - только после смены due_date.
+type DeadlineChecker struct {
+	notifier notify.Notifier
+	interval time.Duration
+	dueSoon  time.Duration
+}
+
+func NewDeadlineChecker(cfg config.Config, notifier notify.Notifier) *DeadlineChecker {
+	return &DeadlineChecker{
+		notifier: notifier,
+		interval: cfg.DeadlineCheckInterval,
+		dueSoon:  cfg.DueSoonWindow,
+	}
+}
+
+// Start запускает проверку сразу и затем каждые interval; interval 0 отключает проверку
+func (d *DeadlineChecker) Start() {
+	if d.interval <= 0 {
+		log.Printf("deadline checker disabled")
+		return
+	}
+	go func() {
+		for {
+			if err := d.Run(time.Now()); err != nil {
+				log.Printf("deadline check failed: %v", err)
+			}
+			time.Sleep(d.interval)
+		}
+	}()
+}
+
+// Run выполняет одну проверку на момент now
+func (d *DeadlineChecker) Run(now time.Time) error {
+	if err := d.clearResolved(now); err != nil {
+		return err
+	}
+	if err := d.checkProjects(now); err != nil {
+		return err
+	}
+	return d.checkTasks(now)
+}
+
+// clearResolved снимает отметку просрочки с завершённых проектов и задач
+func (d *DeadlineChecker) clearResolved(now time.Time) error {
+	inactive := []models.ProjectStatus{models.ProjectDone, models.ProjectCancelled}
+	if err := db.DB.Model(&models.Project{}).
+		Where("overdue_at IS NOT NULL AND (due_date IS NULL OR due_date >= ? OR status IN ? OR archived_at IS NOT NULL)", now, inactive).
+		UpdateColumn("overdue_at", nil).Error; err != nil {
+		return err
+	}
+	return db.DB.Model(&models.Task{}).
+		Where("overdue_at IS NOT NULL AND (due_date IS NULL OR due_date >= ? OR done = ?)", now, true).
+		UpdateColumn("overdue_at", nil).Error
+}
+
+func (d *DeadlineChecker) checkProjects(now time.Time) error {
+	var projects []models.Project
+	if err := db.DB.Where("due_date IS NOT NULL AND archived_at IS NULL AND status NOT IN ?",
+		[]models.ProjectStatus{models.ProjectDone, models.ProjectCancelled}).Find(&projects).Error; err != nil {
+		return err
+	}
+	for i := range projects {
+		p := &projects[i]
+		var kind, subject, column string
+		switch {
+		case p.Overdue(now) && p.OverdueAt == nil:
+			kind, column = notify.ProjectOverdue, "overdue_at"
+			subject = fmt.Sprintf("Project %q is overdue", p.Title)
+		case p.DueSoon(now, d.dueSoon) && p.DueReminderAt == nil:
+			kind, column = notify.ProjectDueSoon, "due_reminder_at"
+			subject = fmt.Sprintf("Project %q is due on %s", p.Title, p.DueDate.Format(time.DateOnly))
+		default:
+			continue
+		}
+		if err := db.DB.Model(p).UpdateColumn(column, now).Error; err != nil {
+			return err
+		}
+		recipients, err := projectRecipients(p)
+		if err != nil {
+			return err
+		}
+		d.send(recipients, notify.Notification{
+			Kind:      kind,
+			ProjectID: p.ID,
+			Subject:   subject,
+			Body:      fmt.Sprintf("Due date: %s. Status: %s, progress: %d%%.", p.DueDate.Format(time.DateOnly), p.Status, p.Progress),
+			DueDate:   p.DueDate,
+		})
+	}
+	return nil
+}
+
+func (d *DeadlineChecker) checkTasks(now time.Time) error {
+	var tasks []models.Task
+	if err := db.DB.Joins("JOIN projects ON projects.id = tasks.project_id").
+		Where("tasks.due_date IS NOT NULL AND tasks.done = ? AND projects.archived_at IS NULL AND projects.status <> ?", false, models.ProjectCancelled).
+		Find(&tasks).Error; err != nil {
+		return err
+	}
+	projects := map[uint]*models.Project{}
+	for i := range tasks {
+		t := &tasks[i]
+		var kind, subject, column string
+		switch {
+		case t.Overdue(now) && t.OverdueAt == nil:
+			kind, column = notify.TaskOverdue, "overdue_at"
+			subject = fmt.Sprintf("Task %q is overdue", t.Title)
+		case t.DueSoon(now, d.dueSoon) && t.DueReminderAt == nil:
+			kind, column = notify.TaskDueSoon, "due_reminder_at"
+			subject = fmt.Sprintf("Task %q is due on %s", t.Title, t.DueDate.Format(time.DateOnly))
+		default:
+			continue
+		}
+		if err := db.DB.Model(t).UpdateColumn(column, now).Error; err != nil {
+			return err
+		}
+		p, ok := projects[t.ProjectID]
+		if !ok {
+			p = &models.Project{}
+			if err := db.DB.First(p, t.ProjectID).Error; err != nil {
+				return err
+			}
+			projects[t.ProjectID] = p
+		}
+		recipients := uniqueIDs(p.UserID)
+		if t.AssigneeID != nil {
+			recipients = uniqueIDs(append(recipients, *t.AssigneeID)...)
+		}
+		taskID := t.ID
+		d.send(recipients, notify.Notification{
+			Kind:      kind,
+			ProjectID: p.ID,
+			TaskID:    &taskID,
+			Subject:   subject,
+			Body:      fmt.Sprintf("Project: %s. Due date: %s.", p.Title, t.DueDate.Format(time.DateOnly)),
+			DueDate:   t.DueDate,
+		})
+	}
+	return nil
+}
+
+// send доставляет уведомление каждому получателю; ошибки только пишутся в лог,
+// чтобы сбой доставки не повторял напоминание остальным при следующей проверке
+func (d *DeadlineChecker) send(recipients []uint, n notify.Notification) {
+	for _, userID := range recipients {
+		n.UserID = userID
+		if err := d.notifier.Notify(n); err != nil {
+			log.Printf("notify user %d about %s: %v", userID, n.Kind, err)
+		}
+	}
+}
+
+// projectRecipients - владелец проекта и администраторы, назначенные на его этапы и задачи
+func projectRecipients(p *models.Project) ([]uint, error) {
+	var assignees []uint
+	for _, m := range []interface{}{&models.Task{}, &models.Milestone{}} {
+		var ids []uint
+		if err := db.DB.Model(m).Where("project_id = ? AND assignee_id IS NOT NULL", p.ID).
+			Distinct().Pluck("assignee_id", &ids).Error; err != nil {
+			return nil, err
+		}
+		assignees = append(assignees, ids...)
+	}
+	return uniqueIDs(append([]uint{p.UserID}, assignees...)...), nil
+}
+
+// uniqueIDs убирает повторы и нулевой id (удалённый пользователь)
+func uniqueIDs(ids ...uint) []uint {
+	seen := map[uint]bool{}
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"ooolalex/project-service/db"
+	"ooolalex/project-service/models"
+	"ooolalex/project-service/notify"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) {
+	// Используем in-memory SQLite для тестов
+	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect test database: %v", err)
+	}
+	// у каждого соединения с :memory: своя база
+	sqlDB, err := testDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := testDB.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db.DB = testDB
+}
+
+// fakeNotifier запоминает уведомления вместо отправки
+type fakeNotifier struct {
+	sent []notify.Notification
+}
+
+func (n *fakeNotifier) Notify(msg notify.Notification) error {
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// take возвращает уведомления, отправленные с прошлого вызова, как отсортированные "вид:получатель"
+func (n *fakeNotifier) take() []string {
+	out := []string{}
+	for _, msg := range n.sent {
+		out = append(out, fmt.Sprintf("%s:%d", msg.Kind, msg.UserID))
+	}
+	sort.Strings(out)
+	n.sent = nil
+	return out
+}
+
+func TestDeadlineCheckerRun(t *testing.T) {
+	setupTestDB(t)
+	notifier := &fakeNotifier{}
+	checker := &DeadlineChecker{notifier: notifier, dueSoon: 48 * time.Hour}
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	due := now.Add(24 * time.Hour)
+	project := models.Project{UserID: 2, Title: "Лендинг", Status: models.ProjectInProgress, Progress: 40, DueDate: &due}
+	if err := db.DB.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	assignee := uint(5)
+	taskDue := now.Add(72 * time.Hour)
+	task := models.Task{ProjectID: project.ID, Title: "Макет", AssigneeID: &assignee, DueDate: &taskDue}
+	if err := db.DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	// без напоминаний: срока нет, проект выполнен или заархивирован
+	past := now.Add(-24 * time.Hour)
+	for _, p := range []models.Project{
+		{UserID: 3, Title: "Без срока", Status: models.ProjectInProgress},
+		{UserID: 3, Title: "Выполнен", Status: models.ProjectDone, Progress: 100, DueDate: &past},
+		{UserID: 3, Title: "В архиве", Status: models.ProjectOnHold, DueDate: &past, ArchivedAt: &past},
+	} {
+		if err := db.DB.Create(&p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// каждый шаг - одна проверка; move меняет данные перед ней
+	steps := []struct {
+		name string
+		at   time.Time
+		move func(t *testing.T)
+		want []string
+	}{
+		{"Срок проекта скоро", now, nil, []string{"project_due_soon:2", "project_due_soon:5"}},
+		{"Повторная проверка молчит", now.Add(time.Hour), nil, []string{}},
+		{"Срок задачи скоро", now.Add(25 * time.Hour), nil, []string{"project_overdue:2", "project_overdue:5", "task_due_soon:2", "task_due_soon:5"}},
+		{"Просрочка не повторяется", now.Add(26 * time.Hour), nil, []string{}},
+		{"Задача просрочена", now.Add(73 * time.Hour), nil, []string{"task_overdue:2", "task_overdue:5"}},
+		{"Всё уже отправлено", now.Add(80 * time.Hour), nil, []string{}},
+		{"Новый срок проекта - напоминания снова", now.Add(81 * time.Hour), func(t *testing.T) {
+			var p models.Project
+			if err := db.DB.First(&p, project.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			newDue := now.Add(100 * time.Hour)
+			if err := p.SetSchedule(nil, &newDue); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.DB.Save(&p).Error; err != nil {
+				t.Fatal(err)
+			}
+		}, []string{"project_due_soon:2", "project_due_soon:5"}},
+		{"Новый срок снова прошёл", now.Add(101 * time.Hour), nil, []string{"project_overdue:2", "project_overdue:5"}},
+		{"Новый срок задачи", now.Add(102 * time.Hour), func(t *testing.T) {
+			var tk models.Task
+			if err := db.DB.First(&tk, task.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			tk.SetDueDate(now.Add(120 * time.Hour))
+			if err := db.DB.Save(&tk).Error; err != nil {
+				t.Fatal(err)
+			}
+		}, []string{"task_due_soon:2", "task_due_soon:5"}},
+		{"Выполненный проект не напоминает", now.Add(130 * time.Hour), func(t *testing.T) {
+			if err := db.DB.Model(&models.Project{}).Where("id = ?", project.ID).
+				UpdateColumns(map[string]interface{}{"status": models.ProjectDone, "progress": 100}).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.DB.Model(&models.Task{}).Where("id = ?", task.ID).UpdateColumn("done", true).Error; err != nil {
+				t.Fatal(err)
+			}
+		}, []string{}},
+	}
+	for _, step := range steps {
+		if step.move != nil {
+			step.move(t)
+		}
+		if err := checker.Run(step.at); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := notifier.take(); fmt.Sprint(got) != fmt.Sprint(step.want) {
+			t.Errorf("%s: ожидалось %v, получено %v", step.name, step.want, got)
+		}
+	}
+
+	// завершённые проект и задача больше не отмечены просроченными
+	var p models.Project
+	if err := db.DB.First(&p, project.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var tk models.Task
+	if err := db.DB.First(&tk, task.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if p.OverdueAt != nil || tk.OverdueAt != nil {
+		t.Errorf("Отметка просрочки должна сниматься: проект %v, задача %v", p.OverdueAt, tk.OverdueAt)
+	}
+}
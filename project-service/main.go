@@ -4,7 +4,10 @@ import (
 	"log"
 	"ooolalex/project-service/config"
 	"ooolalex/project-service/db"
+	"ooolalex/project-service/jobs"
+	"ooolalex/project-service/notify"
 	"ooolalex/project-service/routes"
+	"ooolalex/project-service/storage"
 	"os"
 
 	"github.com/gin-gonic/gin"
@@ -16,9 +19,26 @@ func main() {
 		cfg.DBPath = "./project.db"
 	}
 
+	// ключ подписи ссылок на файлы - отдельный секрет, без него сервис не запускается
+	if len(cfg.AttachmentSigningKey) == 0 {
+		log.Fatal("ATTACHMENT_SIGNING_KEY is required")
+	}
+
 	db.InitDB(cfg.DBPath)
+
+	notifier, err := notify.New(cfg.Notifier, cfg.NotifyWebhookURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jobs.NewDeadlineChecker(cfg, notifier).Start()
+
+	store, err := storage.New(cfg.StorageBackend, cfg.AttachmentsDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	r := gin.Default()
-	routes.SetupRoutes(r, cfg)
+	routes.SetupRoutes(r, cfg, store)
 
 	port := os.Getenv("PORT")
 	if port == "" {
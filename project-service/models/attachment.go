@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Attachment - файл проекта или его задачи: бриф, макет, готовый результат.
+// Содержимое лежит в хранилище по Key, видимость - как у комментариев.
+type Attachment struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	ProjectID   uint              `gorm:"index;not null" json:"project_id"`
+	TaskID      *uint             `gorm:"index" json:"task_id"`
+	UploadedBy  uint              `gorm:"not null" json:"uploaded_by"`
+	FileName    string            `gorm:"not null" json:"file_name"`
+	ContentType string            `json:"content_type"`
+	Size        int64             `gorm:"not null" json:"size"`
+	Key         string            `gorm:"uniqueIndex;not null" json:"-"`
+	Visibility  CommentVisibility `gorm:"type:text;not null;default:client" json:"visibility"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"-"`
+}
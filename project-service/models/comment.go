@@ -0,0 +1,66 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CommentVisibility string
+
+const (
+	CommentClient   CommentVisibility = "client"   // видят владелец проекта и администраторы
+	CommentInternal CommentVisibility = "internal" // только администраторы
+)
+
+// MaxCommentLength - ограничение длины текста комментария в символах
+const MaxCommentLength = 10000
+
+// Comment - комментарий к проекту или к его задаче. Body хранится в markdown
+// и отображается клиентом; сырой HTML при отображении должен экранироваться.
+type Comment struct {
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	ProjectID  uint              `gorm:"index;not null" json:"project_id"`
+	TaskID     *uint             `gorm:"index" json:"task_id"`
+	AuthorID   uint              `gorm:"not null" json:"author_id"`
+	Body       string            `gorm:"type:text;not null" json:"body"`
+	Visibility CommentVisibility `gorm:"type:text;not null;default:client" json:"visibility"`
+	Mentions   []uint            `gorm:"-" json:"mentions"`
+	EditedAt   *time.Time        `json:"edited_at"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"-"`
+	DeletedAt  gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// CommentMention - упоминание пользователя в комментарии
+type CommentMention struct {
+	ID        uint `gorm:"primaryKey"`
+	CommentID uint `gorm:"uniqueIndex:idx_comment_mention;not null"`
+	UserID    uint `gorm:"uniqueIndex:idx_comment_mention;index;not null"`
+}
+
+// mentionPattern - упоминание в markdown вида @[Имя](user:42)
+var mentionPattern = regexp.MustCompile(`@\[[^\]\n]*\]\(user:(\d+)\)`)
+
+// ParseMentions - ID упомянутых пользователей без повторов, в порядке появления
+func ParseMentions(body string) []uint {
+	ids := []uint{}
+	seen := map[uint]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		id, err := strconv.ParseUint(m[1], 10, 32)
+		if err != nil || id == 0 || seen[uint(id)] {
+			continue
+		}
+		seen[uint(id)] = true
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// AfterFind заполняет Mentions из текста
+func (c *Comment) AfterFind(tx *gorm.DB) error {
+	c.Mentions = ParseMentions(c.Body)
+	return nil
+}
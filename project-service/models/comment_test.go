@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []uint
+	}{
+		{"Без упоминаний", "Готово, проверьте", []uint{}},
+		{"Одно упоминание", "@[Иван Петров](user:42) посмотри", []uint{42}},
+		{"Порядок появления", "@[Б](user:7) и @[А](user:3)", []uint{7, 3}},
+		{"Повторы убираются", "@[А](user:3), @[Б](user:7), снова @[А](user:3)", []uint{3, 7}},
+		{"Пустое имя допустимо", "@[](user:5)", []uint{5}},
+		{"Нулевой ID игнорируется", "@[Никто](user:0)", []uint{}},
+		{"Слишком большой ID игнорируется", "@[X](user:99999999999)", []uint{}},
+		{"Без @ не упоминание", "[Иван](user:42)", []uint{}},
+		{"Обычная ссылка не упоминание", "@[сайт](https://example.com)", []uint{}},
+		{"Перенос строки в имени", "@[Иван\nПетров](user:42)", []uint{}},
+		{"Email не упоминание", "пишите на user@example.com", []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseMentions(tt.body)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || got == nil {
+				t.Errorf("Ожидалось %v, получено %v", tt.want, got)
+			}
+		})
+	}
+}
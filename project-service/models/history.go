@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ProjectHistory - изменение статуса или прогресса проекта
+type ProjectHistory struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	ProjectID    uint          `gorm:"index;not null" json:"project_id"`
+	ChangedBy    uint          `json:"changed_by"`
+	FromStatus   ProjectStatus `gorm:"type:text" json:"from_status,omitempty"` // пусто при создании
+	ToStatus     ProjectStatus `gorm:"type:text" json:"to_status"`
+	FromProgress int           `json:"from_progress"`
+	ToProgress   int           `json:"to_progress"`
+	Note         string        `json:"note,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
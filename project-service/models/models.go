@@ -0,0 +1,10 @@
+package models
+
+// All - все модели project-service для AutoMigrate; новую модель достаточно добавить сюда,
+// чтобы её получили и сервис, и тестовые базы
+func All() []interface{} {
+	return []interface{}{
+		&Project{}, &Log{}, &ProjectHistory{}, &Milestone{}, &Task{}, &Comment{}, &CommentMention{},
+		&Attachment{}, &TimeEntry{}, &RateCard{}, &ProjectTemplate{}, &TemplateMilestone{}, &TemplateTask{},
+	}
+}
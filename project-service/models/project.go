@@ -1,14 +1,272 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrStatusChange - переход или сочетание статуса и прогресса запрещены правилами
+var ErrStatusChange = errors.New("status change not allowed")
+
+type ProjectStatus string
+
+const (
+	ProjectPending    ProjectStatus = "pending"
+	ProjectInProgress ProjectStatus = "in_progress"
+	ProjectReview     ProjectStatus = "review"
+	ProjectOnHold     ProjectStatus = "on_hold"
+	ProjectDone       ProjectStatus = "done"
+	ProjectCancelled  ProjectStatus = "cancelled"
+)
+
+// ProjectStatuses - все статусы в порядке жизненного цикла
+var ProjectStatuses = []ProjectStatus{ProjectPending, ProjectInProgress, ProjectReview, ProjectOnHold, ProjectDone, ProjectCancelled}
+
+// projectTransitions - разрешённые переходы между статусами; cancelled - конечный статус
+var projectTransitions = map[ProjectStatus][]ProjectStatus{
+	ProjectPending:    {ProjectInProgress, ProjectOnHold, ProjectCancelled},
+	ProjectInProgress: {ProjectReview, ProjectOnHold, ProjectCancelled},
+	ProjectReview:     {ProjectInProgress, ProjectDone, ProjectOnHold, ProjectCancelled},
+	ProjectOnHold:     {ProjectPending, ProjectInProgress, ProjectCancelled},
+	ProjectDone:       {ProjectInProgress}, // повторное открытие
+	ProjectCancelled:  {},
+}
 
 type Project struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	UserID      uint      `json:"user_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Progress    int       `json:"progress"`
-	Status      string    `gorm:"type:text" json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	UserID      uint   `json:"user_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Progress    int    `json:"progress"`
+	// ProgressManual - прогресс задан вручную и не пересчитывается по задачам
+	ProgressManual bool          `gorm:"default:false" json:"progress_manual"`
+	Status         ProjectStatus `gorm:"type:text;default:pending" json:"status"`
+	// ArchivedAt - проект заархивирован, например после удаления владельца
+	ArchivedAt     *time.Time `gorm:"index" json:"archived_at,omitempty"`
+	StartDate      *time.Time `json:"start_date"`
+	DueDate        *time.Time `gorm:"index" json:"due_date"`
+	EstimatedHours float64    `json:"estimated_hours"`
+	// OverdueAt и DueReminderAt ставит проверка сроков, когда отправлено напоминание;
+	// смена due_date их сбрасывает
+	OverdueAt     *time.Time `json:"overdue_at,omitempty"`
+	DueReminderAt *time.Time `json:"-"`
+	// TemplateID - шаблон, по которому создан проект
+	TemplateID *uint `gorm:"index" json:"template_id,omitempty"`
+	// Rank - позиция в колонке доски (см. RankBetween), Version растёт при каждом сохранении
+	// и защищает перемещения на доске от одновременной правки
+	Rank      string    `gorm:"index;not null;default:''" json:"rank"`
+	Version   int       `gorm:"not null;default:0" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate ставит новый проект в конец колонки его статуса
+func (p *Project) BeforeCreate(tx *gorm.DB) error {
+	if p.Rank != "" {
+		return nil
+	}
+	if p.Status == "" {
+		p.Status = ProjectPending
+	}
+	column := tx.Session(&gorm.Session{NewDB: true}).Model(&Project{}).Where("status = ?", p.Status)
+	rank, err := PlaceRank(column, 0, nil, nil)
+	p.Rank = rank
+	return err
+}
+
+// BeforeSave увеличивает версию проекта
+func (p *Project) BeforeSave(tx *gorm.DB) error {
+	p.Version++
+	return nil
+}
+
+// ScheduleTolerance - на сколько процентов прогресс может отставать от ожидаемого по срокам,
+// прежде чем проект считается отстающим
+const ScheduleTolerance = 15
+
+// Active сообщает, что работа по проекту ещё должна быть завершена
+func (p *Project) Active() bool {
+	return p.ArchivedAt == nil && p.Status != ProjectDone && p.Status != ProjectCancelled
+}
+
+// Overdue - срок прошёл, а проект не завершён
+func (p *Project) Overdue(now time.Time) bool {
+	return p.Active() && p.DueDate != nil && now.After(*p.DueDate)
+}
+
+// DueSoon - до срока осталось не больше within
+func (p *Project) DueSoon(now time.Time, within time.Duration) bool {
+	return p.Active() && p.DueDate != nil && !now.After(*p.DueDate) && p.DueDate.Sub(now) <= within
+}
+
+// ExpectedProgress - прогресс, которого следовало бы достичь к now при равномерной работе
+// от start_date (или даты создания) до due_date. ok == false, если срок не задан.
+func (p *Project) ExpectedProgress(now time.Time) (int, bool) {
+	if p.DueDate == nil {
+		return 0, false
+	}
+	start := p.CreatedAt
+	if p.StartDate != nil {
+		start = *p.StartDate
+	}
+	total := p.DueDate.Sub(start)
+	switch {
+	case total <= 0 || !now.Before(*p.DueDate):
+		return 100, true
+	case !now.After(start):
+		return 0, true
+	}
+	return int(float64(now.Sub(start)) * 100 / float64(total)), true
+}
+
+// SetSchedule меняет даты проекта; новая due_date заново включает напоминания
+func (p *Project) SetSchedule(start, due *time.Time) error {
+	if start == nil {
+		start = p.StartDate
+	}
+	if due == nil {
+		due = p.DueDate
+	}
+	if start != nil && due != nil && due.Before(*start) {
+		return fmt.Errorf("due_date must not be before start_date")
+	}
+	if due != nil && (p.DueDate == nil || !due.Equal(*p.DueDate)) {
+		p.OverdueAt = nil
+		p.DueReminderAt = nil
+	}
+	p.StartDate, p.DueDate = start, due
+	return nil
+}
+
+// Valid сообщает, известен ли статус
+func (s ProjectStatus) Valid() bool {
+	_, ok := projectTransitions[s]
+	return ok
+}
+
+// Next - статусы, в которые можно перейти из s
+func (s ProjectStatus) Next() []ProjectStatus {
+	return projectTransitions[s]
+}
+
+// CanTransition сообщает, разрешён ли переход из s в to
+func (s ProjectStatus) CanTransition(to ProjectStatus) bool {
+	for _, next := range s.Next() {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckProgress проверяет, что прогресс допустим для статуса
+func (s ProjectStatus) CheckProgress(progress int) error {
+	if progress < 0 || progress > 100 {
+		return fmt.Errorf("progress must be between 0 and 100")
+	}
+	switch s {
+	case ProjectPending:
+		if progress != 0 {
+			return fmt.Errorf("pending requires progress 0, move the project to in_progress first")
+		}
+	case ProjectInProgress:
+		if progress == 100 {
+			return fmt.Errorf("in_progress requires progress below 100, move the project to review")
+		}
+	case ProjectDone:
+		if progress != 100 {
+			return fmt.Errorf("done requires progress 100")
+		}
+	}
+	return nil
+}
+
+// ChangeState меняет статус и прогресс по правилам переходов.
+// Возвращает false без ошибки, если ничего не изменилось.
+func (p *Project) ChangeState(status ProjectStatus, progress int) (bool, error) {
+	if !status.Valid() {
+		return false, fmt.Errorf("unknown status %q", status)
+	}
+	if status == p.Status && progress == p.Progress {
+		return false, nil
+	}
+	if progress < 0 || progress > 100 {
+		return false, fmt.Errorf("progress must be between 0 and 100")
+	}
+	if p.Status == ProjectCancelled {
+		return false, fmt.Errorf("%w: project is cancelled", ErrStatusChange)
+	}
+	if status != p.Status && !p.Status.CanTransition(status) {
+		return false, fmt.Errorf("%w: %s -> %s", ErrStatusChange, p.Status, status)
+	}
+	if err := status.CheckProgress(progress); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrStatusChange, err)
+	}
+	p.Status = status
+	p.Progress = progress
+	return true, nil
+}
+
+// ApplyProgress выставляет прогресс, вычисленный по задачам, и двигает статус
+// по разрешённому пути: начатый проект переходит в работу, проект со всеми
+// выполненными задачами - на проверку, а выполненный проект с открытой задачей - снова в работу.
+// Проект из pending попадает на проверку через in_progress; промежуточный статус
+// возвращается в via, чтобы записать в историю оба перехода.
+func (p *Project) ApplyProgress(progress int) (changed bool, via ProjectStatus, err error) {
+	if progress == p.Progress {
+		return false, "", nil
+	}
+	if p.Status == ProjectCancelled {
+		return false, "", fmt.Errorf("%w: project is cancelled", ErrStatusChange)
+	}
+	status := p.Status
+	if progress > 0 && status == ProjectPending {
+		status = ProjectInProgress
+		if progress == 100 {
+			via = status
+		}
+	}
+	switch {
+	case progress == 100 && status == ProjectInProgress:
+		status = ProjectReview
+	case progress < 100 && status == ProjectDone:
+		status = ProjectInProgress
+	}
+	if err := status.CheckProgress(progress); err != nil {
+		return false, "", fmt.Errorf("%w: %v", ErrStatusChange, err)
+	}
+	p.Status = status
+	p.Progress = progress
+	return true, via, nil
+}
+
+// Archive помечает проект архивным и приостанавливает его, если работа по нему ещё шла.
+// Возвращает true, если изменился статус.
+func (p *Project) Archive(now time.Time) bool {
+	if p.ArchivedAt == nil {
+		p.ArchivedAt = &now
+	}
+	if p.Status == ProjectOnHold || !p.Status.CanTransition(ProjectOnHold) {
+		return false
+	}
+	p.Status = ProjectOnHold
+	return true
+}
+
+// NormalizeStatus приводит статус из старых записей, где он был произвольной строкой, к известному
+func NormalizeStatus(status ProjectStatus, progress int) ProjectStatus {
+	if status.Valid() && status.CheckProgress(progress) == nil {
+		return status
+	}
+	switch {
+	case progress >= 100:
+		return ProjectDone
+	case progress <= 0:
+		return ProjectPending
+	default:
+		return ProjectInProgress
+	}
 }
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProjectStatusCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to ProjectStatus
+		want     bool
+	}{
+		{ProjectPending, ProjectInProgress, true},
+		{ProjectPending, ProjectReview, false},
+		{ProjectPending, ProjectDone, false},
+		{ProjectInProgress, ProjectReview, true},
+		{ProjectInProgress, ProjectDone, false},
+		{ProjectReview, ProjectDone, true},
+		{ProjectReview, ProjectInProgress, true},
+		{ProjectOnHold, ProjectPending, true},
+		{ProjectOnHold, ProjectDone, false},
+		{ProjectDone, ProjectInProgress, true},
+		{ProjectDone, ProjectCancelled, false},
+		{ProjectCancelled, ProjectPending, false},
+		{ProjectCancelled, ProjectInProgress, false},
+		{"unknown", ProjectInProgress, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: ожидалось %v, получено %v", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
+
+func TestProjectStatusCheckProgress(t *testing.T) {
+	tests := []struct {
+		status   ProjectStatus
+		progress int
+		wantErr  bool
+	}{
+		{ProjectPending, 0, false},
+		{ProjectPending, 10, true},
+		{ProjectInProgress, 0, false},
+		{ProjectInProgress, 99, false},
+		{ProjectInProgress, 100, true},
+		{ProjectReview, 100, false},
+		{ProjectReview, 40, false},
+		{ProjectOnHold, 50, false},
+		{ProjectDone, 100, false},
+		{ProjectDone, 90, true},
+		{ProjectCancelled, 30, false},
+		{ProjectInProgress, -1, true},
+		{ProjectReview, 101, true},
+	}
+	for _, tt := range tests {
+		err := tt.status.CheckProgress(tt.progress)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s с прогрессом %d: ожидалась ошибка %v, получено %v", tt.status, tt.progress, tt.wantErr, err)
+		}
+	}
+}
+
+func TestProjectChangeState(t *testing.T) {
+	tests := []struct {
+		name         string
+		from         Project
+		status       ProjectStatus
+		progress     int
+		wantChanged  bool
+		wantErr      bool
+		wantRuleErr  bool
+		wantStatus   ProjectStatus
+		wantProgress int
+	}{
+		{"Начало работы", Project{Status: ProjectPending}, ProjectInProgress, 10, true, false, false, ProjectInProgress, 10},
+		{"Без изменений", Project{Status: ProjectReview, Progress: 100}, ProjectReview, 100, false, false, false, ProjectReview, 100},
+		{"Только прогресс", Project{Status: ProjectInProgress, Progress: 10}, ProjectInProgress, 60, true, false, false, ProjectInProgress, 60},
+		{"Сразу в done нельзя", Project{Status: ProjectInProgress, Progress: 90}, ProjectDone, 100, false, true, true, ProjectInProgress, 90},
+		{"done требует 100", Project{Status: ProjectReview, Progress: 90}, ProjectDone, 90, false, true, true, ProjectReview, 90},
+		{"Отменённый проект не меняется", Project{Status: ProjectCancelled, Progress: 30}, ProjectInProgress, 30, false, true, true, ProjectCancelled, 30},
+		{"Неизвестный статус", Project{Status: ProjectPending}, "archived", 0, false, true, false, ProjectPending, 0},
+		{"Прогресс вне диапазона", Project{Status: ProjectInProgress}, ProjectInProgress, 120, false, true, false, ProjectInProgress, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.from
+			changed, err := p.ChangeState(tt.status, tt.progress)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ожидалась ошибка %v, получено %v", tt.wantErr, err)
+			}
+			if tt.wantErr && errors.Is(err, ErrStatusChange) != tt.wantRuleErr {
+				t.Errorf("ErrStatusChange: ожидалось %v, получено %v", tt.wantRuleErr, err)
+			}
+			if changed != tt.wantChanged || p.Status != tt.wantStatus || p.Progress != tt.wantProgress {
+				t.Errorf("Ожидалось %v %s/%d, получено %v %s/%d", tt.wantChanged, tt.wantStatus, tt.wantProgress, changed, p.Status, p.Progress)
+			}
+		})
+	}
+}
+
+func TestProjectApplyProgress(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        Project
+		progress    int
+		wantChanged bool
+		wantErr     bool
+		wantStatus  ProjectStatus
+		wantVia     ProjectStatus
+	}{
+		{"Первая выполненная задача начинает работу", Project{Status: ProjectPending}, 25, true, false, ProjectInProgress, ""},
+		{"Все задачи выполнены - на проверку", Project{Status: ProjectInProgress, Progress: 75}, 100, true, false, ProjectReview, ""},
+		{"Из pending на проверку через in_progress", Project{Status: ProjectPending}, 100, true, false, ProjectReview, ProjectInProgress},
+		{"Открытая задача возвращает done в работу", Project{Status: ProjectDone, Progress: 100}, 80, true, false, ProjectInProgress, ""},
+		{"На проверке статус не меняется", Project{Status: ProjectReview, Progress: 100}, 50, true, false, ProjectReview, ""},
+		{"Приостановленный остаётся приостановленным", Project{Status: ProjectOnHold, Progress: 20}, 40, true, false, ProjectOnHold, ""},
+		{"Прогресс не изменился", Project{Status: ProjectInProgress, Progress: 50}, 50, false, false, ProjectInProgress, ""},
+		{"Отменённый проект", Project{Status: ProjectCancelled, Progress: 30}, 60, false, true, ProjectCancelled, ""},
+		{"Все задачи снова открыты", Project{Status: ProjectInProgress, Progress: 50}, 0, true, false, ProjectInProgress, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.from
+			changed, via, err := p.ApplyProgress(tt.progress)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ожидалась ошибка %v, получено %v", tt.wantErr, err)
+			}
+			if tt.wantErr && !errors.Is(err, ErrStatusChange) {
+				t.Errorf("Ожидалась ErrStatusChange, получено %v", err)
+			}
+			if changed != tt.wantChanged || p.Status != tt.wantStatus {
+				t.Errorf("Ожидалось %v %s, получено %v %s", tt.wantChanged, tt.wantStatus, changed, p.Status)
+			}
+			if via != tt.wantVia {
+				t.Errorf("Промежуточный статус: ожидался %q, получен %q", tt.wantVia, via)
+			}
+			if !tt.wantErr && via != "" && (!tt.from.Status.CanTransition(via) || !via.CanTransition(p.Status)) {
+				t.Errorf("Путь %s -> %s -> %s не разрешён правилами переходов", tt.from.Status, via, p.Status)
+			}
+			if !tt.wantErr && via == "" && p.Status != tt.from.Status && !tt.from.Status.CanTransition(p.Status) {
+				t.Errorf("Переход %s -> %s не разрешён правилами переходов", tt.from.Status, p.Status)
+			}
+			if changed && p.Progress != tt.progress {
+				t.Errorf("Прогресс: ожидалось %d, получено %d", tt.progress, p.Progress)
+			}
+		})
+	}
+}
+
+func TestProjectDeadlines(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { v := now.Add(d); return &v }
+	day := 24 * time.Hour
+	within := 2 * day
+
+	tests := []struct {
+		name        string
+		project     Project
+		wantOverdue bool
+		wantDueSoon bool
+	}{
+		{"Без срока", Project{Status: ProjectInProgress}, false, false},
+		{"Срок далеко", Project{Status: ProjectInProgress, DueDate: at(10 * day)}, false, false},
+		{"Срок ровно на границе окна", Project{Status: ProjectInProgress, DueDate: at(within)}, false, true},
+		{"Срок скоро", Project{Status: ProjectPending, DueDate: at(day)}, false, true},
+		{"Срок прямо сейчас", Project{Status: ProjectInProgress, DueDate: at(0)}, false, true},
+		{"Срок прошёл секунду назад", Project{Status: ProjectInProgress, DueDate: at(-time.Second)}, true, false},
+		{"Просрочен на проверке", Project{Status: ProjectReview, DueDate: at(-day)}, true, false},
+		{"Приостановленный тоже просрочен", Project{Status: ProjectOnHold, DueDate: at(-day)}, true, false},
+		{"Выполненный не просрочен", Project{Status: ProjectDone, DueDate: at(-day)}, false, false},
+		{"Отменённый не просрочен", Project{Status: ProjectCancelled, DueDate: at(-day)}, false, false},
+		{"Архивный не просрочен", Project{Status: ProjectOnHold, DueDate: at(-day), ArchivedAt: at(-day)}, false, false},
+		{"Архивный не напоминает о сроке", Project{Status: ProjectOnHold, DueDate: at(day), ArchivedAt: at(-day)}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.project.Overdue(now); got != tt.wantOverdue {
+				t.Errorf("Overdue: ожидалось %v, получено %v", tt.wantOverdue, got)
+			}
+			if got := tt.project.DueSoon(now, within); got != tt.wantDueSoon {
+				t.Errorf("DueSoon: ожидалось %v, получено %v", tt.wantDueSoon, got)
+			}
+		})
+	}
+}
+
+func TestProjectExpectedProgress(t *testing.T) {
+	created := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) *time.Time { v := created.AddDate(0, 0, n); return &v }
+
+	tests := []struct {
+		name   string
+		start  *time.Time
+		due    *time.Time
+		now    time.Time
+		want   int
+		wantOK bool
+	}{
+		{"Без срока", day(0), nil, *day(5), 0, false},
+		{"Без даты начала - от создания", nil, day(10), *day(5), 50, true},
+		{"С даты начала", day(2), day(12), *day(7), 50, true},
+		{"Округление вниз", day(0), day(3), *day(1), 33, true},
+		{"До начала работ", day(5), day(15), *day(3), 0, true},
+		{"В день начала", day(5), day(15), *day(5), 0, true},
+		{"В срок", day(0), day(10), *day(10), 100, true},
+		{"После срока", day(0), day(10), *day(20), 100, true},
+		{"Начало после срока", day(10), day(5), *day(1), 100, true},
+		{"Начало совпадает со сроком", day(5), day(5), *day(1), 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Project{CreatedAt: created, StartDate: tt.start, DueDate: tt.due}
+			got, ok := p.ExpectedProgress(tt.now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Ожидалось %d, %v; получено %d, %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestProjectSetSchedule(t *testing.T) {
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) *time.Time { v := base.AddDate(0, 0, n); return &v }
+
+	tests := []struct {
+		name          string
+		start, due    *time.Time
+		wantErr       bool
+		wantStart     *time.Time
+		wantDue       *time.Time
+		wantReminders bool // отметки о напоминаниях сохранились
+	}{
+		{"Ничего не передано", nil, nil, false, day(0), day(10), true},
+		{"Тот же срок", nil, day(10), false, day(0), day(10), true},
+		{"Только начало", day(2), nil, false, day(2), day(10), true},
+		{"Новый срок сбрасывает напоминания", nil, day(20), false, day(0), day(20), false},
+		{"Срок раньше начала", nil, day(-1), true, day(0), day(10), true},
+		{"Начало позже срока", day(11), nil, true, day(0), day(10), true},
+		{"Начало и срок в один день", day(10), nil, false, day(10), day(10), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Project{StartDate: day(0), DueDate: day(10), OverdueAt: day(11), DueReminderAt: day(8)}
+			err := p.SetSchedule(tt.start, tt.due)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ожидалась ошибка %v, получено %v", tt.wantErr, err)
+			}
+			if !p.StartDate.Equal(*tt.wantStart) || !p.DueDate.Equal(*tt.wantDue) {
+				t.Errorf("Даты: ожидалось %v - %v, получено %v - %v", tt.wantStart, tt.wantDue, p.StartDate, p.DueDate)
+			}
+			if reminders := p.OverdueAt != nil && p.DueReminderAt != nil; reminders != tt.wantReminders {
+				t.Errorf("Отметки напоминаний: ожидалось %v, получено overdue_at=%v due_reminder_at=%v", tt.wantReminders, p.OverdueAt, p.DueReminderAt)
+			}
+		})
+	}
+
+	t.Run("Первый срок", func(t *testing.T) {
+		p := Project{}
+		if err := p.SetSchedule(nil, day(10)); err != nil {
+			t.Fatal(err)
+		}
+		if p.StartDate != nil || p.DueDate == nil || !p.DueDate.Equal(*day(10)) {
+			t.Errorf("Ожидался срок %v без даты начала, получено %v - %v", day(10), p.StartDate, p.DueDate)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// rankDigits - алфавит позиций на доске. Порядок символов совпадает с байтовым,
+// поэтому ключи одинаково сортируются в Go и в SQL.
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// RankBetween возвращает позицию строго между a и b (дробное ранжирование).
+// Пустая a - начало колонки, пустая b - конец. Ключи никогда не оканчиваются
+// на младшую цифру, поэтому место между соседями есть всегда.
+func RankBetween(a, b string) (string, error) {
+	if b != "" && a >= b {
+		return "", fmt.Errorf("rank %q is not before %q", a, b)
+	}
+	if strings.HasSuffix(a, rankDigits[:1]) || strings.HasSuffix(b, rankDigits[:1]) {
+		return "", fmt.Errorf("invalid rank %q / %q", a, b)
+	}
+	return rankMidpoint(a, b), nil
+}
+
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		// общий префикс переносится как есть, середина ищется в остатке
+		n := 0
+		for n < len(b) && rankDigit(a, n) == strings.IndexByte(rankDigits, b[n]) {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + rankMidpoint(rest, b[n:])
+		}
+	}
+	lo := rankDigit(a, 0)
+	hi := len(rankDigits)
+	if b != "" {
+		hi = strings.IndexByte(rankDigits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(rankDigits[(lo+hi+1)/2])
+	}
+	// соседние цифры: берём первую цифру b, если за ней что-то есть, иначе уходим на разряд глубже
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(rankDigits[lo]) + rankMidpoint(rest, "")
+}
+
+// rankDigit - значение i-го символа a; за концом строки - младшая цифра
+func rankDigit(a string, i int) int {
+	if i >= len(a) {
+		return 0
+	}
+	return strings.IndexByte(rankDigits, a[i])
+}
+
+// RankSequence - n возрастающих позиций одинаковой длины, равномерно распределённых
+// по алфавиту: для начальной расстановки, чтобы ключи не росли при добавлении по одному
+func RankSequence(n int) []string {
+	base := len(rankDigits)
+	width, capacity := 1, base
+	for capacity < (n+1)*base {
+		width++
+		capacity *= base
+	}
+	step := capacity / (n + 1)
+	ranks := make([]string, n)
+	buf := make([]byte, width)
+	for i := range ranks {
+		v := (i + 1) * step
+		if v%base == 0 { // ключ не должен оканчиваться на младшую цифру; шаг не меньше base
+			v++
+		}
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = rankDigits[v%base]
+			v /= base
+		}
+		ranks[i] = string(buf)
+	}
+	return ranks
+}
+
+// MaxRankLength - длина ключа, после которой позиции колонки расставляются заново
+const MaxRankLength = 24
+
+// ErrRankConflict - соседи, переданные при перемещении, уже не стоят рядом в колонке
+var ErrRankConflict = errors.New("board has changed, reload it")
+
+// PlaceRank вычисляет позицию карточки selfID в колонке column между afterID (выше) и
+// beforeID (ниже); без соседей - в конец колонки. column - запрос к модели с условиями колонки.
+// Если ключ получается слишком длинным или у соседей одинаковые позиции, колонка
+// расставляется заново равномерными ключами.
+func PlaceRank(column *gorm.DB, selfID uint, afterID, beforeID *uint) (string, error) {
+	col := func() *gorm.DB { return column.Session(&gorm.Session{}).Where("id <> ?", selfID) }
+	neighbour := func(id *uint) (string, error) {
+		var ranks []string
+		if err := col().Where("id = ?", *id).Pluck("rank", &ranks).Error; err != nil {
+			return "", err
+		}
+		if len(ranks) == 0 {
+			return "", ErrRankConflict
+		}
+		return ranks[0], nil
+	}
+
+	var lower, upper string
+	var err error
+	if afterID != nil {
+		if lower, err = neighbour(afterID); err != nil {
+			return "", err
+		}
+	}
+	if beforeID != nil {
+		if upper, err = neighbour(beforeID); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case afterID == nil && beforeID == nil:
+		err = col().Select("COALESCE(MAX(rank), '')").Scan(&lower).Error
+	case afterID == nil:
+		err = col().Where("rank < ?", upper).Select("COALESCE(MAX(rank), '')").Scan(&lower).Error
+	case beforeID == nil:
+		err = col().Where("rank > ?", lower).Select("COALESCE(MIN(rank), '')").Scan(&upper).Error
+	}
+	if err != nil {
+		return "", err
+	}
+	if upper != "" && lower > upper {
+		return "", ErrRankConflict
+	}
+	if upper == "" || lower < upper {
+		if rank, err := RankBetween(lower, upper); err == nil && len(rank) <= MaxRankLength {
+			return rank, nil
+		}
+	}
+	return rebalanceRanks(col, afterID, beforeID)
+}
+
+// rebalanceRanks заново расставляет колонку, оставляя место для новой карточки
+// после afterID, перед beforeID или в конце, и возвращает её позицию
+func rebalanceRanks(col func() *gorm.DB, afterID, beforeID *uint) (string, error) {
+	var ids []uint
+	if err := col().Order("rank, id").Pluck("id", &ids).Error; err != nil {
+		return "", err
+	}
+	pos := len(ids)
+	for i, id := range ids {
+		if afterID != nil && id == *afterID {
+			pos = i + 1
+			break
+		}
+		if afterID == nil && beforeID != nil && id == *beforeID {
+			pos = i
+			break
+		}
+	}
+	ranks := RankSequence(len(ids) + 1)
+	for i, id := range ids {
+		r := ranks[i]
+		if i >= pos {
+			r = ranks[i+1]
+		}
+		if err := col().Where("id = ?", id).UpdateColumn("rank", r).Error; err != nil {
+			return "", err
+		}
+	}
+	return ranks[pos], nil
+}
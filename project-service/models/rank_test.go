@@ -0,0 +1,212 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// checkRank - ключ строго между a и b и не оканчивается на младшую цифру
+func checkRank(t *testing.T, a, b, got string) {
+	t.Helper()
+	if got <= a || (b != "" && got >= b) {
+		t.Errorf("Позиция %q не между %q и %q", got, a, b)
+	}
+	if strings.HasSuffix(got, rankDigits[:1]) {
+		t.Errorf("Позиция %q оканчивается на младшую цифру", got)
+	}
+}
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		wantErr bool
+	}{
+		{"Пустая колонка", "", "", false},
+		{"В конец", "V", "", false},
+		{"В начало", "", "V", false},
+		{"Между далёкими", "A", "Z", false},
+		{"Между соседними цифрами", "A", "B", false},
+		{"Общий префикс", "AB", "AC", false},
+		{"Короткий перед длинным", "A", "A1", false},
+		{"Перед самой младшей позицией", "", "1", false},
+		{"После самой старшей цифры", "z", "", false},
+		{"Между z и zz", "z", "zz", false},
+		{"Обратный порядок", "B", "A", true},
+		{"Одинаковые позиции", "A", "A", true},
+		{"Ключ на младшую цифру", "A0", "B", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RankBetween(tt.a, tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ожидалась ошибка %v, получено %v", tt.wantErr, err)
+			}
+			if err == nil {
+				checkRank(t, tt.a, tt.b, got)
+			}
+		})
+	}
+}
+
+func TestRankBetweenRepeated(t *testing.T) {
+	// pos - куда встаёт каждая новая карточка в колонке из n карточек
+	tests := []struct {
+		name string
+		pos  func(n int) int
+	}{
+		{"В начало", func(n int) int { return 0 }},
+		{"В конец", func(n int) int { return n }},
+		{"Сразу после первой", func(n int) int { return 1 }},
+		{"Сразу перед последней", func(n int) int { return n - 1 }},
+		{"В середину", func(n int) int { return n / 2 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranks := []string{"V", "W"}
+			for i := 0; i < 200; i++ {
+				pos := tt.pos(len(ranks))
+				var a, b string
+				if pos > 0 {
+					a = ranks[pos-1]
+				}
+				if pos < len(ranks) {
+					b = ranks[pos]
+				}
+				r, err := RankBetween(a, b)
+				if err != nil {
+					t.Fatalf("Шаг %d: %v", i, err)
+				}
+				checkRank(t, a, b, r)
+				ranks = append(ranks[:pos], append([]string{r}, ranks[pos:]...)...)
+			}
+		})
+	}
+}
+
+func TestRankSequence(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 61, 62, 63, 500, 5000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			ranks := RankSequence(n)
+			if len(ranks) != n {
+				t.Fatalf("Ожидалось %d позиций, получено %d", n, len(ranks))
+			}
+			for i, r := range ranks {
+				if len(r) != len(ranks[0]) {
+					t.Errorf("Позиции разной длины: %q и %q", ranks[0], r)
+				}
+				if strings.HasSuffix(r, rankDigits[:1]) {
+					t.Errorf("Позиция %q оканчивается на младшую цифру", r)
+				}
+				if i > 0 && r <= ranks[i-1] {
+					t.Fatalf("Позиции не возрастают: %q после %q", r, ranks[i-1])
+				}
+			}
+			// между соседними позициями и по краям остаётся место
+			if n > 0 {
+				if _, err := RankBetween("", ranks[0]); err != nil {
+					t.Errorf("Нет места в начале: %v", err)
+				}
+				if _, err := RankBetween(ranks[n-1], ""); err != nil {
+					t.Errorf("Нет места в конце: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func setupRankDB(t *testing.T) *gorm.DB {
+	d, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect test database: %v", err)
+	}
+	sqlDB, err := d.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := d.AutoMigrate(&Task{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	return d
+}
+
+// columnOrder - id задач колонки в порядке доски
+func columnOrder(t *testing.T, d *gorm.DB) []uint {
+	t.Helper()
+	var ids []uint
+	if err := d.Model(&Task{}).Where("project_id = ? AND status = ?", 1, TaskTodo).Order("rank, id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestPlaceRank(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	long := "V" + strings.Repeat("0", MaxRankLength-2)
+
+	tests := []struct {
+		name string
+		// ranks - позиции задач 1..n в колонке; задача n+1 - перемещаемая
+		ranks           []string
+		after, before   *uint
+		want            []uint
+		wantErr         error
+		wantRebalancing bool
+	}{
+		{"В пустую колонку", nil, nil, nil, []uint{1}, nil, false},
+		{"В конец", []string{"F", "V"}, nil, nil, []uint{1, 2, 3}, nil, false},
+		{"После первой", []string{"F", "V"}, id(1), nil, []uint{1, 3, 2}, nil, false},
+		{"Перед первой", []string{"F", "V"}, nil, id(1), []uint{3, 1, 2}, nil, false},
+		{"Между соседями", []string{"F", "V", "k"}, id(2), id(3), []uint{1, 2, 4, 3}, nil, false},
+		{"Перед последней", []string{"F", "V"}, nil, id(2), []uint{1, 3, 2}, nil, false},
+		{"Нет места между соседями", []string{"F", long + "1", long + "2", "k"}, id(2), id(3), []uint{1, 2, 5, 3, 4}, nil, true},
+		{"Одинаковые позиции соседей", []string{"F", "V", "V"}, id(2), id(3), []uint{1, 2, 4, 3}, nil, true},
+		{"Сосед не из колонки", []string{"F"}, id(9), nil, nil, ErrRankConflict, false},
+		{"Соседи в обратном порядке", []string{"F", "V"}, id(2), id(1), nil, ErrRankConflict, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := setupRankDB(t)
+			for _, r := range tt.ranks {
+				if err := d.Create(&Task{ProjectID: 1, Title: "x", Rank: r}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			// перемещаемая задача пока в другой колонке
+			self := Task{ProjectID: 1, Title: "self", Status: TaskDone, Rank: "V"}
+			if err := d.Create(&self).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			column := d.Model(&Task{}).Where("project_id = ? AND status = ?", 1, TaskTodo)
+			rank, err := PlaceRank(column, self.ID, tt.after, tt.before)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Ожидалась ошибка %v, получено %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(rank) > MaxRankLength {
+				t.Errorf("Позиция длиннее %d: %q", MaxRankLength, rank)
+			}
+			if err := d.Model(&self).UpdateColumns(map[string]interface{}{"status": TaskTodo, "rank": rank}).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got := columnOrder(t, d); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Порядок: ожидалось %v, получено %v", tt.want, got)
+			}
+
+			var ranks []string
+			d.Model(&Task{}).Where("id <= ?", len(tt.ranks)).Order("id").Pluck("rank", &ranks)
+			if rebalanced := fmt.Sprint(ranks) != fmt.Sprint(tt.ranks) && len(tt.ranks) > 0; rebalanced != tt.wantRebalancing {
+				t.Errorf("Перерасстановка колонки: ожидалось %v, позиции %v", tt.wantRebalancing, ranks)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TaskStatus - колонка задачи на доске; выполненной считается задача в done
+type TaskStatus string
+
+const (
+	TaskTodo       TaskStatus = "todo"
+	TaskInProgress TaskStatus = "in_progress"
+	TaskReview     TaskStatus = "review"
+	TaskDone       TaskStatus = "done"
+)
+
+// TaskStatuses - колонки доски задач по порядку
+var TaskStatuses = []TaskStatus{TaskTodo, TaskInProgress, TaskReview, TaskDone}
+
+// Valid сообщает, известен ли статус задачи
+func (s TaskStatus) Valid() bool {
+	for _, st := range TaskStatuses {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Milestone - этап проекта, объединяет задачи
+type Milestone struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ProjectID  uint       `gorm:"index;not null" json:"project_id"`
+	Title      string     `gorm:"not null" json:"title"`
+	AssigneeID *uint      `json:"assignee_id"`
+	DueDate    *time.Time `json:"due_date"`
+	Position   int        `json:"position"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Task - задача проекта; Weight задаёт её долю в прогрессе проекта
+type Task struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ProjectID   uint       `gorm:"index;not null" json:"project_id"`
+	MilestoneID *uint      `gorm:"index" json:"milestone_id"`
+	Title       string     `gorm:"not null" json:"title"`
+	AssigneeID  *uint      `json:"assignee_id"`
+	DueDate     *time.Time `json:"due_date"`
+	Weight      int        `gorm:"default:1;not null" json:"weight"`
+	Done        bool       `gorm:"default:false" json:"done"`
+	DoneAt      *time.Time `json:"done_at"`
+	Status      TaskStatus `gorm:"type:text;not null;default:todo" json:"status"`
+	// OverdueAt и DueReminderAt ставит проверка сроков; смена due_date их сбрасывает
+	OverdueAt     *time.Time `json:"overdue_at,omitempty"`
+	DueReminderAt *time.Time `json:"-"`
+	// Rank и Version - позиция на доске и версия для перемещений, как у проекта
+	Rank      string    `gorm:"index;not null;default:''" json:"rank"`
+	Version   int       `gorm:"not null;default:0" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate ставит новую задачу в конец колонки её статуса на доске проекта
+func (t *Task) BeforeCreate(tx *gorm.DB) error {
+	if t.Status == "" {
+		t.Status = TaskTodo
+		if t.Done {
+			t.Status = TaskDone
+		}
+	}
+	if t.Rank != "" {
+		return nil
+	}
+	column := tx.Session(&gorm.Session{NewDB: true}).Model(&Task{}).Where("project_id = ? AND status = ?", t.ProjectID, t.Status)
+	rank, err := PlaceRank(column, 0, nil, nil)
+	t.Rank = rank
+	return err
+}
+
+// BeforeSave увеличивает версию задачи
+func (t *Task) BeforeSave(tx *gorm.DB) error {
+	t.Version++
+	return nil
+}
+
+// MaxTaskWeight - верхняя граница веса задачи
+const MaxTaskWeight = 100
+
+// SetDone отмечает задачу выполненной (done) или снова открывает её (in_progress)
+func (t *Task) SetDone(done bool, now time.Time) {
+	if t.Done == done {
+		return
+	}
+	t.Done = done
+	if done {
+		t.DoneAt = &now
+		t.Status = TaskDone
+	} else {
+		t.DoneAt = nil
+		t.Status = TaskInProgress
+	}
+}
+
+// SetStatus переносит задачу в колонку s; колонка done означает выполненную задачу
+func (t *Task) SetStatus(s TaskStatus, now time.Time) {
+	t.SetDone(s == TaskDone, now)
+	t.Status = s
+}
+
+// SetDueDate меняет срок задачи; новый срок заново включает напоминания
+func (t *Task) SetDueDate(due time.Time) {
+	if t.DueDate != nil && t.DueDate.Equal(due) {
+		return
+	}
+	t.DueDate = &due
+	t.OverdueAt = nil
+	t.DueReminderAt = nil
+}
+
+// Overdue - срок прошёл, а задача не выполнена
+func (t *Task) Overdue(now time.Time) bool {
+	return !t.Done && t.DueDate != nil && now.After(*t.DueDate)
+}
+
+// DueSoon - до срока невыполненной задачи осталось не больше within
+func (t *Task) DueSoon(now time.Time, within time.Duration) bool {
+	return !t.Done && t.DueDate != nil && !now.After(*t.DueDate) && t.DueDate.Sub(now) <= within
+}
+
+// ComputeProgress - доля веса выполненных задач, 0..100 с округлением вниз,
+// чтобы 100 означало «выполнено всё». ok == false, если задач нет.
+func ComputeProgress(tasks []Task) (progress int, ok bool) {
+	var total, done int
+	for _, t := range tasks {
+		total += t.Weight
+		if t.Done {
+			done += t.Weight
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return done * 100 / total, true
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeProgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		tasks  []Task
+		want   int
+		wantOK bool
+	}{
+		{"Нет задач", nil, 0, false},
+		{"Ничего не выполнено", []Task{{Weight: 1}, {Weight: 1}}, 0, true},
+		{"Всё выполнено", []Task{{Weight: 1, Done: true}, {Weight: 3, Done: true}}, 100, true},
+		{"Половина по весу", []Task{{Weight: 2, Done: true}, {Weight: 1}, {Weight: 1}}, 50, true},
+		{"Тяжёлая задача перевешивает", []Task{{Weight: 1, Done: true}, {Weight: 9}}, 10, true},
+		{"Округление вниз", []Task{{Weight: 1, Done: true}, {Weight: 1, Done: true}, {Weight: 1}}, 66, true},
+		{"Почти всё - ещё не 100", []Task{{Weight: 199, Done: true}, {Weight: 1}}, 99, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ComputeProgress(tt.tasks)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Ожидалось %d, %v; получено %d, %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestTaskSetStatus(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		from     Task
+		status   TaskStatus
+		wantDone bool
+	}{
+		{"В работу", Task{Status: TaskTodo}, TaskInProgress, false},
+		{"В done - выполнена", Task{Status: TaskReview}, TaskDone, true},
+		{"Из done в review - снова открыта", Task{Status: TaskDone, Done: true, DoneAt: &now}, TaskReview, false},
+		{"Из done в todo", Task{Status: TaskDone, Done: true, DoneAt: &now}, TaskTodo, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.from
+			task.SetStatus(tt.status, now)
+			if task.Status != tt.status || task.Done != tt.wantDone || (task.DoneAt != nil) != tt.wantDone {
+				t.Errorf("Ожидалось %s done=%v, получено %s done=%v done_at=%v", tt.status, tt.wantDone, task.Status, task.Done, task.DoneAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxTemplateDays - самый дальний срок в шаблоне, дней от начала проекта
+const MaxTemplateDays = 3650
+
+// ProjectTemplate - типовая структура проекта («лендинг», «интернет-магазин»): описание
+// по умолчанию, этапы и задачи со сроками в днях от начала проекта
+type ProjectTemplate struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	Name           string              `gorm:"uniqueIndex;not null" json:"name"`
+	Description    string              `gorm:"type:text" json:"description"`
+	DurationDays   *int                `json:"duration_days"` // срок проекта
+	EstimatedHours float64             `json:"estimated_hours"`
+	Milestones     []TemplateMilestone `gorm:"-" json:"milestones"`
+	Tasks          []TemplateTask      `gorm:"-" json:"tasks"` // задачи вне этапов
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// TemplateMilestone - этап шаблона
+type TemplateMilestone struct {
+	ID         uint           `gorm:"primaryKey" json:"-"`
+	TemplateID uint           `gorm:"index;not null" json:"-"`
+	Title      string         `gorm:"not null" json:"title"`
+	DueInDays  *int           `json:"due_in_days"`
+	Position   int            `json:"-"`
+	Tasks      []TemplateTask `gorm:"-" json:"tasks"`
+}
+
+// TemplateTask - задача шаблона; MilestoneID указывает на этап шаблона
+type TemplateTask struct {
+	ID          uint   `gorm:"primaryKey" json:"-"`
+	TemplateID  uint   `gorm:"index;not null" json:"-"`
+	MilestoneID *uint  `gorm:"index" json:"-"`
+	Title       string `gorm:"not null" json:"title"`
+	Weight      int    `gorm:"default:1;not null" json:"weight"`
+	DueInDays   *int   `json:"due_in_days"`
+	Position    int    `json:"-"`
+}
+
+// Validate проверяет названия, веса и сроки; нулевой вес задачи становится 1
+func (t *ProjectTemplate) Validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if t.EstimatedHours < 0 {
+		return errors.New("estimated_hours must not be negative")
+	}
+	if err := t.checkDays(t.DurationDays); err != nil {
+		return err
+	}
+	for i := range t.Milestones {
+		m := &t.Milestones[i]
+		if m.Title == "" {
+			return errors.New("milestone title is required")
+		}
+		if err := t.checkDays(m.DueInDays); err != nil {
+			return err
+		}
+		if err := t.validateTasks(m.Tasks); err != nil {
+			return err
+		}
+	}
+	return t.validateTasks(t.Tasks)
+}
+
+func (t *ProjectTemplate) validateTasks(tasks []TemplateTask) error {
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Title == "" {
+			return errors.New("task title is required")
+		}
+		if task.Weight == 0 {
+			task.Weight = 1
+		}
+		if task.Weight < 1 || task.Weight > MaxTaskWeight {
+			return errors.New("weight must be between 1 and 100")
+		}
+		if err := t.checkDays(task.DueInDays); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDays - срок в пределах MaxTemplateDays и не позже срока всего проекта
+func (t *ProjectTemplate) checkDays(days *int) error {
+	if days == nil {
+		return nil
+	}
+	if *days < 0 || *days > MaxTemplateDays {
+		return errors.New("due_in_days must be between 0 and 3650")
+	}
+	if t.DurationDays != nil && *days > *t.DurationDays {
+		return errors.New("due_in_days must not exceed duration_days")
+	}
+	return nil
+}
+
+// DueIn - дата через days дней от start; nil, если срок не задан
+func DueIn(start time.Time, days *int) *time.Time {
+	if days == nil {
+		return nil
+	}
+	due := start.AddDate(0, 0, *days)
+	return &due
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProjectTemplateValidate(t *testing.T) {
+	days := func(n int) *int { return &n }
+	tests := []struct {
+		name    string
+		tpl     ProjectTemplate
+		wantErr bool
+	}{
+		{"Пустой шаблон с названием", ProjectTemplate{Name: "Лендинг"}, false},
+		{"Без названия", ProjectTemplate{}, true},
+		{"Отрицательная оценка часов", ProjectTemplate{Name: "x", EstimatedHours: -1}, true},
+		{"Срок проекта вне диапазона", ProjectTemplate{Name: "x", DurationDays: days(MaxTemplateDays + 1)}, true},
+		{"Этап без названия", ProjectTemplate{Name: "x", Milestones: []TemplateMilestone{{}}}, true},
+		{"Этап позже срока проекта", ProjectTemplate{Name: "x", DurationDays: days(30), Milestones: []TemplateMilestone{{Title: "Дизайн", DueInDays: days(31)}}}, true},
+		{"Этап в последний день", ProjectTemplate{Name: "x", DurationDays: days(30), Milestones: []TemplateMilestone{{Title: "Дизайн", DueInDays: days(30)}}}, false},
+		{"Отрицательный срок задачи", ProjectTemplate{Name: "x", Tasks: []TemplateTask{{Title: "Бриф", DueInDays: days(-1)}}}, true},
+		{"Задача без названия в этапе", ProjectTemplate{Name: "x", Milestones: []TemplateMilestone{{Title: "Дизайн", Tasks: []TemplateTask{{}}}}}, true},
+		{"Задача этапа позже срока проекта", ProjectTemplate{Name: "x", DurationDays: days(10), Milestones: []TemplateMilestone{{Title: "Дизайн", Tasks: []TemplateTask{{Title: "Макет", DueInDays: days(11)}}}}}, true},
+		{"Вес больше допустимого", ProjectTemplate{Name: "x", Tasks: []TemplateTask{{Title: "Бриф", Weight: MaxTaskWeight + 1}}}, true},
+		{"Отрицательный вес", ProjectTemplate{Name: "x", Tasks: []TemplateTask{{Title: "Бриф", Weight: -1}}}, true},
+		{"Срок без срока проекта", ProjectTemplate{Name: "x", Tasks: []TemplateTask{{Title: "Бриф", DueInDays: days(MaxTemplateDays)}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tpl.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ожидалась ошибка %v, получено %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProjectTemplateValidateDefaultsWeight(t *testing.T) {
+	tpl := ProjectTemplate{
+		Name:       "Лендинг",
+		Milestones: []TemplateMilestone{{Title: "Дизайн", Tasks: []TemplateTask{{Title: "Макет"}}}},
+		Tasks:      []TemplateTask{{Title: "Бриф"}, {Title: "Запуск", Weight: 5}},
+	}
+	if err := tpl.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if w := tpl.Milestones[0].Tasks[0].Weight; w != 1 {
+		t.Errorf("Вес задачи этапа: ожидалось 1, получено %d", w)
+	}
+	if w := tpl.Tasks[0].Weight; w != 1 {
+		t.Errorf("Нулевой вес должен стать 1, получено %d", w)
+	}
+	if w := tpl.Tasks[1].Weight; w != 5 {
+		t.Errorf("Заданный вес не должен меняться, получено %d", w)
+	}
+}
+
+func TestDueIn(t *testing.T) {
+	days := func(n int) *int { return &n }
+	start := time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		days *int
+		want *time.Time
+	}{
+		{"Срок не задан", nil, nil},
+		{"В день начала", days(0), &start},
+		{"Через месяц без переполнения", days(30), ptrTime(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))},
+		{"Через год", days(365), ptrTime(time.Date(2026, 1, 30, 0, 0, 0, 0, time.UTC))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DueIn(start, tt.days)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("Ожидалось %v, получено %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxEntryMinutes - самая длинная запись времени, внесённая вручную
+const MaxEntryMinutes = 24 * 60
+
+// TimeEntry - учтённое время сотрудника по проекту или его задаче.
+// Запись без EndedAt - запущенный таймер; у сотрудника он может быть только один.
+type TimeEntry struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	ProjectID uint       `gorm:"index;not null" json:"project_id"`
+	TaskID    *uint      `gorm:"index" json:"task_id"`
+	UserID    uint       `gorm:"index;not null;uniqueIndex:idx_time_entries_running,where:ended_at IS NULL" json:"user_id"`
+	StartedAt time.Time  `gorm:"index;not null" json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Minutes   int        `gorm:"not null" json:"minutes"`
+	Note      string     `gorm:"type:text" json:"note"`
+	Billable  bool       `gorm:"not null" json:"billable"`
+	// HourlyRate - ставка на момент записи, чтобы смена ставок не меняла выставленные суммы
+	HourlyRate float64   `gorm:"not null" json:"hourly_rate"`
+	Amount     float64   `gorm:"not null" json:"amount"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Running - таймер ещё не остановлен
+func (e *TimeEntry) Running() bool {
+	return e.EndedAt == nil
+}
+
+// Stop останавливает таймер; длительность округляется до минуты
+func (e *TimeEntry) Stop(now time.Time) {
+	if !e.Running() {
+		return
+	}
+	if now.Before(e.StartedAt) {
+		now = e.StartedAt
+	}
+	e.EndedAt = &now
+	e.Minutes = int(now.Sub(e.StartedAt).Round(time.Minute) / time.Minute)
+}
+
+// SetSpan задаёт начало и длительность завершённой записи
+func (e *TimeEntry) SetSpan(start time.Time, minutes int) {
+	end := start.Add(time.Duration(minutes) * time.Minute)
+	e.StartedAt = start
+	e.Minutes = minutes
+	e.EndedAt = &end
+}
+
+// BeforeSave пересчитывает сумму: оплачиваемые минуты по ставке с точностью до копейки
+func (e *TimeEntry) BeforeSave(tx *gorm.DB) error {
+	e.Amount = 0
+	if e.Billable {
+		e.Amount = math.Round(float64(e.Minutes)*e.HourlyRate/60*100) / 100
+	}
+	return nil
+}
+
+// RateCard - почасовая ставка. Условия (проект, клиент - владелец проекта, сотрудник)
+// необязательны; карта без условий - ставка по умолчанию.
+type RateCard struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `json:"name"`
+	ProjectID  *uint     `gorm:"index" json:"project_id"`
+	ClientID   *uint     `gorm:"index" json:"client_id"`
+	UserID     *uint     `gorm:"index" json:"user_id"`
+	HourlyRate float64   `gorm:"not null" json:"hourly_rate"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// specificity - насколько карта конкретна: проект важнее клиента, клиент важнее сотрудника
+func (r *RateCard) specificity() int {
+	n := 0
+	if r.ProjectID != nil {
+		n += 4
+	}
+	if r.ClientID != nil {
+		n += 2
+	}
+	if r.UserID != nil {
+		n++
+	}
+	return n
+}
+
+func (r *RateCard) matches(projectID, clientID, userID uint) bool {
+	return (r.ProjectID == nil || *r.ProjectID == projectID) &&
+		(r.ClientID == nil || *r.ClientID == clientID) &&
+		(r.UserID == nil || *r.UserID == userID)
+}
+
+// PickRate выбирает ставку для работы сотрудника userID над проектом клиента clientID:
+// самую конкретную подходящую карту, при равенстве - более новую. 0, если карт нет.
+func PickRate(cards []RateCard, projectID, clientID, userID uint) float64 {
+	var best *RateCard
+	for i := range cards {
+		r := &cards[i]
+		if !r.matches(projectID, clientID, userID) {
+			continue
+		}
+		if best == nil || r.specificity() > best.specificity() ||
+			r.specificity() == best.specificity() && r.ID > best.ID {
+			best = r
+		}
+	}
+	if best == nil {
+		return 0
+	}
+	return best.HourlyRate
+}
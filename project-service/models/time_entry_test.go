@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestPickRate(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	cards := []RateCard{
+		{ID: 1, Name: "По умолчанию", HourlyRate: 1000},
+		{ID: 2, Name: "Сотрудник 7", UserID: id(7), HourlyRate: 1500},
+		{ID: 3, Name: "Клиент 20", ClientID: id(20), HourlyRate: 2000},
+		{ID: 4, Name: "Клиент 20, сотрудник 7", ClientID: id(20), UserID: id(7), HourlyRate: 2500},
+		{ID: 5, Name: "Проект 100", ProjectID: id(100), HourlyRate: 3000},
+		{ID: 6, Name: "Проект 100, сотрудник 8", ProjectID: id(100), UserID: id(8), HourlyRate: 3500},
+		{ID: 7, Name: "Сотрудник 9", UserID: id(9), HourlyRate: 1100},
+		{ID: 8, Name: "Сотрудник 9, новая", UserID: id(9), HourlyRate: 1200},
+	}
+
+	tests := []struct {
+		name                        string
+		cards                       []RateCard
+		projectID, clientID, userID uint
+		want                        float64
+	}{
+		{"Нет карт", nil, 1, 1, 1, 0},
+		{"Нет подходящих карт", cards[1:2], 1, 1, 8, 0},
+		{"Ставка по умолчанию", cards, 1, 10, 1, 1000},
+		{"Сотрудник важнее умолчания", cards, 1, 10, 7, 1500},
+		{"Клиент важнее сотрудника", cards, 1, 20, 8, 2000},
+		{"Клиент и сотрудник важнее клиента", cards, 1, 20, 7, 2500},
+		{"Проект важнее клиента и сотрудника", cards, 100, 20, 7, 3000},
+		{"Проект и сотрудник важнее проекта", cards, 100, 20, 8, 3500},
+		{"При равенстве - более новая карта", cards, 1, 10, 9, 1200},
+		{"Порядок карт не важен", []RateCard{cards[7], cards[5], cards[0], cards[6], cards[4]}, 1, 10, 9, 1200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PickRate(tt.cards, tt.projectID, tt.clientID, tt.userID); got != tt.want {
+				t.Errorf("Ожидалась ставка %v, получено %v", tt.want, got)
+			}
+		})
+	}
+}
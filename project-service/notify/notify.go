@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Виды уведомлений о сроках
+const (
+	ProjectDueSoon = "project_due_soon"
+	ProjectOverdue = "project_overdue"
+	TaskDueSoon    = "task_due_soon"
+	TaskOverdue    = "task_overdue"
+)
+
+// Notification - уведомление одному пользователю
+type Notification struct {
+	UserID    uint       `json:"user_id"`
+	Kind      string     `json:"kind"`
+	ProjectID uint       `json:"project_id"`
+	TaskID    *uint      `json:"task_id,omitempty"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body"`
+	DueDate   *time.Time `json:"due_date,omitempty"`
+}
+
+// Notifier доставляет уведомления. Реализации: LogNotifier, WebhookNotifier.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// New создаёт Notifier по имени бэкенда. Неизвестный бэкенд - ошибка.
+func New(backend, webhookURL string) (Notifier, error) {
+	switch backend {
+	case "webhook":
+		if webhookURL == "" {
+			return nil, fmt.Errorf("NOTIFY_WEBHOOK_URL is required for the webhook notifier")
+		}
+		return &WebhookNotifier{URL: webhookURL, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+	case "log", "":
+		return &LogNotifier{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier backend %q", backend)
+	}
+}
+
+// LogNotifier только пишет уведомления в лог (для разработки)
+type LogNotifier struct{}
+
+func (n *LogNotifier) Notify(msg Notification) error {
+	log.Printf("notify user %d [%s] %s", msg.UserID, msg.Kind, msg.Subject)
+	return nil
+}
+
+// WebhookNotifier отправляет уведомление JSON-ом на URL, например в сервис рассылок
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(msg Notification) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook error: status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -3,10 +3,12 @@ package routes
 import (
 	"ooolalex/project-service/config"
 	"ooolalex/project-service/handlers"
+	"ooolalex/project-service/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, cfg config.Config) {
-	handlers.RegisterProjectRoutes(r)
+func SetupRoutes(r *gin.Engine, cfg config.Config, store storage.Storage) {
+	handlers.RegisterProjectRoutes(r, cfg, store)
+	handlers.RegisterInternalRoutes(r, cfg)
 }
@@ -3,14 +3,19 @@ package clients
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 )
 
 type AuthClient struct {
 	BaseURL string
+	// InternalToken передаётся в X-Internal-Token для защищённых внутренних эндпоинтов
+	InternalToken string
 }
 
 type User struct {
@@ -21,26 +26,59 @@ type User struct {
 }
 
 type UserRoleResponse struct {
-	ID   uint   `json:"id"`
-	Role string `json:"role"`
+	ID          uint     `json:"id"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// Can проверяет наличие права у пользователя
+func (r *UserRoleResponse) Can(perm string) bool {
+	for _, p := range r.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
 }
 
 type AuthServiceResponse struct {
 	Data interface{} `json:"data"`
 }
 
+var (
+	// ErrUserNotFound - пользователь не существует или удалён
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserSuspended - учётная запись заблокирована
+	ErrUserSuspended = errors.New("account suspended")
+	// ErrSessionRevoked - сессия токена отозвана (выход на устройстве или "выйти везде")
+	ErrSessionRevoked = errors.New("session revoked")
+	// ErrInvalidAPIKey - ключ не найден, истёк или отозван
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrInternalToken - auth-service не принял X-Internal-Token (INTERNAL_API_TOKEN не совпадает)
+	ErrInternalToken = errors.New("auth service rejected internal token")
+	// ErrInvalidRole - auth-service не знает такой роли
+	ErrInvalidRole = errors.New("invalid role")
+)
+
 func NewAuthClient() *AuthClient {
 	baseURL := os.Getenv("AUTH_SERVICE_URL")
 	if baseURL == "" {
 		baseURL = "http://localhost:8080"
 	}
-	return &AuthClient{BaseURL: baseURL}
+	return &AuthClient{
+		BaseURL:       baseURL,
+		InternalToken: os.Getenv("INTERNAL_API_TOKEN"),
+	}
 }
 
 // GetUser fetches user by ID from auth-service
 func (c *AuthClient) GetUser(userID uint) (*User, error) {
-	url := fmt.Sprintf("%s/api/v1/internal/users/%d", c.BaseURL, userID)
-	resp, err := http.Get(url)
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/internal/users/%d", c.BaseURL, userID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", c.InternalToken)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -69,48 +107,155 @@ func (c *AuthClient) GetUser(userID uint) (*User, error) {
 	return &user, nil
 }
 
-// GetUserRole fetches user role from auth-service
-func (c *AuthClient) GetUserRole(userID uint) (string, error) {
-	url := fmt.Sprintf("%s/api/v1/internal/users/%d/role", c.BaseURL, userID)
-	resp, err := http.Get(url)
+// GetUserAccess fetches user role and permissions from auth-service.
+// sid - session from the user's token; auth-service rejects revoked sessions.
+func (c *AuthClient) GetUserAccess(userID uint, sid string) (*UserRoleResponse, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/internal/users/%d/role", c.BaseURL, userID)
+	if sid != "" {
+		endpoint += "?sid=" + url.QueryEscape(sid)
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", c.InternalToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrUserNotFound
+	case http.StatusForbidden:
+		return nil, ErrUserSuspended
+	case http.StatusUnauthorized:
+		return nil, unauthorizedError(resp)
+	default:
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("auth service error: %s", string(body))
+		return nil, fmt.Errorf("auth service error: %s", string(body))
 	}
 
 	var authResp AuthServiceResponse
 	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	roleBytes, err := json.Marshal(authResp.Data)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var roleResp UserRoleResponse
 	if err := json.Unmarshal(roleBytes, &roleResp); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return roleResp.Role, nil
+	return &roleResp, nil
 }
 
-// ListUsers fetches list of users from auth-service
+// VerifyAPIKey проверяет персональный API-ключ. Права в ответе уже ограничены scopes ключа.
+func (c *AuthClient) VerifyAPIKey(key string) (*UserRoleResponse, error) {
+	payload, err := json.Marshal(map[string]string{"key": key})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.BaseURL+"/api/v1/internal/api-keys/verify", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.InternalToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidAPIKey
+	case http.StatusForbidden:
+		return nil, ErrUserSuspended
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("auth service error: %s", string(body))
+	}
+
+	var authResp AuthServiceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+		return nil, err
+	}
+	roleBytes, err := json.Marshal(authResp.Data)
+	if err != nil {
+		return nil, err
+	}
+	var roleResp UserRoleResponse
+	if err := json.Unmarshal(roleBytes, &roleResp); err != nil {
+		return nil, err
+	}
+	return &roleResp, nil
+}
+
+// ImpersonatedAction - запрос, выполненный администратором от имени пользователя
+type ImpersonatedAction struct {
+	SID     string `json:"sid"`
+	ActorID uint   `json:"actor_id"`
+	UserID  uint   `json:"user_id"`
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Status  int    `json:"status"`
+	IP      string `json:"ip"`
+}
+
+// RecordImpersonatedAction отправляет действие в журнал имперсонаций auth-service
+func (c *AuthClient) RecordImpersonatedAction(a ImpersonatedAction) error {
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.BaseURL+"/api/v1/internal/impersonation/actions", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.InternalToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("auth service error: %s", string(body))
+	}
+	return nil
+}
+
+// ListUsers fetches list of users from auth-service.
+// Supported filters: role, email, q, created_from, created_to, status, sort, order.
 func (c *AuthClient) ListUsers(page, size int, filters map[string]string) ([]User, int64, error) {
-	url := fmt.Sprintf("%s/api/v1/users?page=%d&size=%d", c.BaseURL, page, size)
-	
-	// Add filters to URL
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("size", strconv.Itoa(size))
 	for key, value := range filters {
-		url += fmt.Sprintf("&%s=%s", key, value)
+		query.Set(key, value)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/api/v1/internal/users?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
 	}
+	req.Header.Set("X-Internal-Token", c.InternalToken)
 
-	resp, err := http.Get(url)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -158,6 +303,7 @@ func (c *AuthClient) UpdateUserRole(userID uint, role string) error {
 		return err
 	}
 	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("X-Internal-Token", c.InternalToken)
 	
 	client := &http.Client{}
 	httpResp, err := client.Do(resp)
@@ -166,6 +312,9 @@ func (c *AuthClient) UpdateUserRole(userID uint, role string) error {
 	}
 	defer httpResp.Body.Close()
 	
+	if httpResp.StatusCode == http.StatusBadRequest {
+		return ErrInvalidRole
+	}
 	if httpResp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(httpResp.Body)
 		return fmt.Errorf("auth service error: %s", string(body))
@@ -174,3 +323,13 @@ func (c *AuthClient) UpdateUserRole(userID uint, role string) error {
 	return nil
 }
 
+// unauthorizedError различает отозванную сессию и отклонённый X-Internal-Token: оба дают 401
+func unauthorizedError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error == "invalid internal token" {
+		return ErrInternalToken
+	}
+	return ErrSessionRevoked
+}
@@ -14,6 +14,8 @@ type Config struct {
 	DBPath    string
 	JWTSecret string
 	JWTTTLMin int
+	// InternalToken - секрет для внутренних эндпоинтов (X-Internal-Token)
+	InternalToken string
 }
 
 func LoadConfig() Config {
@@ -35,6 +37,8 @@ func LoadConfig() Config {
 		DBPath:    os.Getenv("DB_PATH"),
 		JWTSecret: os.Getenv("JWT_SECRET"),
 		JWTTTLMin: ttl,
+
+		InternalToken: os.Getenv("INTERNAL_API_TOKEN"),
 	}
 
 	if cfg.DBPath == "" {
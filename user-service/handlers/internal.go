@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"user-service/config"
+	"user-service/db"
+	"user-service/middleware"
+	"user-service/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterInternalRoutes - межсервисные эндпоинты, защищённые X-Internal-Token
+func RegisterInternalRoutes(r *gin.Engine, cfg *config.Config) {
+	internal := r.Group("/api/internal")
+	internal.Use(middleware.InternalOnly(cfg.InternalToken))
+
+	internal.GET("/users/:id/export", exportUser)
+	internal.POST("/users/:id/anonymize", anonymizeUser)
+}
+
+// exportUser отдаёт профиль, адреса и журнал пользователя для выгрузки персональных данных
+func exportUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var profile *models.Profile
+	var p models.Profile
+	if err := db.DB.Where("user_id = ?", id).First(&p).Error; err == nil {
+		profile = &p
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot export user"})
+		return
+	}
+	addresses := []models.Address{}
+	logs := []models.Log{}
+	if err := db.DB.Where("user_id = ?", id).Order("id").Find(&addresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot export user"})
+		return
+	}
+	if err := db.DB.Where("user_id = ?", id).Order("id").Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot export user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile":   profile,
+		"addresses": addresses,
+		"logs":      logs,
+	})
+}
+
+// anonymizeUser удаляет профиль и адреса пользователя и отвязывает его от журнала действий
+func anonymizeUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.Address{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.Profile{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Log{}).Where("user_id = ?", id).Update("user_id", 0).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot anonymize user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"anonymized": true})
+}
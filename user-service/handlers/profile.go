@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"user-service/clients"
+	"user-service/config"
+	"user-service/db"
+	"user-service/middleware"
+	"user-service/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type updateProfileRequest struct {
+	Phone            *string `json:"phone"`
+	AvatarURL        *string `json:"avatar_url"`
+	MarketingConsent *bool   `json:"marketing_consent"`
+}
+
+type addressRequest struct {
+	Type       models.AddressType `json:"type" binding:"required,oneof=shipping billing"`
+	FullName   string             `json:"full_name" binding:"required"`
+	Phone      string             `json:"phone"`
+	Line1      string             `json:"line1" binding:"required"`
+	Line2      string             `json:"line2"`
+	City       string             `json:"city" binding:"required"`
+	Region     string             `json:"region"`
+	PostalCode string             `json:"postal_code"`
+	Country    string             `json:"country" binding:"required"`
+	IsDefault  bool               `json:"is_default"`
+}
+
+func RegisterProfileRoutes(r *gin.Engine, cfg *config.Config) {
+	me := r.Group("/api/me")
+	me.Use(middleware.AuthRequired(middleware.Config{JWTSecret: cfg.JWTSecret}))
+
+	me.GET("/profile", getMyProfile)
+	me.PUT("/profile", updateMyProfile)
+
+	me.GET("/addresses", listMyAddresses)
+	me.POST("/addresses", createMyAddress)
+	me.PUT("/addresses/:id", updateMyAddress)
+	me.DELETE("/addresses/:id", deleteMyAddress)
+	me.POST("/addresses/:id/default", setMyDefaultAddress)
+}
+
+func currentUserID(c *gin.Context) uint {
+	return c.MustGet(middleware.ContextUserID).(uint)
+}
+
+// loadOrInitProfile возвращает профиль пользователя, создавая пустой при первом обращении
+func loadOrInitProfile(userID uint) (*models.Profile, error) {
+	profile := models.Profile{UserID: userID}
+	if err := db.DB.Where(models.Profile{UserID: userID}).FirstOrCreate(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func getMyProfile(c *gin.Context) {
+	profile, err := loadOrInitProfile(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func updateMyProfile(c *gin.Context) {
+	userID := currentUserID(c)
+	var req updateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := loadOrInitProfile(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load profile"})
+		return
+	}
+
+	if req.Phone != nil {
+		profile.Phone = *req.Phone
+	}
+	if req.AvatarURL != nil {
+		profile.AvatarURL = *req.AvatarURL
+	}
+	if req.MarketingConsent != nil && *req.MarketingConsent != profile.MarketingConsent {
+		profile.MarketingConsent = *req.MarketingConsent
+		// фиксируем момент, когда согласие было дано или отозвано
+		now := time.Now()
+		profile.MarketingConsentAt = &now
+	}
+
+	if err := db.DB.Save(profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update profile"})
+		return
+	}
+
+	writeLog(userID, "updated profile")
+	c.JSON(http.StatusOK, profile)
+}
+
+func listMyAddresses(c *gin.Context) {
+	var items []models.Address
+	q := db.DB.Where("user_id = ?", currentUserID(c))
+	if t := c.Query("type"); t != "" {
+		q = q.Where("type = ?", t)
+	}
+	if err := q.Order("is_default desc, created_at desc").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch addresses"})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func createMyAddress(c *gin.Context) {
+	userID := currentUserID(c)
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addr := models.Address{UserID: userID}
+	applyAddressRequest(&addr, &req)
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// первый адрес данного типа автоматически становится адресом по умолчанию
+		var count int64
+		if err := tx.Model(&models.Address{}).Where("user_id = ? AND type = ?", userID, addr.Type).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			addr.IsDefault = true
+		}
+		if addr.IsDefault {
+			if err := clearDefaultAddress(tx, userID, addr.Type); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&addr).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create address"})
+		return
+	}
+
+	writeLog(userID, "added "+string(addr.Type)+" address id="+strconv.Itoa(int(addr.ID)))
+	c.JSON(http.StatusCreated, addr)
+}
+
+func updateMyAddress(c *gin.Context) {
+	userID := currentUserID(c)
+	addr, ok := findMyAddress(c, userID)
+	if !ok {
+		return
+	}
+
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wasDefault, oldType := addr.IsDefault, addr.Type
+	applyAddressRequest(addr, &req)
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// у каждого типа остаётся адрес по умолчанию: единственный адрес типа снять нельзя
+		var others int64
+		if err := tx.Model(&models.Address{}).Where("user_id = ? AND type = ? AND id <> ?", userID, addr.Type, addr.ID).Count(&others).Error; err != nil {
+			return err
+		}
+		if others == 0 {
+			addr.IsDefault = true
+		}
+		if addr.IsDefault {
+			if err := clearDefaultAddress(tx, userID, addr.Type); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(addr).Error; err != nil {
+			return err
+		}
+		// снятый флаг переходит к самому свежему другому адресу прежнего типа
+		if wasDefault && (!addr.IsDefault || oldType != addr.Type) {
+			return promoteDefaultAddress(tx, userID, oldType, addr.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update address"})
+		return
+	}
+
+	writeLog(userID, "updated address id="+strconv.Itoa(int(addr.ID)))
+	c.JSON(http.StatusOK, addr)
+}
+
+func deleteMyAddress(c *gin.Context) {
+	userID := currentUserID(c)
+	addr, ok := findMyAddress(c, userID)
+	if !ok {
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(addr).Error; err != nil {
+			return err
+		}
+		if !addr.IsDefault {
+			return nil
+		}
+		return promoteDefaultAddress(tx, userID, addr.Type, addr.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete address"})
+		return
+	}
+
+	writeLog(userID, "deleted address id="+strconv.Itoa(int(addr.ID)))
+	c.Status(http.StatusNoContent)
+}
+
+func setMyDefaultAddress(c *gin.Context) {
+	userID := currentUserID(c)
+	addr, ok := findMyAddress(c, userID)
+	if !ok {
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultAddress(tx, userID, addr.Type); err != nil {
+			return err
+		}
+		addr.IsDefault = true
+		return tx.Model(addr).Update("is_default", true).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot set default address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, addr)
+}
+
+// userProfile - полный профиль покупателя для администратора
+func userProfile(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	authClient := clients.NewAuthClient()
+	user, err := authClient.GetUser(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found in auth-service"})
+		return
+	}
+
+	var profile models.Profile
+	if err := db.DB.Where("user_id = ?", user.ID).First(&profile).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load profile"})
+		return
+	}
+	profile.UserID = user.ID
+
+	var addresses []models.Address
+	if err := db.DB.Where("user_id = ?", user.ID).Order("type, is_default desc, created_at desc").Find(&addresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot fetch addresses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":      user,
+		"profile":   profile,
+		"addresses": addresses,
+	})
+}
+
+func findMyAddress(c *gin.Context, userID uint) (*models.Address, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	var addr models.Address
+	if err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&addr).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "address not found"})
+		return nil, false
+	}
+	return &addr, true
+}
+
+func applyAddressRequest(addr *models.Address, req *addressRequest) {
+	addr.Type = req.Type
+	addr.FullName = req.FullName
+	addr.Phone = req.Phone
+	addr.Line1 = req.Line1
+	addr.Line2 = req.Line2
+	addr.City = req.City
+	addr.Region = req.Region
+	addr.PostalCode = req.PostalCode
+	addr.Country = req.Country
+	addr.IsDefault = req.IsDefault
+}
+
+func clearDefaultAddress(tx *gorm.DB, userID uint, t models.AddressType) error {
+	return tx.Model(&models.Address{}).
+		Where("user_id = ? AND type = ? AND is_default = ?", userID, t, true).
+		Update("is_default", false).Error
+}
+
+// promoteDefaultAddress передаёт флаг по умолчанию самому свежему адресу типа t, кроме exceptID
+func promoteDefaultAddress(tx *gorm.DB, userID uint, t models.AddressType, exceptID uint) error {
+	var next models.Address
+	err := tx.Where("user_id = ? AND type = ? AND id <> ?", userID, t, exceptID).Order("created_at desc, id desc").First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&next).Update("is_default", true).Error
+}
+
+func writeLog(userID uint, action string) {
+	db.DB.Create(&models.Log{
+		UserID:    userID,
+		Action:    action,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-service/db"
+	"user-service/middleware"
+	"user-service/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) {
+	// Используем in-memory SQLite для тестов
+	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect test database: %v", err)
+	}
+	// у каждого соединения с :memory: своя база
+	sqlDB, err := testDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := testDB.AutoMigrate(&models.Log{}, &models.Profile{}, &models.Address{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	db.DB = testDB
+}
+
+// setupRouter - маршруты профиля от имени пользователя userID, без проверки токена
+func setupRouter(userID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	me := r.Group("/api/me", func(c *gin.Context) { c.Set(middleware.ContextUserID, userID) })
+	me.GET("/profile", getMyProfile)
+	me.PUT("/profile", updateMyProfile)
+	me.GET("/addresses", listMyAddresses)
+	me.POST("/addresses", createMyAddress)
+	me.PUT("/addresses/:id", updateMyAddress)
+	me.DELETE("/addresses/:id", deleteMyAddress)
+	me.POST("/addresses/:id/default", setMyDefaultAddress)
+	return r
+}
+
+func doJSON(t *testing.T, r *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func address(t models.AddressType, name string, isDefault bool) addressRequest {
+	return addressRequest{Type: t, FullName: name, Line1: "ул. Ленина, 1", City: "Москва", Country: "RU", IsDefault: isDefault}
+}
+
+func createAddress(t *testing.T, r *gin.Engine, req addressRequest) models.Address {
+	t.Helper()
+	w := doJSON(t, r, http.MethodPost, "/api/me/addresses", req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var addr models.Address
+	if err := json.Unmarshal(w.Body.Bytes(), &addr); err != nil {
+		t.Fatalf("Не удалось распарсить ответ: %v", err)
+	}
+	return addr
+}
+
+// defaults - ID адресов по умолчанию пользователя по типам
+func defaults(t *testing.T, userID uint) map[models.AddressType][]uint {
+	t.Helper()
+	var items []models.Address
+	if err := db.DB.Where("user_id = ? AND is_default = ?", userID, true).Order("id").Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := map[models.AddressType][]uint{}
+	for _, a := range items {
+		out[a.Type] = append(out[a.Type], a.ID)
+	}
+	return out
+}
+
+func expectDefault(t *testing.T, userID uint, typ models.AddressType, want ...uint) {
+	t.Helper()
+	got := defaults(t, userID)[typ]
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Адрес %s по умолчанию: ожидалось %v, получено %v", typ, want, got)
+	}
+}
+
+func TestUpdateMyProfile(t *testing.T) {
+	setupTestDB(t)
+	r := setupRouter(1)
+
+	consent := true
+	phone := "+79990000000"
+	w := doJSON(t, r, http.MethodPut, "/api/me/profile", updateProfileRequest{Phone: &phone, MarketingConsent: &consent})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, w.Code)
+	}
+	var profile models.Profile
+	if err := json.Unmarshal(w.Body.Bytes(), &profile); err != nil {
+		t.Fatalf("Не удалось распарсить ответ: %v", err)
+	}
+	if profile.UserID != 1 || profile.Phone != phone || !profile.MarketingConsent || profile.MarketingConsentAt == nil {
+		t.Errorf("Профиль не обновлён: %+v", profile)
+	}
+}
+
+func TestCreateMyAddressDefaults(t *testing.T) {
+	setupTestDB(t)
+	r := setupRouter(1)
+
+	first := createAddress(t, r, address(models.AddressShipping, "Первый", false))
+	if !first.IsDefault {
+		t.Errorf("Первый адрес типа должен стать адресом по умолчанию")
+	}
+	createAddress(t, r, address(models.AddressShipping, "Второй", false))
+	expectDefault(t, 1, models.AddressShipping, first.ID)
+
+	third := createAddress(t, r, address(models.AddressShipping, "Третий", true))
+	expectDefault(t, 1, models.AddressShipping, third.ID)
+
+	billing := createAddress(t, r, address(models.AddressBilling, "Счёт", false))
+	expectDefault(t, 1, models.AddressBilling, billing.ID)
+	expectDefault(t, 1, models.AddressShipping, third.ID)
+
+	// адреса другого пользователя не затрагиваются
+	other := createAddress(t, setupRouter(2), address(models.AddressShipping, "Чужой", true))
+	expectDefault(t, 2, models.AddressShipping, other.ID)
+	expectDefault(t, 1, models.AddressShipping, third.ID)
+
+	w := doJSON(t, r, http.MethodPost, "/api/me/addresses", addressRequest{Type: "pickup", FullName: "x", Line1: "x", City: "x", Country: "RU"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Неизвестный тип: ожидался статус %d, получен %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdateMyAddressDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		// update получает созданные адреса: shipping a, b и billing c (a и c - по умолчанию)
+		update      func(a, b, c models.Address) (uint, addressRequest)
+		wantShip    func(a, b, c models.Address) []uint
+		wantBilling func(a, b, c models.Address) []uint
+	}{
+		{
+			name:        "Новый адрес по умолчанию снимает флаг с прежнего",
+			update:      func(a, b, c models.Address) (uint, addressRequest) { return b.ID, address(models.AddressShipping, "B", true) },
+			wantShip:    func(a, b, c models.Address) []uint { return []uint{b.ID} },
+			wantBilling: func(a, b, c models.Address) []uint { return []uint{c.ID} },
+		},
+		{
+			name:        "Снятый флаг переходит к другому адресу типа",
+			update:      func(a, b, c models.Address) (uint, addressRequest) { return a.ID, address(models.AddressShipping, "A", false) },
+			wantShip:    func(a, b, c models.Address) []uint { return []uint{b.ID} },
+			wantBilling: func(a, b, c models.Address) []uint { return []uint{c.ID} },
+		},
+		{
+			name:        "Единственный адрес типа остаётся по умолчанию",
+			update:      func(a, b, c models.Address) (uint, addressRequest) { return c.ID, address(models.AddressBilling, "C", false) },
+			wantShip:    func(a, b, c models.Address) []uint { return []uint{a.ID} },
+			wantBilling: func(a, b, c models.Address) []uint { return []uint{c.ID} },
+		},
+		{
+			name:        "Смена типа у адреса по умолчанию",
+			update:      func(a, b, c models.Address) (uint, addressRequest) { return a.ID, address(models.AddressBilling, "A", false) },
+			wantShip:    func(a, b, c models.Address) []uint { return []uint{b.ID} },
+			wantBilling: func(a, b, c models.Address) []uint { return []uint{c.ID} },
+		},
+		{
+			name:        "Смена типа с флагом по умолчанию",
+			update:      func(a, b, c models.Address) (uint, addressRequest) { return b.ID, address(models.AddressBilling, "B", true) },
+			wantShip:    func(a, b, c models.Address) []uint { return []uint{a.ID} },
+			wantBilling: func(a, b, c models.Address) []uint { return []uint{b.ID} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			r := setupRouter(1)
+			a := createAddress(t, r, address(models.AddressShipping, "A", false))
+			b := createAddress(t, r, address(models.AddressShipping, "B", false))
+			c := createAddress(t, r, address(models.AddressBilling, "C", false))
+
+			id, req := tt.update(a, b, c)
+			w := doJSON(t, r, http.MethodPut, fmt.Sprintf("/api/me/addresses/%d", id), req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			expectDefault(t, 1, models.AddressShipping, tt.wantShip(a, b, c)...)
+			expectDefault(t, 1, models.AddressBilling, tt.wantBilling(a, b, c)...)
+		})
+	}
+}
+
+func TestUpdateMyAddressNotFound(t *testing.T) {
+	setupTestDB(t)
+	a := createAddress(t, setupRouter(1), address(models.AddressShipping, "A", false))
+
+	w := doJSON(t, setupRouter(2), http.MethodPut, fmt.Sprintf("/api/me/addresses/%d", a.ID), address(models.AddressShipping, "X", true))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Чужой адрес: ожидался статус %d, получен %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDeleteMyAddressDefaults(t *testing.T) {
+	setupTestDB(t)
+	r := setupRouter(1)
+	a := createAddress(t, r, address(models.AddressShipping, "A", false))
+	b := createAddress(t, r, address(models.AddressShipping, "B", false))
+	c := createAddress(t, r, address(models.AddressShipping, "C", false))
+
+	// удаление адреса не по умолчанию флаг не трогает
+	if w := doJSON(t, r, http.MethodDelete, fmt.Sprintf("/api/me/addresses/%d", b.ID), nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusNoContent, w.Code)
+	}
+	expectDefault(t, 1, models.AddressShipping, a.ID)
+
+	// удаление адреса по умолчанию передаёт флаг оставшемуся
+	if w := doJSON(t, r, http.MethodDelete, fmt.Sprintf("/api/me/addresses/%d", a.ID), nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusNoContent, w.Code)
+	}
+	expectDefault(t, 1, models.AddressShipping, c.ID)
+
+	if w := doJSON(t, r, http.MethodDelete, fmt.Sprintf("/api/me/addresses/%d", c.ID), nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusNoContent, w.Code)
+	}
+	expectDefault(t, 1, models.AddressShipping)
+
+	if w := doJSON(t, r, http.MethodDelete, fmt.Sprintf("/api/me/addresses/%d", c.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("Повторное удаление: ожидался статус %d, получен %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestSetMyDefaultAddress(t *testing.T) {
+	setupTestDB(t)
+	r := setupRouter(1)
+	createAddress(t, r, address(models.AddressShipping, "A", false))
+	b := createAddress(t, r, address(models.AddressShipping, "B", false))
+
+	if w := doJSON(t, r, http.MethodPost, fmt.Sprintf("/api/me/addresses/%d/default", b.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, w.Code)
+	}
+	expectDefault(t, 1, models.AddressShipping, b.ID)
+}
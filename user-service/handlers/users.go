@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,11 +21,13 @@ type changeRoleRequest struct {
 
 func RegisterUserRoutes(r *gin.Engine, cfg *config.Config) {
 	admin := r.Group("/api/users")
-	admin.Use(middleware.AuthRequired(middleware.Config{JWTSecret: cfg.JWTSecret}), middleware.AdminOnly(cfg))
+	admin.Use(middleware.AuthRequired(middleware.Config{JWTSecret: cfg.JWTSecret}))
 
-	admin.GET("", listUsers)
-	admin.PATCH(":id/role", changeUserRole)
-	admin.GET(":id/activity", userActivity)
+	read := middleware.RequirePermission(middleware.PermUsersRead)
+	admin.GET("", read, listUsers)
+	admin.PATCH(":id/role", middleware.RequirePermission(middleware.PermRolesManage), changeUserRole)
+	admin.GET(":id/activity", read, userActivity)
+	admin.GET(":id/profile", read, userProfile)
 }
 
 func listUsers(c *gin.Context) {
@@ -44,11 +47,10 @@ func listUsers(c *gin.Context) {
 	}
 
 	filters := make(map[string]string)
-	if role := c.Query("role"); role != "" {
-		filters["role"] = role
-	}
-	if email := c.Query("email"); email != "" {
-		filters["email"] = email
+	for _, key := range []string{"role", "email", "q", "created_from", "created_to", "status", "sort", "order"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
 	}
 
 	users, total, err := authClient.ListUsers(page, size, filters)
@@ -68,7 +70,7 @@ func listUsers(c *gin.Context) {
 func changeUserRole(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	var req changeRoleRequest
-	if err := c.ShouldBindJSON(&req); err != nil || (req.Role != "admin" && req.Role != "user") {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
 		return
 	}
@@ -82,8 +84,11 @@ func changeUserRole(c *gin.Context) {
 		return
 	}
 
-	// Update role in auth-service via HTTP call
-	if err := authClient.UpdateUserRole(user.ID, req.Role); err != nil {
+	// Update role in auth-service via HTTP call; auth-service validates the role name
+	if err := authClient.UpdateUserRole(user.ID, req.Role); errors.Is(err, clients.ErrInvalidRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update role in auth-service"})
 		return
 	}
@@ -15,13 +15,14 @@ import (
 func main() {
 	cfg := config.LoadConfig()
 
-	// Инициализация БД (только Log, User хранится в auth-service)
-	db.InitDB(cfg.DBPath, &models.Log{})
+	// Инициализация БД: учётные записи хранятся в auth-service,
+	// здесь только профили покупателей, адреса и журнал действий
+	db.InitDB(cfg.DBPath, &models.Log{}, &models.Profile{}, &models.Address{})
 
 	r := gin.Default()
 
 	// Регистрируем маршруты
-	mwCfg := &middleware.Config{JWTSecret: cfg.JWTSecret}
+	mwCfg := &middleware.Config{JWTSecret: cfg.JWTSecret, InternalToken: cfg.InternalToken}
 	routes.SetupRoutes(r, mwCfg)
 
 	port := cfg.Port
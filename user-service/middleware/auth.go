@@ -1,26 +1,51 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"user-service/clients"
-	"user-service/config"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type Config struct {
-	JWTSecret string
+	JWTSecret     string
+	InternalToken string
 }
 
 const ContextUserID = "user_id"
+const ContextSessionID = "session_id"
+
+// ContextAccess - роль и права пользователя, полученные от auth-service в AuthRequired
+const ContextAccess = "access"
+
+// ContextAPIKeyPermissions - права запроса с API-ключом, уже ограниченные его scopes
+const ContextAPIKeyPermissions = "api_key_permissions"
+
+// APIKeyHeader - альтернатива "Authorization: Bearer ak_..." для персональных API-ключей
+const APIKeyHeader = "X-API-Key"
+
+const apiKeyPrefix = "ak_"
+
+// Права auth-service, которые проверяет user-service
+const (
+	PermUsersRead   = "users.read"
+	PermRolesManage = "roles.manage"
+)
 
 // AuthRequired проверяет JWT и извлекает user_id
 func AuthRequired(cfg Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing Authorization header"})
@@ -58,13 +83,70 @@ func AuthRequired(cfg Config) gin.HandlerFunc {
 			return
 		}
 
-		c.Set(ContextUserID, uint(userIDFloat))
+		userID := uint(userIDFloat)
+		c.Set(ContextUserID, userID)
+		sid, _ := claims["sid"].(string)
+		if sid != "" {
+			c.Set(ContextSessionID, sid)
+		}
+
+		// сессию и блокировку проверяем на каждом запросе: отозванный токен или заблокированный
+		// пользователь должны сразу перестать работать, в том числе завершённая имперсонация
+		access, err := clients.NewAuthClient().GetUserAccess(userID, sid)
+		if errors.Is(err, clients.ErrSessionRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			return
+		}
+		if errors.Is(err, clients.ErrUserSuspended) || errors.Is(err, clients.ErrUserNotFound) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is suspended or deleted"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify session"})
+			return
+		}
+		c.Set(ContextAccess, access)
+
+		if actorID := actorFromClaims(claims); actorID != 0 {
+			impersonationGuard(c, actorID, userID, sid)
+			return
+		}
 		c.Next()
 	}
 }
 
-// AdminOnly проверяет, что пользователь является администратором через auth-service
-func AdminOnly(cfg *config.Config) gin.HandlerFunc {
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(token, apiKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey проверяет ключ в auth-service и запоминает права, ограниченные его scopes
+func authenticateAPIKey(c *gin.Context, key string) {
+	access, err := clients.NewAuthClient().VerifyAPIKey(key)
+	if errors.Is(err, clients.ErrInvalidAPIKey) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		return
+	}
+	if errors.Is(err, clients.ErrUserSuspended) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is suspended or deleted"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify api key"})
+		return
+	}
+	c.Set(ContextUserID, access.ID)
+	c.Set(ContextAPIKeyPermissions, access.Permissions)
+	c.Next()
+}
+
+// RequirePermission проверяет право пользователя через auth-service
+func RequirePermission(perm string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userIDRaw, exists := c.Get(ContextUserID)
 		if !exists {
@@ -78,16 +160,45 @@ func AdminOnly(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// Проверяем роль через auth-service
+		// права API-ключа уже получены от auth-service в AuthRequired
+		if raw, ok := c.Get(ContextAPIKeyPermissions); ok {
+			perms, _ := raw.([]string)
+			for _, p := range perms {
+				if p == perm {
+					c.Next()
+					return
+				}
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission required: " + perm})
+			return
+		}
+
+		// права уже получены в AuthRequired; иначе проверяем через auth-service
+		if raw, ok := c.Get(ContextAccess); ok {
+			if access, _ := raw.(*clients.UserRoleResponse); access != nil && access.Can(perm) {
+				c.Next()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission required: " + perm})
+			return
+		}
 		authClient := clients.NewAuthClient()
-		role, err := authClient.GetUserRole(userID)
+		access, err := authClient.GetUserAccess(userID, c.GetString(ContextSessionID))
+		if errors.Is(err, clients.ErrSessionRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			return
+		}
+		if errors.Is(err, clients.ErrUserSuspended) || errors.Is(err, clients.ErrUserNotFound) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is suspended or deleted"})
+			return
+		}
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "failed to verify user role"})
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "failed to verify user permissions"})
 			return
 		}
 
-		if role != "admin" {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+		if !access.Can(perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission required: " + perm})
 			return
 		}
 
@@ -95,6 +206,19 @@ func AdminOnly(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
+// InternalOnly пропускает только межсервисные запросы с корректным X-Internal-Token.
+// Если токен не задан, все запросы отклоняются.
+func InternalOnly(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader("X-Internal-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid internal token"})
+			return
+		}
+		c.Next()
+	}
+}
+
 // GenerateJWT создает JWT для пользователя
 func GenerateJWT(userID uint, secret string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Profile хранит данные покупателя, которыми владеет user-service.
+// UserID — это ID пользователя в auth-service.
+type Profile struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	UserID             uint       `gorm:"uniqueIndex;not null" json:"user_id"`
+	Phone              string     `json:"phone"`
+	AvatarURL          string     `json:"avatar_url"`
+	MarketingConsent   bool       `gorm:"default:false" json:"marketing_consent"`
+	MarketingConsentAt *time.Time `json:"marketing_consent_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type AddressType string
+
+const (
+	AddressShipping AddressType = "shipping"
+	AddressBilling  AddressType = "billing"
+)
+
+type Address struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	UserID     uint        `gorm:"index;not null" json:"user_id"`
+	Type       AddressType `gorm:"type:text;not null" json:"type"`
+	FullName   string      `json:"full_name"`
+	Phone      string      `json:"phone"`
+	Line1      string      `json:"line1"`
+	Line2      string      `json:"line2"`
+	City       string      `json:"city"`
+	Region     string      `json:"region"`
+	PostalCode string      `json:"postal_code"`
+	Country    string      `json:"country"`
+	IsDefault  bool        `gorm:"default:false" json:"is_default"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
@@ -1,13 +0,0 @@
-package models
-
-import "time"
-
-type User struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Name         string    `json:"name"`
-	Email        string    `gorm:"uniqueIndex" json:"email"`
-	PasswordHash string    `json:"-"`
-	Role         string    `gorm:"type:text;default:user" json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-}
@@ -12,8 +12,11 @@ func SetupRoutes(r *gin.Engine, cfg *middleware.Config) {
 	// Пользовательские маршруты
 	// Преобразуем middleware.Config в config.Config для handlers
 	configCfg := &config.Config{
-		JWTSecret: cfg.JWTSecret,
+		JWTSecret:     cfg.JWTSecret,
+		InternalToken: cfg.InternalToken,
 	}
 	handlers.RegisterUserRoutes(r, configCfg)
+	handlers.RegisterProfileRoutes(r, configCfg)
+	handlers.RegisterInternalRoutes(r, configCfg)
 	// Можно добавить сюда остальные: проекты, контакты, портфолио
 }